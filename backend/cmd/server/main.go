@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
 
 	"watson/internal/app"
+	"watson/internal/attest"
+	"watson/internal/bytecode"
 	"watson/internal/db"
+	"watson/internal/source"
 )
 
 func mustInt64(k string) int64 {
@@ -43,6 +51,7 @@ func main() {
 	sql := db.MustOpenSQLite(dsn)
 	defer sql.Close()
 	db.MustMigrate(sql)
+	chains := loadChains(sql)
 
 	rpcURL := must("RPC_URL")
 	rpc, err := ethclient.Dial(rpcURL)
@@ -50,6 +59,47 @@ func main() {
 		log.Fatalf("ethclient: %v", err)
 	}
 
+	reportSecret := os.Getenv("REPORT_URL_SECRET")
+	if reportSecret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			log.Fatalf("report secret: %v", err)
+		}
+		reportSecret = hex.EncodeToString(b)
+		log.Printf("Warning: REPORT_URL_SECRET not set, report download links won't survive a restart")
+	}
+
+	// Finalized reports are signed when SIGNING_KEY is set, and attested on chain when EAS is configured too
+	var signer *attest.Signer
+	var eas *attest.EAS
+	if key := os.Getenv("SIGNING_KEY"); key != "" {
+		signer, err = attest.NewSigner(key)
+		if err != nil {
+			log.Fatalf("bad SIGNING_KEY: %v", err)
+		}
+		log.Printf("signing reports as %s", signer.Address().Hex())
+
+		if easAddr := os.Getenv("EAS_ADDRESS"); easAddr != "" {
+			easRPC := rpc
+			if u := os.Getenv("EAS_RPC_URL"); u != "" {
+				easRPC, err = ethclient.Dial(u)
+				if err != nil {
+					log.Fatalf("ethclient: %v", err)
+				}
+			}
+			eas, err = attest.NewEAS(easRPC, easAddr, must("EAS_SCHEMA_UID"), signer)
+			if err != nil {
+				log.Fatalf("eas: %v", err)
+			}
+		}
+	}
+
+	// Deployed bytecode is verified against audited sources when a local solc is configured
+	var solc *bytecode.Solc
+	if path := os.Getenv("SOLC_PATH"); path != "" {
+		solc = bytecode.NewSolc(path)
+	}
+
 	a := &app.App{
 		DB:         sql,
 		RPC:        rpc,
@@ -59,11 +109,29 @@ func main() {
 		CookieName: app.EnvOr("COOKIE_NAME", "sid"),
 		NonceTTL:   parseDur("NONCE_TTL", 5*time.Minute),
 		SessTTL:    parseDur("SESSION_TTL", 15*time.Minute),
+		ModelsURL:  app.EnvOr("OPENROUTER_MODELS_URL", "https://openrouter.ai/api/v1/models"),
+
+		PublicURL:    app.EnvOr("PUBLIC_URL", "http://localhost:8080"),
+		ReportSecret: []byte(reportSecret),
+		ReportURLTTL: parseDur("REPORT_URL_TTL", 7*24*time.Hour),
+
+		Signer: signer,
+		EAS:    eas,
+
+		GitHub:   source.NewGitHub(app.EnvOr("GITHUB_API_URL", "https://api.github.com"), os.Getenv("GITHUB_TOKEN")),
+		Explorer: source.NewExplorer(os.Getenv("ETHERSCAN_API_KEY")),
+		Chains:   chains,
+		Solc:     solc,
+
+		WebhookAllowPrivate: os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true",
+		RunnerToken:         os.Getenv("RUNNER_TOKEN"),
 	}
 
 	mux := http.NewServeMux()
 	a.Routes(mux)
 
+	go a.RunWebhookWorker(context.Background())
+
 	addr := app.EnvOr("ADDR", ":8080")
 	log.Printf("listening on %s", addr)
 	log.Fatal(http.ListenAndServe(addr, app.WithJSON(app.WithSecurityHeaders(mux))))
@@ -77,3 +145,30 @@ func parseDur(k string, def time.Duration) time.Duration {
 	}
 	return def
 }
+
+// loadChains adds the chains of CHAINS_FILE to the chains table and loads the registry.
+// <CHAIN>_RPC_URL (e.g. ETHEREUM_RPC_URL) is used before a chain's configured RPCs, and
+// ETHERSCAN_API_URL points every chain at one Etherscan-compatible API, such as a local mock.
+func loadChains(conn *sql.DB) *app.ChainRegistry {
+	if path := os.Getenv("CHAINS_FILE"); path != "" {
+		chains, err := app.ReadChainsFile(path)
+		if err != nil {
+			log.Fatalf("chains: %v", err)
+		}
+		if err := app.SyncChains(conn, chains); err != nil {
+			log.Fatalf("chains: %v", err)
+		}
+	}
+
+	registry, err := app.LoadChains(conn)
+	if err != nil {
+		log.Fatalf("chains: %v", err)
+	}
+	for _, c := range registry.All() {
+		if u := os.Getenv(strings.ToUpper(strings.ReplaceAll(c.ID, "-", "_")) + "_RPC_URL"); u != "" {
+			c.RPCURLs = append([]string{u}, c.RPCURLs...)
+		}
+		c.ExplorerAPIURL = app.EnvOr("ETHERSCAN_API_URL", c.ExplorerAPIURL)
+	}
+	return registry
+}
@@ -13,15 +13,16 @@ import (
 
 // Agent represents an AI agent
 type Agent struct {
-	ID           string    `json:"id"`
-	OwnerAddress string    `json:"owner_address"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description,omitempty"`
-	Model        string    `json:"model"`
-	SystemPrompt string    `json:"system_prompt"`
-	MCPServers   []string  `json:"mcp_servers"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string     `json:"id"`
+	OwnerAddress string     `json:"owner_address"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description,omitempty"`
+	Model        string     `json:"model"`
+	SystemPrompt string     `json:"system_prompt"`
+	MCPServers   []string   `json:"mcp_servers"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
 }
 
 // CreateAgentRequest represents the request to create an agent
@@ -42,7 +43,8 @@ type UpdateAgentRequest struct {
 	MCPServers   []string `json:"mcp_servers"`
 }
 
-// handleGetAgents returns all agents for the authenticated user
+// handleGetAgents returns all agents for the authenticated user.
+// Archived agents are only included with ?include_archived=true.
 func (a *App) handleGetAgents(w http.ResponseWriter, r *http.Request) {
 	address, ok := getAuthAddress(r)
 	if !ok {
@@ -50,12 +52,14 @@ func (a *App) handleGetAgents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+
 	rows, err := a.DB.Query(`
-		SELECT id, owner_address, name, description, model, system_prompt, mcp_servers, created_at, updated_at
+		SELECT id, owner_address, name, description, model, system_prompt, mcp_servers, created_at, updated_at, archived_at
 		FROM agents
-		WHERE owner_address = ?
+		WHERE owner_address = ? AND (archived_at IS NULL OR ?)
 		ORDER BY created_at DESC
-	`, address)
+	`, address, includeArchived)
 	if err != nil {
 		httpErr(w, 500, "db")
 		return
@@ -67,6 +71,7 @@ func (a *App) handleGetAgents(w http.ResponseWriter, r *http.Request) {
 		var agent Agent
 		var mcpServersJSON string
 		var desc sql.NullString
+		var archivedAt sql.NullTime
 
 		err := rows.Scan(
 			&agent.ID,
@@ -78,6 +83,7 @@ func (a *App) handleGetAgents(w http.ResponseWriter, r *http.Request) {
 			&mcpServersJSON,
 			&agent.CreatedAt,
 			&agent.UpdatedAt,
+			&archivedAt,
 		)
 		if err != nil {
 			httpErr(w, 500, "scan")
@@ -87,6 +93,9 @@ func (a *App) handleGetAgents(w http.ResponseWriter, r *http.Request) {
 		if desc.Valid {
 			agent.Description = desc.String
 		}
+		if archivedAt.Valid {
+			agent.ArchivedAt = &archivedAt.Time
+		}
 
 		// Parse JSON array of MCP servers
 		if err := json.Unmarshal([]byte(mcpServersJSON), &agent.MCPServers); err != nil {
@@ -117,9 +126,10 @@ func (a *App) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 	var agent Agent
 	var mcpServersJSON string
 	var desc sql.NullString
+	var archivedAt sql.NullTime
 
 	err := a.DB.QueryRow(`
-		SELECT id, owner_address, name, description, model, system_prompt, mcp_servers, created_at, updated_at
+		SELECT id, owner_address, name, description, model, system_prompt, mcp_servers, created_at, updated_at, archived_at
 		FROM agents
 		WHERE id = ?
 	`, id).Scan(
@@ -132,6 +142,7 @@ func (a *App) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 		&mcpServersJSON,
 		&agent.CreatedAt,
 		&agent.UpdatedAt,
+		&archivedAt,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -152,6 +163,9 @@ func (a *App) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 	if desc.Valid {
 		agent.Description = desc.String
 	}
+	if archivedAt.Valid {
+		agent.ArchivedAt = &archivedAt.Time
+	}
 
 	// Parse JSON array of MCP servers
 	if err := json.Unmarshal([]byte(mcpServersJSON), &agent.MCPServers); err != nil {
@@ -256,7 +270,8 @@ func (a *App) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 
 	// Check if agent exists and user owns it
 	var ownerAddress string
-	err := a.DB.QueryRow(`SELECT owner_address FROM agents WHERE id = ?`, id).Scan(&ownerAddress)
+	var archivedAt sql.NullTime
+	err := a.DB.QueryRow(`SELECT owner_address, archived_at FROM agents WHERE id = ?`, id).Scan(&ownerAddress, &archivedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		httpErr(w, 404, "Agent not found")
 		return
@@ -271,6 +286,11 @@ func (a *App) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if archivedAt.Valid {
+		httpErr(w, 409, "Agent is archived; restore it before modifying")
+		return
+	}
+
 	// Validation
 	req.Name = strings.TrimSpace(req.Name)
 	req.Model = strings.TrimSpace(req.Model)
@@ -319,7 +339,7 @@ func (a *App) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 	var mcpJSON string
 	var desc sql.NullString
 	err = a.DB.QueryRow(`
-		SELECT id, owner_address, name, description, model, system_prompt, mcp_servers, created_at, updated_at
+		SELECT id, owner_address, name, description, model, system_prompt, mcp_servers, created_at, updated_at, archived_at
 		FROM agents
 		WHERE id = ?
 	`, id).Scan(
@@ -332,6 +352,7 @@ func (a *App) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 		&mcpJSON,
 		&agent.CreatedAt,
 		&agent.UpdatedAt,
+		&archivedAt,
 	)
 
 	if err != nil {
@@ -350,7 +371,10 @@ func (a *App) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, agent)
 }
 
-// handleDeleteAgent deletes an agent
+// handleDeleteAgent archives an agent. Archived agents are hidden from listings and can't be
+// used for new audits, but still resolve for the findings and runs that reference them.
+// With ?purge=true an already archived agent is deleted permanently instead, which is only
+// allowed once nothing references it anymore.
 func (a *App) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
 	address, ok := getAuthAddress(r)
 	if !ok {
@@ -367,7 +391,8 @@ func (a *App) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
 
 	// Check if agent exists and user owns it
 	var ownerAddress string
-	err := a.DB.QueryRow(`SELECT owner_address FROM agents WHERE id = ?`, id).Scan(&ownerAddress)
+	var archivedAt sql.NullTime
+	err := a.DB.QueryRow(`SELECT owner_address, archived_at FROM agents WHERE id = ?`, id).Scan(&ownerAddress, &archivedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		httpErr(w, 404, "Agent not found")
 		return
@@ -382,7 +407,55 @@ func (a *App) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = a.DB.Exec(`DELETE FROM agents WHERE id = ?`, id)
+	if r.URL.Query().Get("purge") == "true" {
+		a.purgeAgent(w, id, archivedAt.Valid)
+		return
+	}
+
+	if !archivedAt.Valid {
+		now := time.Now().UTC()
+		_, err = a.DB.Exec(`UPDATE agents SET archived_at = ?, updated_at = ? WHERE id = ?`, now, now, id)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+	}
+
+	writeJSON(w, 200, map[string]bool{"success": true, "archived": true})
+}
+
+// handleRestoreAgent makes an archived agent available again
+func (a *App) handleRestoreAgent(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /agents/{id}/restore
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing agent ID")
+		return
+	}
+
+	var ownerAddress string
+	err := a.DB.QueryRow(`SELECT owner_address FROM agents WHERE id = ?`, id).Scan(&ownerAddress)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Agent not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if ownerAddress != address {
+		httpErr(w, 403, "Not authorized to modify this agent")
+		return
+	}
+
+	_, err = a.DB.Exec(`UPDATE agents SET archived_at = NULL, updated_at = ? WHERE id = ?`, time.Now().UTC(), id)
 	if err != nil {
 		httpErr(w, 500, "db")
 		return
@@ -390,3 +463,52 @@ func (a *App) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, 200, map[string]bool{"success": true})
 }
+
+// purgeAgent permanently deletes an archived agent. Agents that produced findings or runs,
+// or that are still part of a pending audit, a pipeline or a project's default agents, are kept
+// so history stays intact.
+func (a *App) purgeAgent(w http.ResponseWriter, id string, archived bool) {
+	if !archived {
+		httpErr(w, 409, "Agent must be archived before it can be purged")
+		return
+	}
+
+	var findings, runs, audits, pipelines, projects int
+	err := a.DB.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM findings WHERE agent_id = ?),
+			(SELECT COUNT(*) FROM agent_runs WHERE agent_id = ?),
+			(SELECT COUNT(*) FROM audits, json_each(audits.agents_used)
+			 WHERE json_each.value = ? AND audits.status = 'pending'),
+			(SELECT COUNT(*) FROM pipelines, json_each(pipelines.stages) AS stage, json_each(stage.value, '$.agents') AS agent
+			 WHERE agent.value = ?),
+			(SELECT COUNT(*) FROM projects, json_each(projects.default_agents) WHERE json_each.value = ?)
+	`, id, id, id, id, id).Scan(&findings, &runs, &audits, &pipelines, &projects)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	switch {
+	case findings > 0 || runs > 0:
+		httpErr(w, 409, "Agent has findings or runs in past audits and can only be archived")
+		return
+	case audits > 0:
+		httpErr(w, 409, "Agent is used by a pending audit")
+		return
+	case pipelines > 0:
+		httpErr(w, 409, "Agent is used by a pipeline")
+		return
+	case projects > 0:
+		httpErr(w, 409, "Agent is a default agent of a project")
+		return
+	}
+
+	_, err = a.DB.Exec(`DELETE FROM agents WHERE id = ?`, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, map[string]bool{"success": true, "purged": true})
+}
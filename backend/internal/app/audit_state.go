@@ -0,0 +1,382 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// auditTransitions lists the statuses an audit can move to from each status.
+// completed is final; failed, cancelled and partially_completed audits can be retried.
+var auditTransitions = map[string][]string{
+	"pending":             {"in_progress", "cancelled"},
+	"in_progress":         {"completed", "partially_completed", "failed", "cancelled"},
+	"completed":           {},
+	"partially_completed": {"in_progress"},
+	"failed":              {"in_progress"},
+	"cancelled":           {"in_progress"},
+}
+
+// finishedAuditStatuses are the statuses of audits that are no longer running
+var finishedAuditStatuses = map[string]bool{
+	"completed":           true,
+	"partially_completed": true,
+	"failed":              true,
+	"cancelled":           true,
+}
+
+// errAuditChanged means an audit's status changed between reading and updating it
+var errAuditChanged = errors.New("audit status changed concurrently")
+
+// auditTransitionError is returned for a move auditTransitions doesn't allow
+type auditTransitionError struct {
+	From, To string
+}
+
+func (e *auditTransitionError) Error() string {
+	return "Audit can't move from " + e.From + " to " + e.To
+}
+
+// canTransitionAudit reports whether an audit may move between two statuses
+func canTransitionAudit(from, to string) bool {
+	for _, s := range auditTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionAudit moves an audit from one status to another and keeps its timestamps in line:
+// entering in_progress (re)sets started_at, finishing sets completed_at. Every status change goes through here
+// and is recorded as a status.changed event; notify a.events after committing.
+func transitionAudit(tx *sql.Tx, id, from, to string, now time.Time) error {
+	if !canTransitionAudit(from, to) {
+		return &auditTransitionError{From: from, To: to}
+	}
+
+	query := `UPDATE audits SET status = ?, updated_at = ?`
+	args := []interface{}{to, now}
+	switch {
+	case to == "in_progress":
+		query += `, started_at = ?, completed_at = NULL`
+		args = append(args, now)
+	case finishedAuditStatuses[to]:
+		query += `, completed_at = ?`
+		args = append(args, now)
+	}
+	query += ` WHERE id = ? AND status = ?`
+	args = append(args, id, from)
+
+	// Guard against a concurrent change between reading and updating the status
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errAuditChanged
+	}
+	return recordAuditEvent(tx, id, EventStatusChanged, map[string]string{"from": from, "to": to})
+}
+
+// transitionErr writes the response for a failed transitionAudit
+func transitionErr(w http.ResponseWriter, err error) {
+	var te *auditTransitionError
+	switch {
+	case errors.As(err, &te):
+		httpErr(w, 409, te.Error())
+	case errors.Is(err, errAuditChanged):
+		httpErr(w, 409, "Audit status changed concurrently")
+	default:
+		httpErr(w, 500, "db")
+	}
+}
+
+// queueAgentRuns queues one run per agent and stage of an audit, so runtime and token usage can be tracked per agent.
+// Agents that already completed their stage, no longer exist or were archived since the audit was created are skipped.
+// It returns how many runs were queued.
+func queueAgentRuns(tx *sql.Tx, auditID string, stagesJSON sql.NullString, agentsJSON string, now time.Time) (int, error) {
+	queued := 0
+	for stage, s := range auditStages(stagesJSON, agentsJSON) {
+		for _, agentID := range s.Agents {
+			res, err := tx.Exec(`
+				INSERT INTO agent_runs (id, audit_id, agent_id, stage, status, created_at)
+				SELECT ?, ?, id, ?, 'queued', ? FROM agents
+				WHERE id = ? AND archived_at IS NULL
+				  AND NOT EXISTS (SELECT 1 FROM agent_runs
+				                  WHERE audit_id = ? AND agent_id = ? AND stage = ? AND status = 'completed')
+			`, uuid.NewString(), auditID, stage, now, agentID, auditID, agentID, stage)
+			if err != nil {
+				return 0, err
+			}
+			n, _ := res.RowsAffected()
+			queued += int(n)
+		}
+	}
+	return queued, nil
+}
+
+// settleAudit finishes an in_progress audit once none of its runs are queued or running: completed when every
+// run completed, failed when none did (or none could be queued) and partially_completed otherwise. finishRun calls
+// it as runs finish, and starting or retrying an audit that queued no runs settles it at once. It returns the
+// status the audit settled in, or "" when it's still running or no longer in_progress. Its findings are clustered
+// first.
+func (a *App) settleAudit(auditID string) (string, error) {
+	var queued, completed int
+	err := a.DB.QueryRow(`
+		SELECT COALESCE(SUM(status IN ('queued', 'running')), 0), COALESCE(SUM(status = 'completed'), 0)
+		FROM agent_runs
+		WHERE audit_id = ?
+	`, auditID).Scan(&queued, &completed)
+	if err != nil || queued > 0 {
+		return "", err
+	}
+
+	// Runs of earlier attempts that failed were replaced by later ones, so only the latest run per agent and stage counts
+	var failed int
+	err = a.DB.QueryRow(`
+		SELECT COUNT(*) FROM agent_runs r
+		WHERE r.audit_id = ? AND r.status != 'completed'
+		  AND NOT EXISTS (SELECT 1 FROM agent_runs l
+		                  WHERE l.audit_id = r.audit_id AND l.agent_id = r.agent_id AND l.stage = r.stage
+		                    AND l.created_at > r.created_at)
+	`, auditID).Scan(&failed)
+	if err != nil {
+		return "", err
+	}
+
+	// Findings are deduplicated once every agent has reported, before clients hear the audit finished
+	if err := a.clusterFindings(auditID); err != nil {
+		log.Printf("cluster findings of audit %s: %v", auditID, err)
+	}
+
+	status := "completed"
+	switch {
+	case completed == 0:
+		status = "failed"
+	case failed > 0:
+		status = "partially_completed"
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// The audit may have been cancelled or settled by a concurrent finishRun
+	if err := transitionAudit(tx, auditID, "in_progress", status, time.Now().UTC()); err != nil {
+		if errors.Is(err, errAuditChanged) {
+			return "", nil
+		}
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	a.events.notify(auditID)
+	return status, nil
+}
+
+// handleCancelAudit stops a pending or running audit. Queued and running agent runs are cancelled.
+func (a *App) handleCancelAudit(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/cancel
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	status, ok := a.auditStatusForUpdate(w, id, address, "cancel")
+	if !ok {
+		return
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if err := transitionAudit(tx, id, status, "cancelled", now); err != nil {
+		transitionErr(w, err)
+		return
+	}
+	_, err = tx.Exec(`
+		UPDATE agent_runs SET status = 'cancelled', finished_at = ?
+		WHERE audit_id = ? AND status IN ('queued', 'running')
+	`, now, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	a.events.notify(id)
+
+	writeJSON(w, 200, map[string]interface{}{
+		"id":           id,
+		"status":       "cancelled",
+		"completed_at": now,
+	})
+}
+
+// handleRetryAudit runs a failed, cancelled or partially completed audit again.
+// Only the agents that didn't complete their stage are queued.
+func (a *App) handleRetryAudit(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/retry
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	status, ok := a.auditStatusForUpdate(w, id, address, "retry")
+	if !ok {
+		return
+	}
+	if status != "failed" && status != "cancelled" && status != "partially_completed" {
+		httpErr(w, 409, "Only failed, cancelled or partially completed audits can be retried")
+		return
+	}
+
+	if !a.confirmAuditCost(w, r, id, address) {
+		return
+	}
+
+	var agentsJSON string
+	var stagesJSON sql.NullString
+	err := a.DB.QueryRow(`SELECT agents_used, pipeline_stages FROM audits WHERE id = ?`, id).Scan(&agentsJSON, &stagesJSON)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if err := transitionAudit(tx, id, status, "in_progress", now); err != nil {
+		transitionErr(w, err)
+		return
+	}
+	queued, err := queueAgentRuns(tx, id, stagesJSON, agentsJSON, now)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	a.events.notify(id)
+
+	writeJSON(w, 200, map[string]interface{}{
+		"id":         id,
+		"status":     a.settleUnqueued(id, queued),
+		"started_at": now,
+	})
+}
+
+// settleUnqueued settles an audit that was just (re)started when it queued no runs, as no run will finish to
+// settle it. It returns the audit's status.
+func (a *App) settleUnqueued(auditID string, queued int) string {
+	if queued > 0 {
+		return "in_progress"
+	}
+	status, err := a.settleAudit(auditID)
+	if err != nil {
+		log.Printf("settle audit %s: %v", auditID, err)
+	}
+	if status == "" {
+		return "in_progress"
+	}
+	return status
+}
+
+// handleDeleteAudit deletes an audit with its findings, runs, clusters and reports. Usage stays in the ledger.
+func (a *App) handleDeleteAudit(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	status, ok := a.auditStatusForUpdate(w, id, address, "delete")
+	if !ok {
+		return
+	}
+	if status == "in_progress" {
+		httpErr(w, 409, "Audit is in progress; cancel it before deleting")
+		return
+	}
+
+	// Findings, agent runs, clusters, reports and source files cascade; triage history and comments cascade from findings
+	res, err := a.DB.Exec(`DELETE FROM audits WHERE id = ? AND status = ?`, id, status)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		httpErr(w, 409, "Audit status changed concurrently")
+		return
+	}
+	if err := pruneSourceBlobs(a.DB); err != nil {
+		log.Printf("prune source blobs: %v", err)
+	}
+
+	writeJSON(w, 200, map[string]bool{"success": true})
+}
+
+// auditStatusForUpdate returns the status of an audit the user owns, writing a 404 or 403 response otherwise.
+// action completes the "Not authorized to ... this audit" message.
+func (a *App) auditStatusForUpdate(w http.ResponseWriter, id, address, action string) (string, bool) {
+	var ownerAddress, status string
+	err := a.DB.QueryRow(`SELECT owner_address, status FROM audits WHERE id = ?`, id).Scan(&ownerAddress, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Audit not found")
+		return "", false
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return "", false
+	}
+	if ownerAddress != address {
+		httpErr(w, 403, "Not authorized to "+action+" this audit")
+		return "", false
+	}
+	return status, true
+}
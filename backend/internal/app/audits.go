@@ -10,25 +10,44 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"watson/internal/proxy"
+	"watson/internal/source"
 )
 
 // Audit represents a security audit
 type Audit struct {
-	ID              string         `json:"id"`
-	OwnerAddress    string         `json:"owner_address"`
-	Name            string         `json:"name"`
-	Description     string         `json:"description,omitempty"`
-	Status          string         `json:"status"`
-	ContractAddress string         `json:"contract_address,omitempty"`
-	Blockchain      string         `json:"blockchain"`
-	GitHubURL       string         `json:"github_url,omitempty"`
-	SourceCode      string         `json:"source_code,omitempty"`
-	AgentsUsed      []string       `json:"agents_used,omitempty"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	StartedAt       *time.Time     `json:"started_at,omitempty"`
-	CompletedAt     *time.Time     `json:"completed_at,omitempty"`
-	FindingsCount   *FindingsCount `json:"findings_count,omitempty"`
+	ID               string               `json:"id"`
+	OwnerAddress     string               `json:"owner_address"`
+	Name             string               `json:"name"`
+	Description      string               `json:"description,omitempty"`
+	Status           string               `json:"status"`
+	ContractAddress  string               `json:"contract_address,omitempty"`
+	Blockchain       string               `json:"blockchain"`
+	GitHubURL        string               `json:"github_url,omitempty"`
+	GitHubRef        string               `json:"github_ref,omitempty"`
+	CommitSHA        string               `json:"commit_sha,omitempty"`    // commit of github_url the source snapshot was taken at
+	FileCount        int                  `json:"file_count"`              // files in the source snapshot
+	ContractName     string               `json:"contract_name,omitempty"` // verified contract_address source fetched from a block explorer
+	CompilerVersion  string               `json:"compiler_version,omitempty"`
+	CompilerSettings json.RawMessage      `json:"compiler_settings,omitempty"`
+	Proxy            *proxy.Topology      `json:"proxy,omitempty"`       // proxies in front of contract_address
+	ProxyAdmin       string               `json:"proxy_admin,omitempty"` // who can upgrade contract_address
+	CodeHash         string               `json:"code_hash,omitempty"`   // contract_address bytecode when the audit was created
+	DeploymentBlock  *uint64              `json:"deployment_block,omitempty"`
+	Warnings         []string             `json:"warnings,omitempty"`     // on creation, e.g. the bytecode changed since a previous audit
+	Verification     *Verification        `json:"verification,omitempty"` // contract_address compared with the compiled source snapshot
+	Bytecode         []BytecodeInspection `json:"bytecode,omitempty"`     // metadata and functions read from the audited bytecode
+	AgentsUsed       []string             `json:"agents_used,omitempty"`
+	PipelineID       string               `json:"pipeline_id,omitempty"`
+	Pipeline         []PipelineStage      `json:"pipeline,omitempty"`
+	ProjectID        string               `json:"project_id,omitempty"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+	StartedAt        *time.Time           `json:"started_at,omitempty"`
+	CompletedAt      *time.Time           `json:"completed_at,omitempty"`
+	FindingsCount    *FindingsCount       `json:"findings_count,omitempty"`
+	Usage            *UsageSummary        `json:"usage,omitempty"`
 }
 
 // FindingsCount represents count of findings by severity
@@ -42,13 +61,17 @@ type FindingsCount struct {
 
 // CreateAuditRequest represents the request to create an audit
 type CreateAuditRequest struct {
-	Name            string   `json:"name"`
-	Description     string   `json:"description"`
-	ContractAddress string   `json:"contract_address"`
-	Blockchain      string   `json:"blockchain"`
-	GitHubURL       string   `json:"github_url"`
-	SourceCode      string   `json:"source_code"`
-	Agents          []string `json:"agents"`
+	Name            string            `json:"name"`
+	Description     string            `json:"description"`
+	ContractAddress string            `json:"contract_address"`
+	Blockchain      string            `json:"blockchain"`
+	GitHubURL       string            `json:"github_url"`
+	GitHubRef       string            `json:"github_ref"`  // branch, tag or commit; the default branch when empty
+	SourceCode      string            `json:"source_code"` // a single file, stored as Contract.sol
+	Files           []SourceFileInput `json:"files"`
+	Agents          []string          `json:"agents"`
+	PipelineID      string            `json:"pipeline_id"`
+	ProjectID       string            `json:"project_id"` // fills in the repository, chain, contract and agents left out
 }
 
 // handleGetAudits returns all audits for the authenticated user
@@ -61,9 +84,15 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 
 	// Parse query parameters
 	status := r.URL.Query().Get("status")
+	projectID := r.URL.Query().Get("project_id")
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
+	if _, ok := auditTransitions[status]; status != "" && !ok {
+		httpErr(w, 400, "status must be one of: pending, in_progress, completed, partially_completed, failed, cancelled")
+		return
+	}
+
 	limit := 50
 	offset := 0
 
@@ -82,7 +111,7 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 	// Build query
 	query := `
 		SELECT id, owner_address, name, description, status, contract_address, blockchain, 
-		       github_url, created_at, updated_at, started_at, completed_at
+		       github_url, verification, project_id, created_at, updated_at, started_at, completed_at
 		FROM audits
 		WHERE owner_address = ?`
 	args := []interface{}{address}
@@ -91,6 +120,10 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 		query += ` AND status = ?`
 		args = append(args, status)
 	}
+	if projectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, projectID)
+	}
 
 	query += ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
 	args = append(args, limit, offset)
@@ -105,7 +138,7 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 	audits := []Audit{}
 	for rows.Next() {
 		var audit Audit
-		var desc, contractAddr, githubURL sql.NullString
+		var desc, contractAddr, githubURL, verificationJSON, projID sql.NullString
 		var startedAt, completedAt sql.NullTime
 
 		err := rows.Scan(
@@ -117,6 +150,8 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 			&contractAddr,
 			&audit.Blockchain,
 			&githubURL,
+			&verificationJSON,
+			&projID,
 			&audit.CreatedAt,
 			&audit.UpdatedAt,
 			&startedAt,
@@ -136,6 +171,12 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 		if githubURL.Valid {
 			audit.GitHubURL = githubURL.String
 		}
+		if verificationJSON.Valid {
+			if err := json.Unmarshal([]byte(verificationJSON.String), &audit.Verification); err != nil {
+				audit.Verification = nil
+			}
+		}
+		audit.ProjectID = projID.String
 		if startedAt.Valid {
 			audit.StartedAt = &startedAt.Time
 		}
@@ -143,12 +184,15 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 			audit.CompletedAt = &completedAt.Time
 		}
 
-		// Get findings count
-		findingsCount := a.getFindingsCount(audit.ID)
-		audit.FindingsCount = findingsCount
-
 		audits = append(audits, audit)
 	}
+	// Release the connection before running the per-audit queries below
+	rows.Close()
+
+	for i := range audits {
+		audits[i].FindingsCount = a.getFindingsCount(audits[i].ID)
+		audits[i].Usage = a.getAuditUsage(audits[i].ID)
+	}
 
 	// Get total count
 	countQuery := `SELECT COUNT(*) FROM audits WHERE owner_address = ?`
@@ -157,6 +201,10 @@ func (a *App) handleGetAudits(w http.ResponseWriter, r *http.Request) {
 		countQuery += ` AND status = ?`
 		countArgs = append(countArgs, status)
 	}
+	if projectID != "" {
+		countQuery += ` AND project_id = ?`
+		countArgs = append(countArgs, projectID)
+	}
 
 	var total int
 	err = a.DB.QueryRow(countQuery, countArgs...).Scan(&total)
@@ -189,12 +237,15 @@ func (a *App) handleGetAudit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var audit Audit
-	var desc, contractAddr, githubURL, sourceCode, agentsJSON sql.NullString
+	var desc, contractAddr, githubURL, githubRef, commitSHA, contractName, compilerVersion, compilerSettings, proxyJSON, proxyAdmin, codeHash, verificationJSON, bytecodeJSON, agentsJSON, pipelineID, stagesJSON, projectID sql.NullString
+	var deploymentBlock sql.NullInt64
 	var startedAt, completedAt sql.NullTime
 
 	err := a.DB.QueryRow(`
 		SELECT id, owner_address, name, description, status, contract_address, blockchain,
-		       github_url, source_code, agents_used, created_at, updated_at, started_at, completed_at
+		       github_url, github_ref, commit_sha, contract_name, compiler_version, compiler_settings,
+		       proxy_topology, proxy_admin, code_hash, deployment_block, verification, bytecode,
+		       agents_used, pipeline_id, pipeline_stages, project_id, created_at, updated_at, started_at, completed_at
 		FROM audits
 		WHERE id = ?
 	`, id).Scan(
@@ -206,8 +257,21 @@ func (a *App) handleGetAudit(w http.ResponseWriter, r *http.Request) {
 		&contractAddr,
 		&audit.Blockchain,
 		&githubURL,
-		&sourceCode,
+		&githubRef,
+		&commitSHA,
+		&contractName,
+		&compilerVersion,
+		&compilerSettings,
+		&proxyJSON,
+		&proxyAdmin,
+		&codeHash,
+		&deploymentBlock,
+		&verificationJSON,
+		&bytecodeJSON,
 		&agentsJSON,
+		&pipelineID,
+		&stagesJSON,
+		&projectID,
 		&audit.CreatedAt,
 		&audit.UpdatedAt,
 		&startedAt,
@@ -238,8 +302,33 @@ func (a *App) handleGetAudit(w http.ResponseWriter, r *http.Request) {
 	if githubURL.Valid {
 		audit.GitHubURL = githubURL.String
 	}
-	if sourceCode.Valid {
-		audit.SourceCode = sourceCode.String
+	audit.GitHubRef = githubRef.String
+	audit.CommitSHA = commitSHA.String
+	audit.ContractName = contractName.String
+	audit.CompilerVersion = compilerVersion.String
+	if compilerSettings.Valid {
+		audit.CompilerSettings = json.RawMessage(compilerSettings.String)
+	}
+	if proxyJSON.Valid && proxyJSON.String != "" {
+		if err := json.Unmarshal([]byte(proxyJSON.String), &audit.Proxy); err != nil {
+			audit.Proxy = nil
+		}
+	}
+	audit.ProxyAdmin = proxyAdmin.String
+	audit.CodeHash = codeHash.String
+	if deploymentBlock.Valid {
+		block := uint64(deploymentBlock.Int64)
+		audit.DeploymentBlock = &block
+	}
+	if verificationJSON.Valid {
+		if err := json.Unmarshal([]byte(verificationJSON.String), &audit.Verification); err != nil {
+			audit.Verification = nil
+		}
+	}
+	if bytecodeJSON.Valid {
+		if err := json.Unmarshal([]byte(bytecodeJSON.String), &audit.Bytecode); err != nil {
+			audit.Bytecode = nil
+		}
 	}
 	if startedAt.Valid {
 		audit.StartedAt = &startedAt.Time
@@ -257,6 +346,19 @@ func (a *App) handleGetAudit(w http.ResponseWriter, r *http.Request) {
 		audit.AgentsUsed = []string{}
 	}
 
+	if pipelineID.Valid {
+		audit.PipelineID = pipelineID.String
+	}
+	audit.ProjectID = projectID.String
+	if stagesJSON.Valid && stagesJSON.String != "" {
+		if err := json.Unmarshal([]byte(stagesJSON.String), &audit.Pipeline); err != nil {
+			audit.Pipeline = nil
+		}
+	}
+
+	audit.FileCount = a.countSourceFiles(audit.ID)
+	audit.Usage = a.getAuditUsage(audit.ID)
+
 	writeJSON(w, 200, audit)
 }
 
@@ -268,8 +370,16 @@ func (a *App) handleCreateAudit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sources come inline in JSON, or as an archive uploaded with the audit in a multipart form
 	var req CreateAuditRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var archive []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		var msg string
+		if archive, msg = readAuditUpload(w, r, &req); msg != "" {
+			httpErr(w, 400, msg)
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		httpErr(w, 400, "bad json")
 		return
 	}
@@ -278,6 +388,7 @@ func (a *App) handleCreateAudit(w http.ResponseWriter, r *http.Request) {
 	req.Name = strings.TrimSpace(req.Name)
 	req.Blockchain = strings.TrimSpace(req.Blockchain)
 	req.GitHubURL = strings.TrimSpace(req.GitHubURL)
+	req.GitHubRef = strings.TrimSpace(req.GitHubRef)
 	req.ContractAddress = strings.TrimSpace(req.ContractAddress)
 
 	if req.Name == "" || len(req.Name) > 200 {
@@ -285,26 +396,54 @@ func (a *App) handleCreateAudit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var projectID interface{}
+	if req.ProjectID != "" {
+		p, err := a.getProject(req.ProjectID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && p.OwnerAddress != address) {
+			httpErr(w, 400, "project not found")
+			return
+		}
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		applyProjectDefaults(&req, p)
+		projectID = p.ID
+	}
+
 	if req.Blockchain == "" {
 		httpErr(w, 400, "blockchain is required")
 		return
 	}
 
-	validBlockchains := map[string]bool{
-		"ethereum": true,
-		"polygon":  true,
-		"arbitrum": true,
-		"optimism": true,
-		"base":     true,
+	chain, ok := a.Chains.Get(req.Blockchain)
+	if !ok {
+		httpErr(w, 400, "blockchain must be one of: "+a.Chains.IDs())
+		return
 	}
-	if !validBlockchains[req.Blockchain] {
-		httpErr(w, 400, "blockchain must be one of: ethereum, polygon, arbitrum, optimism, base")
+
+	if req.ContractAddress != "" {
+		contractAddress, msg := normalizeContractAddress(req.ContractAddress)
+		if msg != "" {
+			httpErr(w, 400, msg)
+			return
+		}
+		req.ContractAddress = contractAddress
+	}
+
+	files, msg := requestSources(&req, archive)
+	if msg != "" {
+		httpErr(w, 400, msg)
 		return
 	}
 
-	// Either github_url or source_code must be provided
-	if req.GitHubURL == "" && req.SourceCode == "" {
-		httpErr(w, 400, "either github_url or source_code must be provided")
+	// Either github_url, the sources themselves, or a contract whose source is verified must be provided
+	if req.GitHubURL == "" && len(files) == 0 && (req.ContractAddress == "" || a.Explorer == nil) {
+		httpErr(w, 400, "either github_url, source_code, files, an archive or a verified contract_address must be provided")
+		return
+	}
+	if req.GitHubRef != "" && req.GitHubURL == "" {
+		httpErr(w, 400, "github_ref requires github_url")
 		return
 	}
 
@@ -313,6 +452,59 @@ func (a *App) handleCreateAudit(w http.ResponseWriter, r *http.Request) {
 		req.Agents = []string{}
 	}
 
+	// A pipeline determines the agents to use and the order they run in
+	var pipelineID, stagesJSON interface{}
+	var stages []PipelineStage
+	if req.PipelineID != "" {
+		if len(req.Agents) > 0 {
+			httpErr(w, 400, "agents and pipeline_id are mutually exclusive")
+			return
+		}
+
+		p, err := a.getPipeline(req.PipelineID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && p.OwnerAddress != address) {
+			httpErr(w, 400, "pipeline not found")
+			return
+		}
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+
+		b, err := json.Marshal(p.Stages)
+		if err != nil {
+			httpErr(w, 500, "json marshal")
+			return
+		}
+		stages = p.Stages
+		pipelineID, stagesJSON = p.ID, string(b)
+		req.Agents = pipelineAgents(p.Stages)
+	}
+
+	// Only the user's own agents can be used for new audits, and neither archived nor tool agents
+	for _, agentID := range req.Agents {
+		var archivedAt sql.NullTime
+		var kind string
+		err := a.DB.QueryRow(`SELECT archived_at, kind FROM agents WHERE id = ? AND owner_address = ?`, agentID, address).
+			Scan(&archivedAt, &kind)
+		if errors.Is(err, sql.ErrNoRows) {
+			httpErr(w, 400, "agent "+agentID+" not found")
+			return
+		}
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		if archivedAt.Valid {
+			httpErr(w, 400, "agent "+agentID+" is archived")
+			return
+		}
+		if kind != "ai" {
+			httpErr(w, 400, "agent "+agentID+" only attributes imported findings")
+			return
+		}
+	}
+
 	// Convert agents to JSON
 	agentsJSON, err := json.Marshal(req.Agents)
 	if err != nil {
@@ -320,32 +512,187 @@ func (a *App) handleCreateAudit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The repository is snapshotted now, so the audit analyzes the commit it was created for.
+	// Sources given with the request take precedence; github_url is then kept for reference.
+	snap := &githubSnapshot{Files: files}
+	if len(files) == 0 && req.GitHubURL != "" && a.GitHub != nil {
+		var msg string
+		snap, msg, err = a.snapshotGitHub(r.Context(), req.GitHubURL, req.GitHubRef)
+		if err != nil {
+			httpErr(w, 502, "GitHub unavailable")
+			return
+		}
+		if msg != "" {
+			httpErr(w, 400, msg)
+			return
+		}
+	}
+
+	// The contract must be deployed on the chain; its code is recorded so later audits can tell it changed
+	deployed := &deployedContract{}
+	var warnings []string
+	if req.ContractAddress != "" {
+		var c *deployedContract
+		var msg string
+		c, msg, err = a.inspectContract(r.Context(), chain, req.ContractAddress)
+		if errors.Is(err, errWrongChain) {
+			httpErr(w, 502, "Chain RPC for "+chain.ID+" serves another chain")
+			return
+		}
+		if err != nil {
+			httpErr(w, 502, "Chain RPC unavailable")
+			return
+		}
+		if msg != "" {
+			httpErr(w, 400, msg)
+			return
+		}
+		if c != nil {
+			deployed = c
+		} else {
+			warnings = append(warnings, "contract_address was not checked on chain: no RPC is configured for "+chain.ID)
+		}
+	}
+
+	// A proxy contract_address is followed to the code it runs, which is what gets audited
+	topology, err := a.resolveProxy(r.Context(), chain, req.ContractAddress)
+	if errors.Is(err, proxy.ErrTooDeep) {
+		httpErr(w, 400, "contract_address: "+err.Error())
+		return
+	}
+	if err != nil {
+		httpErr(w, 502, "Chain RPC unavailable")
+		return
+	}
+	var proxyJSON interface{}
+	var proxyAdmin string
+	if topology != nil {
+		b, err := json.Marshal(topology)
+		if err != nil {
+			httpErr(w, 500, "json marshal")
+			return
+		}
+		proxyJSON, proxyAdmin = string(b), topology.Proxies[0].Admin
+	}
+
+	if deployed.CodeHash != "" {
+		changed, err := a.contractWarnings(address, chain.ID, req.ContractAddress, deployed, topology)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		warnings = append(warnings, changed...)
+	}
+
+	// The bytecode gives agents some context on the code, all of it when the source isn't verified
+	var inspections []BytecodeInspection
+	if deployed.CodeHash != "" {
+		inspections, err = a.inspectBytecode(r.Context(), chain, req.ContractAddress, topology)
+		if err != nil {
+			httpErr(w, 502, "Chain RPC unavailable")
+			return
+		}
+	}
+	var bytecodeJSON interface{}
+	if inspections != nil {
+		b, err := json.Marshal(inspections)
+		if err != nil {
+			httpErr(w, 500, "json marshal")
+			return
+		}
+		bytecodeJSON = string(b)
+	}
+
+	// With neither, the verified source of the contract is fetched, along with how it was compiled
+	verified := &source.Verified{}
+	if len(snap.Files) == 0 && req.GitHubURL == "" && a.Explorer != nil {
+		var msg string
+		verified, msg, err = a.fetchVerifiedSource(r.Context(), chain, req.ContractAddress, topology)
+		// An unverified contract is audited from its bytecode alone
+		if errors.Is(err, source.ErrNotVerified) {
+			if inspections == nil {
+				httpErr(w, 400, err.Error())
+				return
+			}
+			verified, err = &source.Verified{}, nil
+		}
+		if err != nil {
+			httpErr(w, 502, "Block explorer unavailable")
+			return
+		}
+		if msg != "" {
+			httpErr(w, 400, msg)
+			return
+		}
+		snap.Files = verified.Files
+		for _, p := range verified.Skipped {
+			warnings = append(warnings, "verified source file "+p+" was skipped: its path escapes the root or repeats another")
+		}
+	}
+
 	id := uuid.NewString()
 	now := time.Now().UTC()
 
-	_, err = a.DB.Exec(`
+	tx, err := a.DB.Begin()
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
 		INSERT INTO audits (id, owner_address, name, description, status, contract_address, blockchain, 
-		                    github_url, source_code, agents_used, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		                    github_url, github_ref, commit_sha, contract_name, compiler_version, compiler_settings,
+		                    proxy_topology, proxy_admin, code_hash, bytecode,
+		                    agents_used, pipeline_id, pipeline_stages, project_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, id, address, req.Name, req.Description, "pending", req.ContractAddress, req.Blockchain,
-		req.GitHubURL, req.SourceCode, string(agentsJSON), now, now)
-
+		req.GitHubURL, nullString(snap.Ref), nullString(snap.CommitSHA),
+		nullString(verified.ContractName), nullString(verified.CompilerVersion), nullString(string(verified.Settings)),
+		proxyJSON, nullString(proxyAdmin), nullString(deployed.CodeHash), bytecodeJSON,
+		string(agentsJSON), pipelineID, stagesJSON, projectID, now, now)
 	if err != nil {
 		httpErr(w, 500, "db")
 		return
 	}
+	if err := insertSourceFiles(tx, id, snap.Files, now); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if deployed.CodeHash != "" {
+		go a.recordDeploymentBlock(id, chain.ID, req.ContractAddress, deployed.Block)
+	}
 
 	audit := Audit{
-		ID:              id,
-		OwnerAddress:    address,
-		Name:            req.Name,
-		Description:     req.Description,
-		Status:          "pending",
-		ContractAddress: req.ContractAddress,
-		Blockchain:      req.Blockchain,
-		GitHubURL:       req.GitHubURL,
-		CreatedAt:       now,
-		UpdatedAt:       now,
+		ID:               id,
+		OwnerAddress:     address,
+		Name:             req.Name,
+		Description:      req.Description,
+		Status:           "pending",
+		ContractAddress:  req.ContractAddress,
+		Blockchain:       req.Blockchain,
+		GitHubURL:        req.GitHubURL,
+		GitHubRef:        snap.Ref,
+		CommitSHA:        snap.CommitSHA,
+		FileCount:        len(snap.Files),
+		ContractName:     verified.ContractName,
+		CompilerVersion:  verified.CompilerVersion,
+		CompilerSettings: verified.Settings,
+		Proxy:            topology,
+		ProxyAdmin:       proxyAdmin,
+		CodeHash:         deployed.CodeHash,
+		Warnings:         warnings,
+		Bytecode:         inspections,
+		AgentsUsed:       req.Agents,
+		PipelineID:       req.PipelineID,
+		Pipeline:         stages,
+		ProjectID:        req.ProjectID,
+		CreatedAt:        now,
+		UpdatedAt:        now,
 	}
 
 	writeJSON(w, 201, audit)
@@ -367,8 +714,11 @@ func (a *App) handleStartAudit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if audit exists and user owns it
-	var ownerAddress, status string
-	err := a.DB.QueryRow(`SELECT owner_address, status FROM audits WHERE id = ?`, id).Scan(&ownerAddress, &status)
+	var ownerAddress, status, agentsJSON string
+	var stagesJSON sql.NullString
+	err := a.DB.QueryRow(`
+		SELECT owner_address, status, agents_used, pipeline_stages FROM audits WHERE id = ?
+	`, id).Scan(&ownerAddress, &status, &agentsJSON, &stagesJSON)
 	if errors.Is(err, sql.ErrNoRows) {
 		httpErr(w, 404, "Audit not found")
 		return
@@ -383,44 +733,105 @@ func (a *App) handleStartAudit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if already started
 	if status != "pending" {
-		httpErr(w, 400, "Audit can only be started from pending status")
+		httpErr(w, 409, "Audit can only be started from pending status")
 		return
 	}
 
-	// Update status to in_progress and set started_at
-	now := time.Now().UTC()
-	_, err = a.DB.Exec(`
-		UPDATE audits 
-		SET status = 'in_progress', started_at = ?, updated_at = ?
-		WHERE id = ?
-	`, now, now, id)
+	if !a.confirmAuditCost(w, r, id, address) {
+		return
+	}
 
+	tx, err := a.DB.Begin()
 	if err != nil {
 		httpErr(w, 500, "db")
 		return
 	}
+	defer tx.Rollback()
 
-	// TODO: Here we would trigger the AI agents to analyze the contract
-	// This would typically be done via a job queue or background worker
-	// For now, we just return success
+	now := time.Now().UTC()
+	if err := transitionAudit(tx, id, status, "in_progress", now); err != nil {
+		transitionErr(w, err)
+		return
+	}
+	queued, err := queueAgentRuns(tx, id, stagesJSON, agentsJSON, now)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	a.events.notify(id)
+	// The agent runner picks up the queued runs through GET /runs
+	go a.runVerification(id)
 
 	writeJSON(w, 200, map[string]interface{}{
 		"id":         id,
-		"status":     "in_progress",
+		"status":     a.settleUnqueued(id, queued),
 		"started_at": now,
 	})
 }
 
-// getFindingsCount returns count of findings by severity for an audit
+// confirmAuditCost checks an audit's estimated cost against the user's cost ceiling. Running an audit
+// estimated above it requires ?confirm=true, as does running one whose cost can't be checked against it:
+// its source size is unknown (e.g. an audit of bytecode alone) or some of its models have no pricing.
+// Otherwise a 409 with the estimate is written.
+func (a *App) confirmAuditCost(w http.ResponseWriter, r *http.Request, id, address string) bool {
+	if r.URL.Query().Get("confirm") == "true" {
+		return true
+	}
+	estimate, err := a.estimateAudit(id, address)
+	if errors.Is(err, errNoAuditSource) {
+		settings, err := a.getUserSettings(address)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return false
+		}
+		if settings.CostCeilingUSD != nil {
+			httpErr(w, 409, "Audit source size is unknown, so its cost can't be checked against your cost ceiling; retry with ?confirm=true to start anyway")
+			return false
+		}
+		return true
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return false
+	}
+
+	msg := ""
+	switch {
+	case estimate.ExceedsCeiling:
+		msg = "Estimated cost exceeds your cost ceiling; retry with ?confirm=true to start anyway"
+	case estimate.CostCeilingUSD != nil && len(estimate.UnpricedModels) > 0:
+		msg = "Some models have no pricing, so the cost can't be checked against your cost ceiling; retry with ?confirm=true to start anyway"
+	}
+	if msg != "" {
+		writeJSON(w, 409, map[string]interface{}{
+			"error":    msg,
+			"estimate": estimate,
+		})
+		return false
+	}
+	return true
+}
+
+// getFindingsCount returns count of findings triaged as real issues by severity for an audit.
+// Duplicates in a cluster are counted once, through the cluster's canonical finding.
 func (a *App) getFindingsCount(auditID string) *FindingsCount {
+	return a.countFindings(`audit_id = ? AND status IN `+confirmedStatuses+` AND `+canonicalFinding, auditID)
+}
+
+// countFindings returns count of findings by severity matching the given WHERE clause
+func (a *App) countFindings(where string, args ...interface{}) *FindingsCount {
 	rows, err := a.DB.Query(`
 		SELECT severity, COUNT(*) as count
 		FROM findings
-		WHERE audit_id = ?
+		WHERE `+where+`
 		GROUP BY severity
-	`, auditID)
+	`, args...)
 	if err != nil {
 		return &FindingsCount{}
 	}
@@ -450,3 +861,8 @@ func (a *App) getFindingsCount(auditID string) *FindingsCount {
 
 	return count
 }
+
+// Total returns the number of findings across all severities
+func (c *FindingsCount) Total() int {
+	return c.Critical + c.High + c.Medium + c.Low + c.Info
+}
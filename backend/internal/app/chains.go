@@ -0,0 +1,233 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// chainIDPattern is the shape of chain slugs, e.g. ethereum or bsc-testnet
+var chainIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// Chain is a chain audited contracts can be deployed on
+type Chain struct {
+	ID             string         `json:"id"` // the slug audits and project deployments store as blockchain
+	ChainID        int64          `json:"chain_id"`
+	Name           string         `json:"name"`
+	RPCURLs        []string       `json:"rpc_urls,omitempty"` // not listed, as they often embed API keys
+	HasRPC         bool           `json:"has_rpc"`
+	ExplorerAPIURL string         `json:"explorer_api_url,omitempty"`
+	NativeCurrency NativeCurrency `json:"native_currency"`
+	Testnet        bool           `json:"testnet"`
+}
+
+// NativeCurrency is the currency gas is paid in on a chain
+type NativeCurrency struct {
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// ChainRegistry holds the chains of the chains table, loaded once at startup.
+// It is the one place chain-dependent features look chains up.
+type ChainRegistry struct {
+	chains []*Chain
+	byID   map[string]*Chain
+
+	mu       sync.Mutex
+	clients  map[string]*ethclient.Client
+	chainIDs map[string]int64 // the chain ID each client's RPC reported
+}
+
+// errWrongChain is returned for an RPC serving another chain than the one it's configured for
+var errWrongChain = errors.New("RPC serves another chain")
+
+// LoadChains reads the chains table into a registry
+func LoadChains(db *sql.DB) (*ChainRegistry, error) {
+	rows, err := db.Query(`
+		SELECT id, chain_id, name, rpc_urls, explorer_api_url, currency_name, currency_symbol, currency_decimals, testnet
+		FROM chains
+		ORDER BY created_at, rowid
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	r := &ChainRegistry{byID: map[string]*Chain{}, clients: map[string]*ethclient.Client{}, chainIDs: map[string]int64{}}
+	for rows.Next() {
+		var c Chain
+		var rpcJSON string
+		var explorer sql.NullString
+		err := rows.Scan(&c.ID, &c.ChainID, &c.Name, &rpcJSON, &explorer,
+			&c.NativeCurrency.Name, &c.NativeCurrency.Symbol, &c.NativeCurrency.Decimals, &c.Testnet)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(rpcJSON), &c.RPCURLs); err != nil {
+			return nil, fmt.Errorf("chain %s: rpc_urls: %w", c.ID, err)
+		}
+		c.ExplorerAPIURL = explorer.String
+		r.chains = append(r.chains, &c)
+		r.byID[c.ID] = &c
+	}
+	return r, rows.Err()
+}
+
+// ReadChainsFile reads a JSON array of chains, such as testnets or a local chain to add to the table
+func ReadChainsFile(path string) ([]Chain, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var chains []Chain
+	if err := json.Unmarshal(b, &chains); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, c := range chains {
+		switch {
+		case !chainIDPattern.MatchString(c.ID):
+			return nil, fmt.Errorf("%s: chain %d: id must be lower-case letters, digits and dashes", path, i)
+		case c.ChainID <= 0:
+			return nil, fmt.Errorf("%s: chain %s: chain_id is required", path, c.ID)
+		case c.Name == "" || c.NativeCurrency.Symbol == "":
+			return nil, fmt.Errorf("%s: chain %s: name and native_currency.symbol are required", path, c.ID)
+		}
+		if c.NativeCurrency.Name == "" {
+			chains[i].NativeCurrency.Name = c.NativeCurrency.Symbol
+		}
+		if c.NativeCurrency.Decimals == 0 {
+			chains[i].NativeCurrency.Decimals = 18
+		}
+	}
+	return chains, nil
+}
+
+// SyncChains adds chains to the chains table, replacing the ones with the same id
+func SyncChains(db *sql.DB, chains []Chain) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, c := range chains {
+		rpcURLs := c.RPCURLs
+		if rpcURLs == nil {
+			rpcURLs = []string{}
+		}
+		rpcJSON, err := json.Marshal(rpcURLs)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO chains (id, chain_id, name, rpc_urls, explorer_api_url, currency_name, currency_symbol, currency_decimals, testnet,
+			                    created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				chain_id = excluded.chain_id, name = excluded.name, rpc_urls = excluded.rpc_urls,
+				explorer_api_url = excluded.explorer_api_url, currency_name = excluded.currency_name,
+				currency_symbol = excluded.currency_symbol, currency_decimals = excluded.currency_decimals,
+				testnet = excluded.testnet, updated_at = excluded.updated_at
+		`, c.ID, c.ChainID, c.Name, string(rpcJSON), nullString(c.ExplorerAPIURL),
+			c.NativeCurrency.Name, c.NativeCurrency.Symbol, c.NativeCurrency.Decimals, c.Testnet, now, now)
+		if err != nil {
+			return fmt.Errorf("chain %s: %w", c.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Get returns a chain by its id
+func (r *ChainRegistry) Get(id string) (*Chain, bool) {
+	c, ok := r.byID[id]
+	return c, ok
+}
+
+// All returns the chains in the order they were added
+func (r *ChainRegistry) All() []*Chain {
+	return r.chains
+}
+
+// IDs lists the chain ids, for validation messages
+func (r *ChainRegistry) IDs() string {
+	ids := make([]string, len(r.chains))
+	for i, c := range r.chains {
+		ids[i] = c.ID
+	}
+	return strings.Join(ids, ", ")
+}
+
+// Client returns an RPC client for a chain's first RPC URL, or nil when it has none
+func (r *ChainRegistry) Client(id string) (*ethclient.Client, error) {
+	c, ok := r.byID[id]
+	if !ok || len(c.RPCURLs) == 0 {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if client, ok := r.clients[id]; ok {
+		return client, nil
+	}
+	client, err := ethclient.Dial(c.RPCURLs[0])
+	if err != nil {
+		return nil, err
+	}
+	r.clients[id] = client
+	return client, nil
+}
+
+// CheckChainID asks a chain's RPC client for its chain ID, once per client, and returns errWrongChain
+// when it isn't the chain's. A misconfigured RPC URL would otherwise have audits pin code from another chain.
+func (r *ChainRegistry) CheckChainID(ctx context.Context, id string, client *ethclient.Client) error {
+	c, ok := r.byID[id]
+	if !ok {
+		return nil
+	}
+
+	r.mu.Lock()
+	got, checked := r.chainIDs[id]
+	r.mu.Unlock()
+	if !checked {
+		n, err := client.ChainID(ctx)
+		if err != nil {
+			return err
+		}
+		got = n.Int64()
+		r.mu.Lock()
+		r.chainIDs[id] = got
+		r.mu.Unlock()
+		if got != c.ChainID {
+			log.Printf("chain %s: RPC serves chain ID %d, not %d", id, got, c.ChainID)
+		}
+	}
+	if got != c.ChainID {
+		return fmt.Errorf("chain %s: %w %d, not %d", id, errWrongChain, got, c.ChainID)
+	}
+	return nil
+}
+
+// handleGetChains lists the chains audits can target
+func (a *App) handleGetChains(w http.ResponseWriter, r *http.Request) {
+	chains := make([]Chain, len(a.Chains.All()))
+	for i, c := range a.Chains.All() {
+		chains[i] = *c
+		chains[i].HasRPC = len(c.RPCURLs) > 0
+		chains[i].RPCURLs = nil
+	}
+
+	writeJSON(w, 200, map[string][]Chain{"chains": chains})
+}
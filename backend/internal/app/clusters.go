@@ -0,0 +1,335 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"watson/internal/match"
+)
+
+// canonicalFinding matches findings that are unique or represent their cluster
+const canonicalFinding = `(findings.cluster_id IS NULL OR findings.id IN (
+	SELECT canonical_id FROM finding_clusters WHERE finding_clusters.id = findings.cluster_id))`
+
+// FindingCluster represents findings of an audit that describe the same issue
+type FindingCluster struct {
+	ID          string         `json:"id"`
+	AuditID     string         `json:"audit_id"`
+	CanonicalID string         `json:"canonical_id"`
+	Canonical   *Finding       `json:"canonical"`
+	Duplicates  []Finding      `json:"duplicates"`
+	Agents      []ClusterAgent `json:"agents"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// ClusterAgent is an agent that contributed a finding to a cluster
+type ClusterAgent struct {
+	AgentID string `json:"agent_id"`
+	Name    string `json:"name"`
+}
+
+// handleGetClusters returns the duplicate clusters of an audit's findings
+func (a *App) handleGetClusters(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/clusters
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	clusters, err := a.getClusters(id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, map[string][]FindingCluster{"clusters": clusters})
+}
+
+// handleClusterFindings recomputes the duplicate clusters of an audit's findings
+func (a *App) handleClusterFindings(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/clusters
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	if err := a.clusterFindings(id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	clusters, err := a.getClusters(id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, map[string][]FindingCluster{"clusters": clusters})
+}
+
+// clusterFindings groups an audit's findings that describe the same issue, replacing any previous clusters.
+// It runs as the audit settles, once its agents have reported their findings, and after imports.
+func (a *App) clusterFindings(auditID string) error {
+	rows, err := a.DB.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.audit_id = ?
+		ORDER BY findings.rowid ASC
+	`, auditID)
+	if err != nil {
+		return err
+	}
+
+	var findings []*Finding
+	var items []match.Item
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		findings = append(findings, f)
+		items = append(items, matchItem(f))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE findings SET cluster_id = NULL WHERE audit_id = ?`, auditID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM finding_clusters WHERE audit_id = ?`, auditID); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, group := range match.Cluster(items, match.DefaultThreshold) {
+		if len(group) < 2 {
+			continue
+		}
+
+		members := make([]*Finding, 0, len(group))
+		ids := make([]interface{}, 0, len(group))
+		for _, i := range group {
+			members = append(members, findings[i])
+			ids = append(ids, findings[i].ID)
+		}
+		canonical := pickCanonical(members)
+
+		clusterID := uuid.NewString()
+		_, err := tx.Exec(`
+			INSERT INTO finding_clusters (id, audit_id, canonical_id, created_at)
+			VALUES (?, ?, ?, ?)
+		`, clusterID, auditID, canonical.ID, now)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE findings SET cluster_id = ? WHERE id IN (`+
+			strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")+`)`, append([]interface{}{clusterID}, ids...)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// matchItem returns the part of a finding used to match it with others
+func matchItem(f *Finding) match.Item {
+	return match.Item{
+		Agent:       f.AgentID,
+		File:        f.Location.File,
+		Line:        f.Location.Line,
+		Function:    f.Location.Function,
+		Title:       f.Title,
+		Description: f.Description,
+	}
+}
+
+// pickCanonical returns the finding that best represents a cluster, the earliest reported among equals
+func pickCanonical(members []*Finding) *Finding {
+	canonical := members[0]
+	for _, f := range members[1:] {
+		if preferCanonical(f, canonical) {
+			canonical = f
+		}
+	}
+	return canonical
+}
+
+// repickCanonical picks the canonical finding of a finding's cluster again after the finding's triage status
+// changed, so a cluster whose canonical finding turns out a false positive is represented by another member.
+// It does nothing for findings outside a cluster.
+func repickCanonical(tx *sql.Tx, findingID string) error {
+	rows, err := tx.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.cluster_id = (SELECT cluster_id FROM findings WHERE id = ?)
+		ORDER BY findings.rowid ASC
+	`, findingID)
+	if err != nil {
+		return err
+	}
+	var members []*Finding
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		members = append(members, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	canonical := pickCanonical(members)
+	_, err = tx.Exec(`UPDATE finding_clusters SET canonical_id = ? WHERE id = ?`, canonical.ID, canonical.ClusterID)
+	return err
+}
+
+// preferCanonical reports whether f represents a cluster better than current:
+// triaged real issues first, then higher severity, then the earliest reported
+func preferCanonical(f, current *Finding) bool {
+	if rf, rc := triageRank(f.Status), triageRank(current.Status); rf != rc {
+		return rf > rc
+	}
+	return severityWeights[f.Severity] > severityWeights[current.Severity]
+}
+
+// triageRank orders statuses by how strongly they confirm a finding
+func triageRank(status string) int {
+	switch status {
+	case "confirmed", "wont_fix", "fixed":
+		return 2
+	case "false_positive":
+		return 0
+	default:
+		return 1
+	}
+}
+
+// getClusters loads the clusters of an audit with their findings and contributing agents
+func (a *App) getClusters(auditID string) ([]FindingCluster, error) {
+	rows, err := a.DB.Query(`
+		SELECT id, audit_id, canonical_id, created_at
+		FROM finding_clusters
+		WHERE audit_id = ?
+		ORDER BY created_at ASC, rowid ASC
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []FindingCluster{}
+	index := map[string]int{}
+	for rows.Next() {
+		c := FindingCluster{Duplicates: []Finding{}, Agents: []ClusterAgent{}}
+		if err := rows.Scan(&c.ID, &c.AuditID, &c.CanonicalID, &c.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[c.ID] = len(clusters)
+		clusters = append(clusters, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = a.DB.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.audit_id = ? AND findings.cluster_id IS NOT NULL
+		ORDER BY findings.rowid ASC
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*Finding
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, f := range members {
+		i, ok := index[f.ClusterID]
+		if !ok {
+			continue
+		}
+		if f.ID == clusters[i].CanonicalID {
+			clusters[i].Canonical = f
+		} else {
+			clusters[i].Duplicates = append(clusters[i].Duplicates, *f)
+		}
+	}
+
+	// List the canonical finding's agent first, then the others in reporting order
+	for i := range clusters {
+		c := &clusters[i]
+		seen := map[string]bool{}
+		for _, f := range append([]*Finding{c.Canonical}, findingPtrs(c.Duplicates)...) {
+			if f == nil || seen[f.AgentID] {
+				continue
+			}
+			seen[f.AgentID] = true
+			c.Agents = append(c.Agents, ClusterAgent{AgentID: f.AgentID, Name: f.AgentName})
+		}
+	}
+
+	return clusters, nil
+}
+
+func findingPtrs(findings []Finding) []*Finding {
+	out := make([]*Finding, len(findings))
+	for i := range findings {
+		out[i] = &findings[i]
+	}
+	return out
+}
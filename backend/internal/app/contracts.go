@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"watson/internal/bytecode"
+	"watson/internal/proxy"
+)
+
+// hexAddressPattern is a 0x-prefixed 20-byte hex address
+var hexAddressPattern = regexp.MustCompile(`^0[xX][0-9a-fA-F]{40}$`)
+
+// deploymentBlockTimeout bounds the search for an audited contract's deployment block, which takes
+// a few dozen historical eth_getCode calls
+const deploymentBlockTimeout = 2 * time.Minute
+
+// deployedContract is what the chain says about an audited contract address
+type deployedContract struct {
+	CodeHash string // keccak256 of the runtime bytecode
+	Block    uint64 // the latest block, at which the code was read
+}
+
+// BytecodeInspection is what the code deployed at an address reveals without its source
+type BytecodeInspection struct {
+	Address string `json:"address"`
+	bytecode.Inspection
+}
+
+// normalizeContractAddress checks a contract address and returns it EIP-55 checksummed.
+// Mixed-case addresses must carry a valid checksum; all-lower or all-upper case ones have none.
+// A non-empty msg describes what's wrong with it.
+func normalizeContractAddress(s string) (address, msg string) {
+	if !hexAddressPattern.MatchString(s) {
+		return "", "contract_address must be a 0x-prefixed 20-byte hex address"
+	}
+	checksummed := common.HexToAddress(s).Hex()
+	digits := s[2:]
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && digits != checksummed[2:] {
+		return "", "contract_address has an invalid EIP-55 checksum"
+	}
+	return checksummed, ""
+}
+
+// inspectContract checks through the chain's RPC that a contract is deployed at address, and returns
+// its code hash. It returns nil when no RPC is configured for the chain, and errWrongChain when the
+// RPC serves another chain. A non-empty msg describes why the address can't be audited.
+func (a *App) inspectContract(ctx context.Context, chain *Chain, address string) (c *deployedContract, msg string, err error) {
+	client, err := a.Chains.Client(chain.ID)
+	if client == nil || err != nil {
+		return nil, "", err
+	}
+	if err := a.Chains.CheckChainID(ctx, chain.ID, client); err != nil {
+		return nil, "", err
+	}
+
+	addr := common.HexToAddress(address)
+	latest, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	code, err := client.CodeAt(ctx, addr, new(big.Int).SetUint64(latest))
+	if err != nil {
+		return nil, "", err
+	}
+	// An EOA, or a contract deployed on another chain
+	if len(code) == 0 {
+		return nil, "contract_address has no contract code on " + chain.ID, nil
+	}
+
+	return &deployedContract{CodeHash: crypto.Keccak256Hash(code).Hex(), Block: latest}, "", nil
+}
+
+// recordDeploymentBlock finds the deployment block of an audit's contract in the background, as it takes
+// many RPC calls, and stores it on the audit. It's left empty when the search fails or times out.
+func (a *App) recordDeploymentBlock(auditID, blockchain, address string, latest uint64) {
+	client, err := a.Chains.Client(blockchain)
+	if client == nil || err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deploymentBlockTimeout)
+	defer cancel()
+
+	block := deploymentBlock(ctx, client, common.HexToAddress(address), latest)
+	if block == nil {
+		return
+	}
+	if _, err := a.DB.Exec(`UPDATE audits SET deployment_block = ? WHERE id = ?`, *block, auditID); err != nil {
+		log.Printf("audit %s: deployment block: %v", auditID, err)
+	}
+}
+
+// deploymentBlock binary searches the first block at which addr has code, which takes
+// historical state. It returns nil when the RPC can't serve it, as pruned nodes can't.
+func deploymentBlock(ctx context.Context, client *ethclient.Client, addr common.Address, latest uint64) *uint64 {
+	lo, hi := uint64(0), latest
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		code, err := client.CodeAt(ctx, addr, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil
+		}
+		if len(code) > 0 {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return &hi
+}
+
+// contractWarnings compares a contract with the owner's previous audit of the same address,
+// warning when its bytecode, or the implementations behind it, changed since
+func (a *App) contractWarnings(owner, blockchain, address string, c *deployedContract, topology *proxy.Topology) ([]string, error) {
+	var prevID, prevHash string
+	var prevTopology sql.NullString
+	var prevCreated time.Time
+	err := a.DB.QueryRow(`
+		SELECT id, code_hash, proxy_topology, created_at FROM audits
+		WHERE owner_address = ? AND blockchain = ? AND contract_address = ? COLLATE NOCASE AND code_hash IS NOT NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, owner, blockchain, address).Scan(&prevID, &prevHash, &prevTopology, &prevCreated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	since := fmt.Sprintf("since audit %s (%s)", prevID, prevCreated.Format("2006-01-02"))
+	var warnings []string
+	if prevHash != c.CodeHash {
+		warnings = append(warnings, "contract bytecode changed "+since)
+	}
+
+	var prev proxy.Topology
+	if prevTopology.Valid {
+		json.Unmarshal([]byte(prevTopology.String), &prev)
+	}
+	var current []string
+	if topology != nil {
+		current = topology.Implementations
+	}
+	if strings.Join(prev.Implementations, ",") != strings.Join(current, ",") {
+		warnings = append(warnings, "proxy implementation changed "+since)
+	}
+	return warnings, nil
+}
+
+// inspectBytecode decodes the metadata and dispatched functions of the code an audit of contractAddress
+// analyzes: the contract itself or, for a proxy, the implementations behind it.
+// It returns nil when no RPC is configured for the chain.
+func (a *App) inspectBytecode(ctx context.Context, chain *Chain, contractAddress string, topology *proxy.Topology) ([]BytecodeInspection, error) {
+	client, err := a.Chains.Client(chain.ID)
+	if client == nil || err != nil {
+		return nil, err
+	}
+	addresses := []string{contractAddress}
+	if topology != nil {
+		addresses = topology.Implementations
+	}
+
+	inspections := []BytecodeInspection{}
+	for _, addr := range addresses {
+		code, err := client.CodeAt(ctx, common.HexToAddress(addr), nil)
+		if err != nil {
+			return nil, err
+		}
+		inspections = append(inspections, BytecodeInspection{Address: addr, Inspection: *bytecode.Inspect(code)})
+	}
+	return inspections, nil
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+
+	"watson/internal/match"
+)
+
+// Kinds of change between the findings of an audit and an earlier audit it is compared with
+const (
+	ChangeNew             = "new"
+	ChangeResolved        = "resolved"
+	ChangePersisting      = "persisting"
+	ChangeSeverityChanged = "severity_changed"
+)
+
+// changeOrder lists the kinds of change in the order a diff presents them
+var changeOrder = map[string]int{
+	ChangeNew:             0,
+	ChangeSeverityChanged: 1,
+	ChangePersisting:      2,
+	ChangeResolved:        3,
+}
+
+// AuditDiff compares the findings of an audit with those of an earlier audit (the base)
+type AuditDiff struct {
+	AuditID     string          `json:"audit_id"`
+	BaseAuditID string          `json:"base_audit_id"`
+	Summary     DiffSummary     `json:"summary"`
+	Changes     []FindingChange `json:"changes"`
+}
+
+// DiffSummary counts the changes of a diff by kind
+type DiffSummary struct {
+	New             int `json:"new"`
+	Resolved        int `json:"resolved"`
+	Persisting      int `json:"persisting"`
+	SeverityChanged int `json:"severity_changed"`
+}
+
+// FindingChange is one finding of either audit and how it changed.
+// Finding is absent for resolved findings and BaseFinding for new ones.
+type FindingChange struct {
+	Change      string   `json:"change"`
+	Finding     *Finding `json:"finding,omitempty"`
+	BaseFinding *Finding `json:"base_finding,omitempty"`
+	Similarity  float64  `json:"similarity,omitempty"`
+}
+
+// handleGetAuditDiff compares an audit's findings with those of an earlier audit, e.g. before a fix round
+func (a *App) handleGetAuditDiff(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract IDs from path: /audits/{id}/diff/{otherId}
+	id, baseID := r.PathValue("id"), r.PathValue("otherId")
+	if id == "" || baseID == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+	if id == baseID {
+		httpErr(w, 400, "an audit can't be compared with itself")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) || !a.checkAuditAccess(w, baseID, address) {
+		return
+	}
+
+	diff, err := a.auditDiff(id, baseID)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, diff)
+}
+
+// auditDiff matches the findings of an audit with those of its base by location and text.
+// Only distinct issues that weren't dismissed as false positives are compared.
+func (a *App) auditDiff(auditID, baseID string) (*AuditDiff, error) {
+	findings, err := a.diffFindings(auditID)
+	if err != nil {
+		return nil, err
+	}
+	base, err := a.diffFindings(baseID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]match.Item, len(findings))
+	for i, f := range findings {
+		items[i] = matchItem(f)
+	}
+	baseItems := make([]match.Item, len(base))
+	for i, f := range base {
+		baseItems[i] = matchItem(f)
+	}
+
+	diff := &AuditDiff{AuditID: auditID, BaseAuditID: baseID, Changes: []FindingChange{}}
+	matched, baseMatched := map[int]bool{}, map[int]bool{}
+	for _, p := range match.Match(items, baseItems, match.DefaultThreshold) {
+		matched[p.Left], baseMatched[p.Right] = true, true
+		c := FindingChange{Change: ChangePersisting, Finding: findings[p.Left], BaseFinding: base[p.Right], Similarity: p.Score}
+		if c.Finding.Severity != c.BaseFinding.Severity {
+			c.Change = ChangeSeverityChanged
+		}
+		diff.Changes = append(diff.Changes, c)
+	}
+	for i, f := range findings {
+		if !matched[i] {
+			diff.Changes = append(diff.Changes, FindingChange{Change: ChangeNew, Finding: f})
+		}
+	}
+	for i, f := range base {
+		if !baseMatched[i] {
+			diff.Changes = append(diff.Changes, FindingChange{Change: ChangeResolved, BaseFinding: f})
+		}
+	}
+
+	// Group by kind of change, most severe first
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		ci, cj := diff.Changes[i], diff.Changes[j]
+		if ci.Change != cj.Change {
+			return changeOrder[ci.Change] < changeOrder[cj.Change]
+		}
+		return severityWeights[ci.current().Severity] > severityWeights[cj.current().Severity]
+	})
+
+	for _, c := range diff.Changes {
+		switch c.Change {
+		case ChangeNew:
+			diff.Summary.New++
+		case ChangeResolved:
+			diff.Summary.Resolved++
+		case ChangePersisting:
+			diff.Summary.Persisting++
+		case ChangeSeverityChanged:
+			diff.Summary.SeverityChanged++
+		}
+	}
+	return diff, nil
+}
+
+// current returns the finding as it stands: the audit's, or the base audit's when it was resolved
+func (c FindingChange) current() *Finding {
+	if c.Finding != nil {
+		return c.Finding
+	}
+	return c.BaseFinding
+}
+
+// diffFindings returns an audit's distinct findings that weren't dismissed as false positives
+func (a *App) diffFindings(auditID string) ([]*Finding, error) {
+	rows, err := a.DB.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.audit_id = ? AND findings.status != 'false_positive' AND `+canonicalFinding+`
+		ORDER BY findings.rowid ASC
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []*Finding
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+	return findings, rows.Err()
+}
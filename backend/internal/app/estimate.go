@@ -0,0 +1,231 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+)
+
+// Heuristics used to estimate audit cost and duration before it runs
+const (
+	charsPerToken = 4 // rough average for Solidity/Vyper source
+
+	estPassesLow  = 1 // times the source is sent to the model
+	estPassesHigh = 3
+
+	estCompletionRatioLow  = 0.05 // completion tokens as a share of prompt tokens
+	estCompletionRatioHigh = 0.20
+	estMinCompletionTokens = 1000
+
+	estToolCallsPerServerLow  = 3
+	estToolCallsPerServerHigh = 15
+	estTokensPerToolCall      = 1500 // prompt tokens added by each tool call result
+
+	estPromptTokensPerSecond = 2000
+	estOutputTokensPerSecond = 50
+	estSecondsPerToolCall    = 5
+)
+
+var errNoAuditSource = errors.New("audit has no source to estimate")
+
+// Range represents a low/high estimate
+type Range struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// AgentEstimate represents the expected cost of running one agent in one stage of an audit
+type AgentEstimate struct {
+	AgentID          string `json:"agent_id"`
+	Stage            int    `json:"stage"`
+	Name             string `json:"name"`
+	Model            string `json:"model"`
+	Priced           bool   `json:"priced"`
+	PromptTokens     Range  `json:"prompt_tokens"`
+	CompletionTokens Range  `json:"completion_tokens"`
+	ToolCalls        Range  `json:"tool_calls"`
+	CostUSD          Range  `json:"cost_usd"`
+	DurationSeconds  Range  `json:"duration_seconds"`
+}
+
+// AuditEstimate represents the expected cost and duration of an audit
+type AuditEstimate struct {
+	AuditID         string          `json:"audit_id"`
+	SourceTokens    int64           `json:"source_tokens"`
+	Agents          []AgentEstimate `json:"agents"`
+	CostUSD         Range           `json:"cost_usd"`
+	DurationSeconds Range           `json:"duration_seconds"` // a stage's agents run in parallel, stages one after another
+	UnpricedModels  []string        `json:"unpriced_models,omitempty"`
+	CostCeilingUSD  *float64        `json:"cost_ceiling_usd,omitempty"`
+	ExceedsCeiling  bool            `json:"exceeds_ceiling"`
+}
+
+// handleGetAuditEstimate returns the expected cost and duration of running an audit
+func (a *App) handleGetAuditEstimate(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/estimate
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	var ownerAddress string
+	err := a.DB.QueryRow(`SELECT owner_address FROM audits WHERE id = ?`, id).Scan(&ownerAddress)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Audit not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if ownerAddress != address {
+		httpErr(w, 404, "Audit not found")
+		return
+	}
+
+	estimate, err := a.estimateAudit(id, address)
+	if errors.Is(err, errNoAuditSource) {
+		httpErr(w, 400, "Audit source is not available yet")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, estimate)
+}
+
+// auditSourceSize returns the size in bytes of the source an audit will analyze
+func (a *App) auditSourceSize(auditID string) (int64, error) {
+	var size int64
+	err := a.DB.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM source_files WHERE audit_id = ?`, auditID).Scan(&size)
+	return size, err
+}
+
+// estimateAudit sizes the audit source and projects it across the runs starting the audit would queue: one per
+// agent and stage, leaving out archived agents and those that already completed their stage, so a retry is
+// estimated by the runs it re-queues
+func (a *App) estimateAudit(auditID, ownerAddress string) (*AuditEstimate, error) {
+	size, err := a.auditSourceSize(auditID)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, errNoAuditSource
+	}
+
+	var agentsJSON string
+	var stagesJSON sql.NullString
+	err = a.DB.QueryRow(`SELECT agents_used, pipeline_stages FROM audits WHERE id = ?`, auditID).Scan(&agentsJSON, &stagesJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &AuditEstimate{
+		AuditID:      auditID,
+		SourceTokens: size / charsPerToken,
+		Agents:       []AgentEstimate{},
+	}
+
+	unpriced := map[string]bool{}
+	for stage, s := range auditStages(stagesJSON, agentsJSON) {
+		var stageDuration Range
+		for _, agentID := range s.Agents {
+			var name, model, systemPrompt, mcpJSON string
+			var completed bool
+			err := a.DB.QueryRow(`
+				SELECT name, model, system_prompt, mcp_servers,
+				       EXISTS (SELECT 1 FROM agent_runs
+				               WHERE audit_id = ? AND agent_id = agents.id AND stage = ? AND status = 'completed')
+				FROM agents WHERE id = ? AND archived_at IS NULL
+			`, auditID, stage, agentID).Scan(&name, &model, &systemPrompt, &mcpJSON, &completed)
+			if errors.Is(err, sql.ErrNoRows) || completed {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			var mcpServers []string
+			if err := json.Unmarshal([]byte(mcpJSON), &mcpServers); err != nil {
+				mcpServers = []string{}
+			}
+
+			est := estimateAgentRun(estimate.SourceTokens, int64(len(systemPrompt))/charsPerToken, len(mcpServers))
+			est.AgentID = agentID
+			est.Stage = stage
+			est.Name = name
+			est.Model = model
+
+			if m, ok := a.lookupModel(model); ok {
+				low, okLow := m.Pricing.Cost(int64(est.PromptTokens.Low), int64(est.CompletionTokens.Low))
+				high, okHigh := m.Pricing.Cost(int64(est.PromptTokens.High), int64(est.CompletionTokens.High))
+				est.Priced = okLow && okHigh
+				est.CostUSD = Range{Low: low, High: high}
+			}
+			if !est.Priced && !unpriced[model] {
+				unpriced[model] = true
+				estimate.UnpricedModels = append(estimate.UnpricedModels, model)
+			}
+
+			estimate.CostUSD.Low += est.CostUSD.Low
+			estimate.CostUSD.High += est.CostUSD.High
+			stageDuration.Low = max(stageDuration.Low, est.DurationSeconds.Low)
+			stageDuration.High = max(stageDuration.High, est.DurationSeconds.High)
+			estimate.Agents = append(estimate.Agents, est)
+		}
+
+		// A stage starts once the stages it depends on finished
+		estimate.DurationSeconds.Low += stageDuration.Low
+		estimate.DurationSeconds.High += stageDuration.High
+	}
+
+	settings, err := a.getUserSettings(ownerAddress)
+	if err != nil {
+		return nil, err
+	}
+	if settings.CostCeilingUSD != nil {
+		estimate.CostCeilingUSD = settings.CostCeilingUSD
+		estimate.ExceedsCeiling = estimate.CostUSD.High > *settings.CostCeilingUSD
+	}
+
+	return estimate, nil
+}
+
+// estimateAgentRun projects token usage, tool calls and duration of a single agent run
+func estimateAgentRun(sourceTokens, systemPromptTokens int64, mcpServers int) AgentEstimate {
+	toolCalls := Range{
+		Low:  float64(mcpServers * estToolCallsPerServerLow),
+		High: float64(mcpServers * estToolCallsPerServerHigh),
+	}
+
+	context := float64(sourceTokens + systemPromptTokens)
+	prompt := Range{
+		Low:  context*estPassesLow + toolCalls.Low*estTokensPerToolCall,
+		High: context*estPassesHigh + toolCalls.High*estTokensPerToolCall,
+	}
+	completion := Range{
+		Low:  math.Ceil(max(prompt.Low*estCompletionRatioLow, estMinCompletionTokens)),
+		High: math.Ceil(max(prompt.High*estCompletionRatioHigh, estMinCompletionTokens)),
+	}
+
+	return AgentEstimate{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		ToolCalls:        toolCalls,
+		DurationSeconds: Range{
+			Low:  prompt.Low/estPromptTokensPerSecond + completion.Low/estOutputTokensPerSecond + toolCalls.Low*estSecondsPerToolCall,
+			High: prompt.High/estPromptTokensPerSecond + completion.High/estOutputTokensPerSecond + toolCalls.High*estSecondsPerToolCall,
+		},
+	}
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Audit event types streamed by GET /audits/{id}/events
+const (
+	EventAgentStarted         = "agent.started"
+	EventAgentFinished        = "agent.finished"
+	EventToolCall             = "tool.call"
+	EventFindingCreated       = "finding.created"
+	EventStatusChanged        = "status.changed"
+	EventCostUpdated          = "cost.updated"
+	EventVerificationFinished = "verification.finished"
+)
+
+// eventKeepalive is how often an idle stream gets a comment line, so proxies don't drop it
+const eventKeepalive = 15 * time.Second
+
+// eventBatchSize caps how many stored events are read at once when a stream catches up
+const eventBatchSize = 500
+
+// AuditEvent represents one persisted progress event of an audit
+type AuditEvent struct {
+	ID        int64           `json:"id"`
+	AuditID   string          `json:"audit_id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// findingEvent is the payload of finding.created events
+type findingEvent struct {
+	FindingID string `json:"finding_id"`
+	AgentID   string `json:"agent_id"`
+	Stage     int    `json:"stage"`
+	Title     string `json:"title"`
+	Severity  string `json:"severity"`
+}
+
+// dbtx is implemented by both *sql.DB and *sql.Tx
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// eventHub wakes up the streams of an audit when events are recorded for it.
+// The events themselves are read from audit_events, so a missed wake-up only delays delivery.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+// subscribe returns a channel signalled after events are recorded for auditID, and a function releasing it
+func (h *eventHub) subscribe(auditID string) (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = map[string]map[chan struct{}]struct{}{}
+	}
+	if h.subs[auditID] == nil {
+		h.subs[auditID] = map[chan struct{}]struct{}{}
+	}
+	h.subs[auditID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs[auditID], ch)
+		if len(h.subs[auditID]) == 0 {
+			delete(h.subs, auditID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// notify wakes up the streams of an audit. Call it once the recorded events are committed.
+func (h *eventHub) notify(auditID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[auditID] {
+		select {
+		case ch <- struct{}{}:
+		default: // already pending
+		}
+	}
+}
+
+// recordAuditEvent appends an event to an audit's log and queues it for the webhooks subscribed to it.
+// Run it in the transaction making the change it describes, then notify a.events after committing.
+func recordAuditEvent(q dbtx, auditID, typ string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`
+		INSERT INTO audit_events (audit_id, type, data, created_at)
+		VALUES (?, ?, ?, ?)
+	`, auditID, typ, string(b), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return enqueueWebhooks(q, auditID, typ, data)
+}
+
+// handleGetAuditEvents streams an audit's progress as Server-Sent Events. Clients resume after the
+// event in the Last-Event-ID header (or last_event_id query parameter); without it the whole log is replayed.
+func (a *App) handleGetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/events
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var after int64
+	if lastID != "" {
+		n, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || n < 0 {
+			httpErr(w, 400, "Last-Event-ID must be an event ID")
+			return
+		}
+		after = n
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	// Subscribe before reading the backlog so no event falls in between
+	wake, unsubscribe := a.events.subscribe(id)
+	defer unsubscribe()
+
+	// WithJSON set application/json; this response is a stream
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		events, err := a.getAuditEvents(id, after)
+		if err != nil {
+			return
+		}
+		for _, e := range events {
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+			after = e.ID
+		}
+		if len(events) > 0 {
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			if len(events) == eventBatchSize {
+				continue
+			}
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// getAuditEvents returns the next batch of an audit's events after the given event ID
+func (a *App) getAuditEvents(auditID string, after int64) ([]AuditEvent, error) {
+	rows, err := a.DB.Query(`
+		SELECT id, audit_id, type, data, created_at
+		FROM audit_events
+		WHERE audit_id = ? AND id > ?
+		ORDER BY id ASC
+		LIMIT ?
+	`, auditID, after, eventBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []AuditEvent{}
+	for rows.Next() {
+		var e AuditEvent
+		var data string
+		if err := rows.Scan(&e.ID, &e.AuditID, &e.Type, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Data = json.RawMessage(data)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,380 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Finding represents a vulnerability reported by an agent
+type Finding struct {
+	ID             string          `json:"id"`
+	AuditID        string          `json:"audit_id"`
+	AgentID        string          `json:"agent_id"`
+	AgentName      string          `json:"agent_name,omitempty"`
+	Stage          int             `json:"stage"`
+	Title          string          `json:"title"`
+	Description    string          `json:"description"`
+	Severity       string          `json:"severity"`
+	Status         string          `json:"status"`
+	Assignee       string          `json:"assignee,omitempty"`
+	ClusterID      string          `json:"cluster_id,omitempty"`
+	Location       FindingLocation `json:"location"`
+	Recommendation string          `json:"recommendation,omitempty"`
+	CodeSnippet    string          `json:"code_snippet,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// FindingLocation represents where in the source a finding was reported
+type FindingLocation struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Function string `json:"function,omitempty"`
+}
+
+// findingColumns lists the columns read by scanFinding, in order. Queries must select FROM findingsFrom.
+const findingColumns = `findings.id, findings.audit_id, findings.agent_id, COALESCE(agents.name, ''), findings.stage,
+	findings.title, findings.description, findings.severity, findings.status, findings.assignee, findings.cluster_id, findings.location_file,
+	findings.location_line, findings.location_function, findings.recommendation, findings.code_snippet,
+	findings.created_at`
+
+// findingsFrom joins findings with the agents that produced them, including archived agents
+const findingsFrom = `findings LEFT JOIN agents ON agents.id = findings.agent_id`
+
+// validSeverities are the severities a finding can have
+var validSeverities = map[string]bool{
+	"critical": true,
+	"high":     true,
+	"medium":   true,
+	"low":      true,
+	"info":     true,
+}
+
+// findingSorts maps sort keys to the SQL expression findings are ordered by.
+// Ties are broken by rowid, which follows creation order.
+var findingSorts = map[string]string{
+	"created_at": `findings.rowid`,
+	"severity":   `CASE findings.severity WHEN 'critical' THEN 4 WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END`,
+	"location":   `printf('%s:%010d', COALESCE(findings.location_file, ''), COALESCE(findings.location_line, 0))`,
+}
+
+// findingsCursor marks the position after the last finding of a page
+type findingsCursor struct {
+	Key   interface{} `json:"k"`
+	RowID int64       `json:"r"`
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFinding reads a finding selected with findingColumns
+func scanFinding(row rowScanner, extra ...interface{}) (*Finding, error) {
+	var f Finding
+	var assignee, clusterID, file, function, recommendation, snippet sql.NullString
+	var line sql.NullInt64
+
+	dest := []interface{}{
+		&f.ID,
+		&f.AuditID,
+		&f.AgentID,
+		&f.AgentName,
+		&f.Stage,
+		&f.Title,
+		&f.Description,
+		&f.Severity,
+		&f.Status,
+		&assignee,
+		&clusterID,
+		&file,
+		&line,
+		&function,
+		&recommendation,
+		&snippet,
+		&f.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	f.Assignee = assignee.String
+	f.ClusterID = clusterID.String
+	f.Location = FindingLocation{File: file.String, Line: int(line.Int64), Function: function.String}
+	f.Recommendation = recommendation.String
+	f.CodeSnippet = snippet.String
+	return &f, nil
+}
+
+// handleGetFindings returns the findings of an audit.
+// Supports severity, agent_id, status and assignee filters (comma-separated for several values),
+// canonical=true to hide duplicates of clustered findings, sort (severity, created_at, location), order (asc, desc) and cursor pagination.
+func (a *App) handleGetFindings(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/findings
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	q := r.URL.Query()
+
+	sortBy := q.Get("sort")
+	if sortBy == "" {
+		sortBy = "severity"
+	}
+	sortExpr, ok := findingSorts[sortBy]
+	if !ok {
+		httpErr(w, 400, "sort must be one of: severity, created_at, location")
+		return
+	}
+
+	order := q.Get("order")
+	if order == "" {
+		order = "desc"
+	}
+	if order != "asc" && order != "desc" {
+		httpErr(w, 400, "order must be asc or desc")
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	query := `SELECT ` + findingColumns + `, findings.rowid, ` + sortExpr + `
+		FROM ` + findingsFrom + `
+		WHERE findings.audit_id = ?`
+	args := []interface{}{id}
+
+	severities := splitParam(q.Get("severity"))
+	for _, s := range severities {
+		if !validSeverities[s] {
+			httpErr(w, 400, "severity must be one of: critical, high, medium, low, info")
+			return
+		}
+	}
+	query, args = appendIn(query, args, "findings.severity", severities)
+	query, args = appendIn(query, args, "findings.agent_id", splitParam(q.Get("agent_id")))
+	query, args = appendIn(query, args, "findings.status", splitParam(q.Get("status")))
+	query, args = appendIn(query, args, "findings.assignee", splitParam(strings.ToLower(q.Get("assignee"))))
+	if q.Get("canonical") == "true" {
+		query += ` AND ` + canonicalFinding
+	}
+
+	cmp := "<"
+	if order == "asc" {
+		cmp = ">"
+	}
+	if c := q.Get("cursor"); c != "" {
+		cur, err := decodeFindingsCursor(c)
+		if err != nil {
+			httpErr(w, 400, "bad cursor")
+			return
+		}
+		query += ` AND (` + sortExpr + ` ` + cmp + ` ? OR (` + sortExpr + ` = ? AND findings.rowid ` + cmp + ` ?))`
+		args = append(args, cur.Key, cur.Key, cur.RowID)
+	}
+
+	query += ` ORDER BY ` + sortExpr + ` ` + order + `, findings.rowid ` + order + ` LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := a.DB.Query(query, args...)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	findings := []Finding{}
+	var last findingsCursor
+	more := false
+	for rows.Next() {
+		// One row beyond the limit is fetched to know whether there is a next page
+		if len(findings) == limit {
+			more = true
+			break
+		}
+		var cur findingsCursor
+		f, err := scanFinding(rows, &cur.RowID, &cur.Key)
+		if err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		findings = append(findings, *f)
+		last = cur
+	}
+
+	res := map[string]interface{}{"findings": findings}
+	if more {
+		res["next_cursor"] = encodeFindingsCursor(last)
+	}
+
+	writeJSON(w, 200, res)
+}
+
+// handleGetFinding returns a specific finding by ID, to the owner of its audit or its assignee
+func (a *App) handleGetFinding(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /findings/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing finding ID")
+		return
+	}
+
+	f, _, err := a.getFinding(id, address)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Finding not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, f)
+}
+
+// handleGetAssignedFindings returns the findings assigned to the authenticated user across all audits,
+// most severe first
+func (a *App) handleGetAssignedFindings(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.assignee = ?
+		ORDER BY `+findingSorts["severity"]+` DESC, findings.created_at DESC, findings.rowid ASC
+	`, address)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	findings := []Finding{}
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		findings = append(findings, *f)
+	}
+
+	writeJSON(w, 200, map[string][]Finding{"findings": findings})
+}
+
+// getFinding loads a finding, returning sql.ErrNoRows unless it belongs to an audit owned by address or is
+// assigned to address. owned reports whether address owns the audit rather than only being assigned to it.
+func (a *App) getFinding(id, address string) (f *Finding, owned bool, err error) {
+	var ownerAddress string
+	f, err = scanFinding(a.DB.QueryRow(`
+		SELECT `+findingColumns+`, audits.owner_address
+		FROM `+findingsFrom+`
+		JOIN audits ON audits.id = findings.audit_id
+		WHERE findings.id = ?
+	`, id), &ownerAddress)
+	if err != nil {
+		return nil, false, err
+	}
+	if ownerAddress != address && f.Assignee != address {
+		return nil, false, sql.ErrNoRows
+	}
+	return f, ownerAddress == address, nil
+}
+
+// checkAuditAccess writes an error and returns false unless the audit exists and is owned by address
+func (a *App) checkAuditAccess(w http.ResponseWriter, auditID, address string) bool {
+	var ownerAddress string
+	err := a.DB.QueryRow(`SELECT owner_address FROM audits WHERE id = ?`, auditID).Scan(&ownerAddress)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Audit not found")
+		return false
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return false
+	}
+	if ownerAddress != address {
+		httpErr(w, 404, "Audit not found")
+		return false
+	}
+	return true
+}
+
+// splitParam splits a comma-separated query parameter, dropping empty values
+func splitParam(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// appendIn adds an "AND column IN (...)" filter to query when values is non-empty
+func appendIn(query string, args []interface{}, column string, values []string) (string, []interface{}) {
+	if len(values) == 0 {
+		return query, args
+	}
+	query += ` AND ` + column + ` IN (` + strings.TrimSuffix(strings.Repeat("?,", len(values)), ",") + `)`
+	for _, v := range values {
+		args = append(args, v)
+	}
+	return query, args
+}
+
+func encodeFindingsCursor(c findingsCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeFindingsCursor(s string) (findingsCursor, error) {
+	var c findingsCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return c, err
+	}
+	// Numeric sort keys must be bound as integers to compare with the SQL expression
+	if n, ok := c.Key.(json.Number); ok {
+		i, err := n.Int64()
+		if err != nil {
+			return c, err
+		}
+		c.Key = i
+	}
+	return c, nil
+}
@@ -14,7 +14,10 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/google/uuid"
 
+	"watson/internal/attest"
 	"watson/internal/auth"
+	"watson/internal/bytecode"
+	"watson/internal/source"
 )
 
 type App struct {
@@ -26,6 +29,33 @@ type App struct {
 	CookieName string
 	NonceTTL   time.Duration
 	SessTTL    time.Duration
+	ModelsURL  string
+
+	// Reports are downloaded through links signed with ReportSecret, under PublicURL
+	PublicURL    string
+	ReportSecret []byte
+	ReportURLTTL time.Duration
+
+	// Finalized reports are signed by Signer and, when EAS is set, attested on chain
+	Signer *attest.Signer
+	EAS    *attest.EAS
+
+	// Audits of a github_url snapshot the repository through GitHub
+	GitHub *source.GitHub
+	// Audits of only a contract_address take its verified source from the chain's block explorer
+	Explorer *source.Explorer
+	// Chains audits can target, with the RPC and explorer each uses
+	Chains *ChainRegistry
+	// Audits of a contract_address check it runs the snapshot's code by compiling it with Solc, when set
+	Solc *bytecode.Solc
+	// Webhooks may target private, loopback and link-local addresses only when WebhookAllowPrivate is set,
+	// e.g. for local testing
+	WebhookAllowPrivate bool
+	// The agent runner reports on the runs it executes with RunnerToken as a bearer token
+	RunnerToken string
+
+	models modelCache
+	events eventHub
 }
 
 func (a *App) Routes(mux *http.ServeMux) {
@@ -41,15 +71,105 @@ func (a *App) Routes(mux *http.ServeMux) {
 	mux.Handle("POST /agents", a.authMiddleware(http.HandlerFunc(a.handleCreateAgent)))
 	mux.Handle("PUT /agents/{id}", a.authMiddleware(http.HandlerFunc(a.handleUpdateAgent)))
 	mux.Handle("DELETE /agents/{id}", a.authMiddleware(http.HandlerFunc(a.handleDeleteAgent)))
+	mux.Handle("POST /agents/{id}/restore", a.authMiddleware(http.HandlerFunc(a.handleRestoreAgent)))
+	mux.Handle("GET /agents/stats", a.authMiddleware(http.HandlerFunc(a.handleGetAgentRanking)))
+	mux.Handle("GET /agents/{id}/stats", a.authMiddleware(http.HandlerFunc(a.handleGetAgentStats)))
 
 	// MCP servers endpoint (authentication required)
 	mux.Handle("GET /mcp-servers", a.authMiddleware(http.HandlerFunc(a.handleGetMCPServers)))
 
+	// Model endpoint (authentication required)
+	mux.Handle("GET /models", a.authMiddleware(http.HandlerFunc(a.handleGetModels)))
+	mux.Handle("GET /chains", a.authMiddleware(http.HandlerFunc(a.handleGetChains)))
+
+	// Usage endpoints (authentication required)
+	mux.Handle("GET /usage", a.authMiddleware(http.HandlerFunc(a.handleGetUsage)))
+	mux.Handle("GET /usage/monthly", a.authMiddleware(http.HandlerFunc(a.handleGetMonthlyUsage)))
+
+	// Settings endpoints (authentication required)
+	mux.Handle("GET /settings", a.authMiddleware(http.HandlerFunc(a.handleGetSettings)))
+	mux.Handle("PUT /settings", a.authMiddleware(http.HandlerFunc(a.handleUpdateSettings)))
+
 	// Audit endpoints (authentication required)
 	mux.Handle("GET /audits", a.authMiddleware(http.HandlerFunc(a.handleGetAudits)))
 	mux.Handle("GET /audits/{id}", a.authMiddleware(http.HandlerFunc(a.handleGetAudit)))
 	mux.Handle("POST /audits", a.authMiddleware(http.HandlerFunc(a.handleCreateAudit)))
+	mux.Handle("GET /audits/{id}/estimate", a.authMiddleware(http.HandlerFunc(a.handleGetAuditEstimate)))
+	mux.Handle("GET /audits/{id}/files", a.authMiddleware(http.HandlerFunc(a.handleGetAuditFiles)))
+	mux.Handle("GET /audits/{id}/files/{path...}", a.authMiddleware(http.HandlerFunc(a.handleGetAuditFile)))
 	mux.Handle("POST /audits/{id}/start", a.authMiddleware(http.HandlerFunc(a.handleStartAudit)))
+	mux.Handle("POST /audits/{id}/cancel", a.authMiddleware(http.HandlerFunc(a.handleCancelAudit)))
+	mux.Handle("POST /audits/{id}/retry", a.authMiddleware(http.HandlerFunc(a.handleRetryAudit)))
+	mux.Handle("POST /audits/{id}/verification", a.authMiddleware(http.HandlerFunc(a.handleVerifyAudit)))
+	mux.Handle("DELETE /audits/{id}", a.authMiddleware(http.HandlerFunc(a.handleDeleteAudit)))
+	mux.Handle("GET /audits/{id}/events", a.authMiddleware(http.HandlerFunc(a.handleGetAuditEvents)))
+	mux.Handle("GET /audits/{id}/stages/{stage}/input", a.authMiddleware(http.HandlerFunc(a.handleGetStageInput)))
+
+	// Finding endpoints (authentication required)
+	mux.Handle("GET /audits/{id}/findings", a.authMiddleware(http.HandlerFunc(a.handleGetFindings)))
+	mux.Handle("GET /audits/{id}/findings.sarif", a.authMiddleware(http.HandlerFunc(a.handleGetFindingsSARIF)))
+	mux.Handle("POST /audits/{id}/findings/import", a.authMiddleware(http.HandlerFunc(a.handleImportFindings)))
+	mux.Handle("GET /findings/assigned", a.authMiddleware(http.HandlerFunc(a.handleGetAssignedFindings)))
+	mux.Handle("GET /findings/{id}", a.authMiddleware(http.HandlerFunc(a.handleGetFinding)))
+	mux.Handle("GET /audits/{id}/clusters", a.authMiddleware(http.HandlerFunc(a.handleGetClusters)))
+	mux.Handle("POST /audits/{id}/clusters", a.authMiddleware(http.HandlerFunc(a.handleClusterFindings)))
+	mux.Handle("GET /audits/{id}/diff/{otherId}", a.authMiddleware(http.HandlerFunc(a.handleGetAuditDiff)))
+
+	// Report endpoints (authentication required, except for signed downloads)
+	mux.Handle("POST /audits/{id}/report", a.authMiddleware(http.HandlerFunc(a.handleCreateReport)))
+	mux.Handle("GET /audits/{id}/reports", a.authMiddleware(http.HandlerFunc(a.handleGetReports)))
+	mux.Handle("GET /reports/{id}", a.authMiddleware(http.HandlerFunc(a.handleGetReport)))
+	mux.HandleFunc("GET /reports/{id}/download", a.handleDownloadReport)
+	mux.Handle("POST /reports/{id}/finalize", a.authMiddleware(http.HandlerFunc(a.handleFinalizeReport)))
+	mux.HandleFunc("GET /reports/signer", a.handleGetReportSigner)
+	mux.HandleFunc("POST /reports/verify", a.handleVerifyReport)
+	mux.Handle("GET /report-templates", a.authMiddleware(http.HandlerFunc(a.handleGetReportTemplates)))
+	mux.Handle("GET /report-templates/{id}", a.authMiddleware(http.HandlerFunc(a.handleGetReportTemplate)))
+	mux.Handle("POST /report-templates", a.authMiddleware(http.HandlerFunc(a.handleCreateReportTemplate)))
+	mux.Handle("PUT /report-templates/{id}", a.authMiddleware(http.HandlerFunc(a.handleUpdateReportTemplate)))
+	mux.Handle("DELETE /report-templates/{id}", a.authMiddleware(http.HandlerFunc(a.handleDeleteReportTemplate)))
+
+	// Triage endpoints (authentication required)
+	mux.Handle("PUT /findings/{id}/status", a.authMiddleware(http.HandlerFunc(a.handleUpdateFindingStatus)))
+	mux.Handle("PUT /findings/{id}/assignee", a.authMiddleware(http.HandlerFunc(a.handleUpdateFindingAssignee)))
+	mux.Handle("GET /findings/{id}/history", a.authMiddleware(http.HandlerFunc(a.handleGetFindingHistory)))
+	mux.Handle("GET /findings/{id}/comments", a.authMiddleware(http.HandlerFunc(a.handleGetFindingComments)))
+	mux.Handle("POST /findings/{id}/comments", a.authMiddleware(http.HandlerFunc(a.handleCreateFindingComment)))
+
+	// Webhook endpoints (authentication required)
+	mux.Handle("GET /webhooks", a.authMiddleware(http.HandlerFunc(a.handleGetWebhooks)))
+	mux.Handle("GET /webhooks/{id}", a.authMiddleware(http.HandlerFunc(a.handleGetWebhook)))
+	mux.Handle("POST /webhooks", a.authMiddleware(http.HandlerFunc(a.handleCreateWebhook)))
+	mux.Handle("PUT /webhooks/{id}", a.authMiddleware(http.HandlerFunc(a.handleUpdateWebhook)))
+	mux.Handle("DELETE /webhooks/{id}", a.authMiddleware(http.HandlerFunc(a.handleDeleteWebhook)))
+	mux.Handle("GET /webhooks/{id}/deliveries", a.authMiddleware(http.HandlerFunc(a.handleGetWebhookDeliveries)))
+	mux.Handle("POST /webhooks/{id}/deliveries/{deliveryId}/redeliver", a.authMiddleware(http.HandlerFunc(a.handleRedeliverWebhook)))
+
+	// Project endpoints (authentication required)
+	mux.Handle("GET /projects", a.authMiddleware(http.HandlerFunc(a.handleGetProjects)))
+	mux.Handle("GET /projects/{id}", a.authMiddleware(http.HandlerFunc(a.handleGetProject)))
+	mux.Handle("POST /projects", a.authMiddleware(http.HandlerFunc(a.handleCreateProject)))
+	mux.Handle("PUT /projects/{id}", a.authMiddleware(http.HandlerFunc(a.handleUpdateProject)))
+	mux.Handle("DELETE /projects/{id}", a.authMiddleware(http.HandlerFunc(a.handleDeleteProject)))
+	mux.Handle("GET /projects/{id}/findings", a.authMiddleware(http.HandlerFunc(a.handleGetProjectFindings)))
+	mux.Handle("GET /projects/{id}/trend", a.authMiddleware(http.HandlerFunc(a.handleGetProjectTrend)))
+
+	// Pipeline endpoints (authentication required)
+	mux.Handle("GET /pipelines", a.authMiddleware(http.HandlerFunc(a.handleGetPipelines)))
+	mux.Handle("GET /pipelines/{id}", a.authMiddleware(http.HandlerFunc(a.handleGetPipeline)))
+	mux.Handle("POST /pipelines", a.authMiddleware(http.HandlerFunc(a.handleCreatePipeline)))
+	mux.Handle("PUT /pipelines/{id}", a.authMiddleware(http.HandlerFunc(a.handleUpdatePipeline)))
+	mux.Handle("DELETE /pipelines/{id}", a.authMiddleware(http.HandlerFunc(a.handleDeletePipeline)))
+
+	// Agent runner endpoints (runner token required)
+	mux.Handle("GET /runs", a.runnerMiddleware(http.HandlerFunc(a.handleGetQueuedRuns)))
+	mux.Handle("GET /runs/{id}/input", a.runnerMiddleware(http.HandlerFunc(a.handleGetRunInput)))
+	mux.Handle("POST /runs/{id}/start", a.runnerMiddleware(http.HandlerFunc(a.handleStartRun)))
+	mux.Handle("POST /runs/{id}/tool-calls", a.runnerMiddleware(http.HandlerFunc(a.handleRecordToolCall)))
+	mux.Handle("POST /runs/{id}/usage", a.runnerMiddleware(http.HandlerFunc(a.handleRecordRunUsage)))
+	mux.Handle("POST /runs/{id}/findings", a.runnerMiddleware(http.HandlerFunc(a.handleRecordRunFindings)))
+	mux.Handle("POST /runs/{id}/verdicts", a.runnerMiddleware(http.HandlerFunc(a.handleRecordVerdict)))
+	mux.Handle("POST /runs/{id}/finish", a.runnerMiddleware(http.HandlerFunc(a.handleFinishRun)))
 }
 
 // ---------- Handlers ----------
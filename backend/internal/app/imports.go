@@ -0,0 +1,155 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"watson/internal/importer"
+)
+
+// maxImportSize caps the size of an uploaded analyzer report
+const maxImportSize = 20 << 20
+
+// toolAgents are the synthetic agents imported findings are attributed to, by analyzer (seeded by 0013_tool_agents.sql)
+var toolAgents = map[string]string{
+	importer.Slither: "fd0da4d9-a532-4d10-aa74-a191189e950c",
+	importer.Aderyn:  "e95a17b0-3605-4c65-b897-b5b1fb913a90",
+	importer.Mythril: "473f2362-8d46-464d-9418-8ac4455f60d6",
+	importer.SARIF:   "c634b5c6-973f-403f-ab99-5bad39cb3dd7",
+}
+
+// validImportFormats are the analyzer output formats that can be imported
+var validImportFormats = map[string]bool{
+	importer.Slither:  true,
+	importer.Aderyn:   true,
+	importer.AderynMD: true,
+	importer.Mythril:  true,
+	importer.SARIF:    true,
+}
+
+// handleImportFindings adds the findings of a static analyzer report, sent as the request body, to an audit.
+// format (slither, aderyn, aderyn-md, mythril, sarif) is detected when omitted; replace=true first removes
+// findings previously imported from the same analyzer.
+func (a *App) handleImportFindings(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/findings/import
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && !validImportFormats[format] {
+		httpErr(w, 400, "format must be one of: slither, aderyn, aderyn-md, mythril, sarif")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		httpErr(w, 413, "report must be at most 20MB")
+		return
+	}
+
+	findings, format, err := importer.Parse(format, data)
+	if errors.Is(err, importer.ErrUnknownFormat) {
+		httpErr(w, 400, "could not detect the report format, pass format=slither, aderyn, aderyn-md, mythril or sarif")
+		return
+	}
+	if err != nil {
+		httpErr(w, 400, "could not parse report: "+err.Error())
+		return
+	}
+
+	agentID := toolAgents[importer.Tool(format)]
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer tx.Rollback()
+
+	var replaced int64
+	if r.URL.Query().Get("replace") == "true" {
+		res, err := tx.Exec(`DELETE FROM findings WHERE audit_id = ? AND agent_id = ?`, id, agentID)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		replaced, _ = res.RowsAffected()
+	}
+
+	now := time.Now().UTC()
+	for _, f := range findings {
+		if f.Title == "" {
+			f.Title = "Untitled finding"
+		}
+		findingID := uuid.NewString()
+		_, err := tx.Exec(`
+			INSERT INTO findings (id, audit_id, agent_id, title, description, severity, location_file, location_line,
+			                      location_function, recommendation, code_snippet, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, findingID, id, agentID, f.Title, f.Description, f.Severity, nullString(f.File), nullInt(f.Line),
+			nullString(f.Function), nullString(f.Recommendation), nullString(f.CodeSnippet), now)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		err = recordAuditEvent(tx, id, EventFindingCreated, findingEvent{
+			FindingID: findingID, AgentID: agentID, Title: f.Title, Severity: f.Severity,
+		})
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	a.events.notify(id)
+
+	// Imported findings are merged with the agents' through the duplicate clusters
+	if err := a.clusterFindings(id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 201, map[string]interface{}{
+		"format":   format,
+		"agent_id": agentID,
+		"imported": len(findings),
+		"replaced": replaced,
+	})
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullInt stores non-positive numbers as NULL
+func nullInt(n int) interface{} {
+	if n <= 0 {
+		return nil
+	}
+	return n
+}
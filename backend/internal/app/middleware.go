@@ -2,10 +2,12 @@ package app
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"errors"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -40,13 +42,31 @@ func (a *App) authMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// runnerMiddleware checks the request carries the runner's bearer token. Runner endpoints are
+// unavailable when no RunnerToken is configured.
+func (a *App) runnerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.RunnerToken == "" {
+			httpErr(w, 503, "Agent runner is not configured")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.RunnerToken)) != 1 {
+			httpErr(w, 401, "Not authenticated")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // getAuthAddress extracts the authenticated address from request context
 func getAuthAddress(r *http.Request) (string, bool) {
 	addr, ok := r.Context().Value(addressKey).(string)
 	return addr, ok
 }
 
-// WithJSON sets JSON content type header
+// WithJSON sets JSON content type header. Handlers serving other content, such as event streams
+// and report files, override it before writing.
 func WithJSON(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -63,7 +83,7 @@ func WithSecurityHeaders(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Cookie")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Cookie, Last-Event-ID, Authorization")
 
 		// Security headers
 		w.Header().Set("X-Content-Type-Options", "nosniff")
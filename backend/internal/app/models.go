@@ -0,0 +1,123 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// modelsCacheTTL is how long the OpenRouter model list is cached
+const modelsCacheTTL = 24 * time.Hour
+
+// Model represents an AI model available through OpenRouter
+type Model struct {
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	ContextLength int          `json:"context_length"`
+	Pricing       ModelPricing `json:"pricing"`
+}
+
+// ModelPricing holds OpenRouter prices in USD per token
+type ModelPricing struct {
+	Prompt     string `json:"prompt"`
+	Completion string `json:"completion"`
+}
+
+// modelCache caches the model list fetched from OpenRouter
+type modelCache struct {
+	mu        sync.Mutex
+	models    []Model
+	byID      map[string]Model
+	fetchedAt time.Time
+}
+
+// handleGetModels returns the available AI models with their pricing
+func (a *App) handleGetModels(w http.ResponseWriter, r *http.Request) {
+	models, err := a.getModels()
+	if err != nil {
+		httpErr(w, 502, "models unavailable")
+		return
+	}
+
+	writeJSON(w, 200, map[string][]Model{"models": models})
+}
+
+// getModels returns the cached model list, refreshing it once it is older than modelsCacheTTL.
+// A stale list is served if the refresh fails.
+func (a *App) getModels() ([]Model, error) {
+	a.models.mu.Lock()
+	defer a.models.mu.Unlock()
+
+	if a.models.models != nil && time.Since(a.models.fetchedAt) < modelsCacheTTL {
+		return a.models.models, nil
+	}
+
+	models, err := fetchModels(a.ModelsURL)
+	if err != nil {
+		if a.models.models != nil {
+			return a.models.models, nil
+		}
+		return nil, err
+	}
+
+	a.models.models = models
+	a.models.byID = make(map[string]Model, len(models))
+	for _, m := range models {
+		a.models.byID[m.ID] = m
+	}
+	a.models.fetchedAt = time.Now()
+	return models, nil
+}
+
+// lookupModel returns a model by its OpenRouter ID
+func (a *App) lookupModel(id string) (Model, bool) {
+	if _, err := a.getModels(); err != nil {
+		return Model{}, false
+	}
+	a.models.mu.Lock()
+	defer a.models.mu.Unlock()
+	m, ok := a.models.byID[id]
+	return m, ok
+}
+
+func fetchModels(url string) ([]Model, error) {
+	if url == "" {
+		return nil, errors.New("models url not configured")
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("models: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data []Model `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Data == nil {
+		body.Data = []Model{}
+	}
+	return body.Data, nil
+}
+
+// Cost returns the USD cost of a request with the given token counts.
+// ok is false if the model has no parseable pricing.
+func (p ModelPricing) Cost(promptTokens, completionTokens int64) (cost float64, ok bool) {
+	prompt, err1 := strconv.ParseFloat(p.Prompt, 64)
+	completion, err2 := strconv.ParseFloat(p.Completion, 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return prompt*float64(promptTokens) + completion*float64(completionTokens), true
+}
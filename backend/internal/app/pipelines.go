@@ -0,0 +1,618 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxPipelineStages caps the number of stages in a pipeline
+const maxPipelineStages = 10
+
+// validStageRoles are the roles a pipeline stage can have
+var validStageRoles = map[string]bool{
+	"triage":   true, // cheap pass that flags areas of interest
+	"analysis": true, // specialist agents digging into flagged areas
+	"verifier": true, // confirms or rejects candidate findings through POST /runs/{id}/verdicts
+}
+
+// Pipeline represents a reusable, ordered graph of agent stages
+type Pipeline struct {
+	ID           string          `json:"id"`
+	OwnerAddress string          `json:"owner_address"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	Stages       []PipelineStage `json:"stages"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// PipelineStage represents one step of a pipeline. Its agents receive the findings
+// of the stages it depends on as input.
+type PipelineStage struct {
+	Name      string   `json:"name"`
+	Role      string   `json:"role"`
+	Agents    []string `json:"agents"`
+	DependsOn []string `json:"depends_on"` // names of earlier stages; defaults to the previous stage
+}
+
+// CreatePipelineRequest represents the request to create or update a pipeline
+type CreatePipelineRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Stages      []PipelineStage `json:"stages"`
+}
+
+// StageInput represents what the agents of a pipeline stage receive
+type StageInput struct {
+	AuditID        string    `json:"audit_id"`
+	Stage          int       `json:"stage"`
+	Name           string    `json:"name"`
+	Role           string    `json:"role"`
+	Agents         []string  `json:"agents"`
+	UpstreamStages []int     `json:"upstream_stages"`
+	Findings       []Finding `json:"findings"`
+	// What the audited contract's bytecode reveals, the only context on an unverified contract
+	Bytecode []BytecodeInspection `json:"bytecode,omitempty"`
+}
+
+// handleGetPipelines returns all pipelines for the authenticated user
+func (a *App) handleGetPipelines(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT id, owner_address, name, description, stages, created_at, updated_at
+		FROM pipelines
+		WHERE owner_address = ?
+		ORDER BY created_at DESC
+	`, address)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	pipelines := []Pipeline{}
+	for rows.Next() {
+		p, err := scanPipeline(rows)
+		if err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		pipelines = append(pipelines, *p)
+	}
+
+	writeJSON(w, 200, map[string][]Pipeline{"pipelines": pipelines})
+}
+
+// handleGetPipeline returns a specific pipeline by ID
+func (a *App) handleGetPipeline(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /pipelines/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing pipeline ID")
+		return
+	}
+
+	p, err := a.getPipeline(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Pipeline not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	// Check ownership
+	if p.OwnerAddress != address {
+		httpErr(w, 404, "Pipeline not found")
+		return
+	}
+
+	writeJSON(w, 200, p)
+}
+
+// handleCreatePipeline creates a new pipeline
+func (a *App) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	var req CreatePipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		httpErr(w, 400, "name must be 1-100 characters")
+		return
+	}
+
+	stages, msg, err := a.validatePipelineStages(address, req.Stages)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	stagesJSON, err := json.Marshal(stages)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+
+	id := uuid.NewString()
+	now := time.Now().UTC()
+
+	_, err = a.DB.Exec(`
+		INSERT INTO pipelines (id, owner_address, name, description, stages, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, id, address, req.Name, req.Description, string(stagesJSON), now, now)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 201, Pipeline{
+		ID:           id,
+		OwnerAddress: address,
+		Name:         req.Name,
+		Description:  req.Description,
+		Stages:       stages,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+}
+
+// handleUpdatePipeline updates an existing pipeline. Audits already created from it keep their stages.
+func (a *App) handleUpdatePipeline(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /pipelines/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing pipeline ID")
+		return
+	}
+
+	var req CreatePipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	p, err := a.getPipeline(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Pipeline not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if p.OwnerAddress != address {
+		httpErr(w, 403, "Not authorized to modify this pipeline")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		httpErr(w, 400, "name must be 1-100 characters")
+		return
+	}
+
+	stages, msg, err := a.validatePipelineStages(address, req.Stages)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	stagesJSON, err := json.Marshal(stages)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+
+	now := time.Now().UTC()
+	_, err = a.DB.Exec(`
+		UPDATE pipelines
+		SET name = ?, description = ?, stages = ?, updated_at = ?
+		WHERE id = ?
+	`, req.Name, req.Description, string(stagesJSON), now, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	p.Name = req.Name
+	p.Description = req.Description
+	p.Stages = stages
+	p.UpdatedAt = now
+
+	writeJSON(w, 200, p)
+}
+
+// handleDeletePipeline deletes a pipeline. Audits created from it keep their copy of the stages.
+func (a *App) handleDeletePipeline(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /pipelines/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing pipeline ID")
+		return
+	}
+
+	var ownerAddress string
+	err := a.DB.QueryRow(`SELECT owner_address FROM pipelines WHERE id = ?`, id).Scan(&ownerAddress)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Pipeline not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if ownerAddress != address {
+		httpErr(w, 403, "Not authorized to delete this pipeline")
+		return
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE audits SET pipeline_id = NULL WHERE pipeline_id = ?`, id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if _, err := tx.Exec(`DELETE FROM pipelines WHERE id = ?`, id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, map[string]bool{"success": true})
+}
+
+// handleGetStageInput returns the input the agents of an audit's pipeline stage receive:
+// the findings produced by every stage it (transitively) depends on, minus rejected ones
+func (a *App) handleGetStageInput(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/stages/{stage}/input
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+	stage, err := strconv.Atoi(r.PathValue("stage"))
+	if err != nil || stage < 0 {
+		httpErr(w, 400, "stage must be a non-negative integer")
+		return
+	}
+
+	var ownerAddress, agentsJSON string
+	var stagesJSON sql.NullString
+	err = a.DB.QueryRow(`
+		SELECT owner_address, agents_used, pipeline_stages FROM audits WHERE id = ?
+	`, id).Scan(&ownerAddress, &agentsJSON, &stagesJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Audit not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if ownerAddress != address {
+		httpErr(w, 404, "Audit not found")
+		return
+	}
+
+	stages := auditStages(stagesJSON, agentsJSON)
+	if stage >= len(stages) {
+		httpErr(w, 404, "Stage not found")
+		return
+	}
+
+	input, err := a.stageInput(id, stages, stage)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, input)
+}
+
+// stageInput collects the findings of every stage the given stage transitively depends on. Findings
+// rejected as false positives, by a verifier stage or in triage, are left out.
+func (a *App) stageInput(auditID string, stages []PipelineStage, stage int) (*StageInput, error) {
+	upstream := upstreamStages(stages, stage)
+
+	input := &StageInput{
+		AuditID:        auditID,
+		Stage:          stage,
+		Name:           stages[stage].Name,
+		Role:           stages[stage].Role,
+		Agents:         stages[stage].Agents,
+		UpstreamStages: upstream,
+		Findings:       []Finding{},
+	}
+
+	var bytecodeJSON sql.NullString
+	if err := a.DB.QueryRow(`SELECT bytecode FROM audits WHERE id = ?`, auditID).Scan(&bytecodeJSON); err != nil {
+		return nil, err
+	}
+	if bytecodeJSON.Valid {
+		if err := json.Unmarshal([]byte(bytecodeJSON.String), &input.Bytecode); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(upstream) == 0 {
+		return input, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(upstream)), ",")
+	args := []interface{}{auditID}
+	for _, s := range upstream {
+		args = append(args, s)
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.audit_id = ? AND findings.stage IN (`+placeholders+`) AND findings.status != 'false_positive'
+		ORDER BY findings.stage ASC, findings.created_at ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			return nil, err
+		}
+		input.Findings = append(input.Findings, *f)
+	}
+	return input, rows.Err()
+}
+
+// upstreamStages returns the indexes of all stages the given stage transitively depends on, ascending
+func upstreamStages(stages []PipelineStage, stage int) []int {
+	index := make(map[string]int, len(stages))
+	for i, s := range stages {
+		index[s.Name] = i
+	}
+
+	seen := map[int]bool{}
+	queue := []int{stage}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, dep := range stages[cur].DependsOn {
+			if i, ok := index[dep]; ok && !seen[i] {
+				seen[i] = true
+				queue = append(queue, i)
+			}
+		}
+	}
+
+	upstream := []int{}
+	for i := range stages {
+		if seen[i] {
+			upstream = append(upstream, i)
+		}
+	}
+	return upstream
+}
+
+// stageWaiting reports whether runs of the stages a stage depends on are still queued or running. The stage's
+// runs are held back until then, so they receive every upstream finding as input.
+func stageWaiting(q dbtx, auditID string, stages []PipelineStage, stage int) (bool, error) {
+	if stage < 0 || stage >= len(stages) {
+		return false, fmt.Errorf("audit %s: stage %d out of range", auditID, stage)
+	}
+	upstream := upstreamStages(stages, stage)
+	if len(upstream) == 0 {
+		return false, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(upstream)), ",")
+	args := []interface{}{auditID}
+	for _, s := range upstream {
+		args = append(args, s)
+	}
+
+	var waiting bool
+	err := q.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM agent_runs
+		               WHERE audit_id = ? AND stage IN (`+placeholders+`) AND status IN ('queued', 'running'))
+	`, args...).Scan(&waiting)
+	return waiting, err
+}
+
+// auditStages returns the stages an audit runs. Audits created without a pipeline
+// run all their agents in a single analysis stage.
+func auditStages(stagesJSON sql.NullString, agentsJSON string) []PipelineStage {
+	var stages []PipelineStage
+	if stagesJSON.Valid && stagesJSON.String != "" {
+		if err := json.Unmarshal([]byte(stagesJSON.String), &stages); err == nil && len(stages) > 0 {
+			return stages
+		}
+	}
+
+	var agentIDs []string
+	if err := json.Unmarshal([]byte(agentsJSON), &agentIDs); err != nil {
+		agentIDs = []string{}
+	}
+	return []PipelineStage{{Name: "analysis", Role: "analysis", Agents: agentIDs, DependsOn: []string{}}}
+}
+
+// pipelineAgents returns the distinct agent IDs used across all stages, in stage order
+func pipelineAgents(stages []PipelineStage) []string {
+	seen := map[string]bool{}
+	agents := []string{}
+	for _, s := range stages {
+		for _, id := range s.Agents {
+			if !seen[id] {
+				seen[id] = true
+				agents = append(agents, id)
+			}
+		}
+	}
+	return agents
+}
+
+// validatePipelineStages normalizes stages and checks that they form a valid graph of
+// the user's agents. It returns a non-empty message if the stages are invalid.
+func (a *App) validatePipelineStages(address string, stages []PipelineStage) ([]PipelineStage, string, error) {
+	if len(stages) == 0 || len(stages) > maxPipelineStages {
+		return nil, fmt.Sprintf("stages must contain 1-%d stages", maxPipelineStages), nil
+	}
+
+	index := map[string]int{}
+	normalized := make([]PipelineStage, 0, len(stages))
+	for i, s := range stages {
+		s.Name = strings.TrimSpace(s.Name)
+		s.Role = strings.TrimSpace(s.Role)
+
+		if s.Name == "" || len(s.Name) > 100 {
+			return nil, fmt.Sprintf("stage %d: name must be 1-100 characters", i), nil
+		}
+		if _, dup := index[s.Name]; dup {
+			return nil, fmt.Sprintf("stage %d: duplicate stage name %q", i, s.Name), nil
+		}
+		if s.Role == "" {
+			s.Role = "analysis"
+		}
+		if !validStageRoles[s.Role] {
+			return nil, fmt.Sprintf("stage %d: role must be one of: triage, analysis, verifier", i), nil
+		}
+		if len(s.Agents) == 0 {
+			return nil, fmt.Sprintf("stage %d: at least one agent is required", i), nil
+		}
+
+		// Stages may only depend on earlier stages, which keeps the graph acyclic
+		if s.DependsOn == nil {
+			s.DependsOn = []string{}
+			if i > 0 {
+				s.DependsOn = []string{normalized[i-1].Name}
+			}
+		}
+		for _, dep := range s.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Sprintf("stage %d: depends_on %q must name an earlier stage", i, dep), nil
+			}
+		}
+
+		for _, agentID := range s.Agents {
+			var ownerAddress string
+			var archivedAt sql.NullTime
+			err := a.DB.QueryRow(`SELECT owner_address, archived_at FROM agents WHERE id = ?`, agentID).Scan(&ownerAddress, &archivedAt)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && ownerAddress != address) {
+				return nil, fmt.Sprintf("stage %d: agent %s not found", i, agentID), nil
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			if archivedAt.Valid {
+				return nil, fmt.Sprintf("stage %d: agent %s is archived", i, agentID), nil
+			}
+		}
+
+		index[s.Name] = i
+		normalized = append(normalized, s)
+	}
+
+	return normalized, "", nil
+}
+
+// getPipeline loads a pipeline by ID
+func (a *App) getPipeline(id string) (*Pipeline, error) {
+	return scanPipeline(a.DB.QueryRow(`
+		SELECT id, owner_address, name, description, stages, created_at, updated_at
+		FROM pipelines
+		WHERE id = ?
+	`, id))
+}
+
+func scanPipeline(row rowScanner) (*Pipeline, error) {
+	var p Pipeline
+	var desc sql.NullString
+	var stagesJSON string
+
+	err := row.Scan(
+		&p.ID,
+		&p.OwnerAddress,
+		&p.Name,
+		&desc,
+		&stagesJSON,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if desc.Valid {
+		p.Description = desc.String
+	}
+	if err := json.Unmarshal([]byte(stagesJSON), &p.Stages); err != nil {
+		p.Stages = []PipelineStage{}
+	}
+	return &p, nil
+}
@@ -0,0 +1,578 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+
+	"watson/internal/source"
+)
+
+// maxProjectDeployments caps the number of deployed contracts tracked per project
+const maxProjectDeployments = 50
+
+// Project groups the audits of one codebase: its repository, where it is deployed and the agents that audit it
+type Project struct {
+	ID            string              `json:"id"`
+	OwnerAddress  string              `json:"owner_address"`
+	Name          string              `json:"name"`
+	Description   string              `json:"description,omitempty"`
+	GitHubURL     string              `json:"github_url,omitempty"`
+	Deployments   []ProjectDeployment `json:"deployments"`
+	DefaultAgents []string            `json:"default_agents"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// ProjectDeployment is a contract of a project deployed on a chain
+type ProjectDeployment struct {
+	Blockchain string `json:"blockchain"`
+	Address    string `json:"address"`
+	Label      string `json:"label,omitempty"`
+}
+
+// CreateProjectRequest represents the request to create or update a project
+type CreateProjectRequest struct {
+	Name          string              `json:"name"`
+	Description   string              `json:"description"`
+	GitHubURL     string              `json:"github_url"`
+	Deployments   []ProjectDeployment `json:"deployments"`
+	DefaultAgents []string            `json:"default_agents"`
+}
+
+// ProjectTrendPoint summarizes the findings of one finished audit of a project
+type ProjectTrendPoint struct {
+	AuditID       string         `json:"audit_id"`
+	Name          string         `json:"name"`
+	Status        string         `json:"status"`
+	CompletedAt   *time.Time     `json:"completed_at,omitempty"`
+	FindingsCount *FindingsCount `json:"findings_count"` // confirmed issues, duplicates counted once
+	Total         int            `json:"total"`
+	Untriaged     int            `json:"untriaged"`
+}
+
+// handleGetProjects returns all projects for the authenticated user
+func (a *App) handleGetProjects(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT `+projectColumns+`
+		FROM projects
+		WHERE owner_address = ?
+		ORDER BY created_at DESC
+	`, address)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	projects := []Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		projects = append(projects, *p)
+	}
+
+	writeJSON(w, 200, map[string][]Project{"projects": projects})
+}
+
+// handleGetProject returns a specific project by ID
+func (a *App) handleGetProject(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /projects/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing project ID")
+		return
+	}
+
+	p, ok := a.projectForRead(w, id, address)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, 200, p)
+}
+
+// handleCreateProject creates a new project
+func (a *App) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	var req CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	msg, err := a.validateProject(address, &req)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	deploymentsJSON, err := json.Marshal(req.Deployments)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+	agentsJSON, err := json.Marshal(req.DefaultAgents)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+
+	id := uuid.NewString()
+	now := time.Now().UTC()
+
+	_, err = a.DB.Exec(`
+		INSERT INTO projects (id, owner_address, name, description, github_url, deployments, default_agents, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, address, req.Name, nullString(req.Description), nullString(req.GitHubURL), string(deploymentsJSON), string(agentsJSON), now, now)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 201, Project{
+		ID:            id,
+		OwnerAddress:  address,
+		Name:          req.Name,
+		Description:   req.Description,
+		GitHubURL:     req.GitHubURL,
+		Deployments:   req.Deployments,
+		DefaultAgents: req.DefaultAgents,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+}
+
+// handleUpdateProject updates an existing project. Audits already created in it keep their settings.
+func (a *App) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /projects/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing project ID")
+		return
+	}
+
+	var req CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	p, err := a.getProject(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Project not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if p.OwnerAddress != address {
+		httpErr(w, 403, "Not authorized to modify this project")
+		return
+	}
+
+	msg, err := a.validateProject(address, &req)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	deploymentsJSON, err := json.Marshal(req.Deployments)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+	agentsJSON, err := json.Marshal(req.DefaultAgents)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+
+	now := time.Now().UTC()
+	_, err = a.DB.Exec(`
+		UPDATE projects
+		SET name = ?, description = ?, github_url = ?, deployments = ?, default_agents = ?, updated_at = ?
+		WHERE id = ?
+	`, req.Name, nullString(req.Description), nullString(req.GitHubURL), string(deploymentsJSON), string(agentsJSON), now, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	p.Name = req.Name
+	p.Description = req.Description
+	p.GitHubURL = req.GitHubURL
+	p.Deployments = req.Deployments
+	p.DefaultAgents = req.DefaultAgents
+	p.UpdatedAt = now
+
+	writeJSON(w, 200, p)
+}
+
+// handleDeleteProject deletes a project. Its audits are kept, outside any project.
+func (a *App) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /projects/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing project ID")
+		return
+	}
+
+	var ownerAddress string
+	err := a.DB.QueryRow(`SELECT owner_address FROM projects WHERE id = ?`, id).Scan(&ownerAddress)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Project not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if ownerAddress != address {
+		httpErr(w, 403, "Not authorized to delete this project")
+		return
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE audits SET project_id = NULL WHERE project_id = ?`, id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if _, err := tx.Exec(`DELETE FROM projects WHERE id = ?`, id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, map[string]bool{"success": true})
+}
+
+// handleGetProjectFindings returns the confirmed findings of a project that are still open, i.e. not yet fixed
+// or accepted as won't fix, as its latest finished audit reported and triaged them; duplicates within the audit
+// are listed once.
+func (a *App) handleGetProjectFindings(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /projects/{id}/findings
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing project ID")
+		return
+	}
+
+	if _, ok := a.projectForRead(w, id, address); !ok {
+		return
+	}
+
+	latest, err := a.latestProjectFindings(id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	findings := []Finding{}
+	for _, f := range latest {
+		if f.Status == "confirmed" {
+			findings = append(findings, *f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityWeights[findings[i].Severity] > severityWeights[findings[j].Severity]
+	})
+
+	writeJSON(w, 200, map[string][]Finding{"findings": findings})
+}
+
+// latestProjectFindings returns the issues a project's codebase still has: the distinct findings of its most
+// recently finished (completed or partially_completed) audit. As in an audit diff, issues of earlier rounds that
+// the latest round didn't report again count as resolved, and those it did are represented by its findings, so
+// only the latest round's findings are returned. Audits still running or that failed don't make a round.
+func (a *App) latestProjectFindings(projectID string) ([]*Finding, error) {
+	var auditID string
+	err := a.DB.QueryRow(`
+		SELECT id FROM audits
+		WHERE project_id = ? AND status IN ('completed', 'partially_completed')
+		ORDER BY COALESCE(completed_at, created_at) DESC, id ASC
+		LIMIT 1
+	`, projectID).Scan(&auditID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.audit_id = ? AND `+canonicalFinding+`
+		ORDER BY findings.rowid ASC
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []*Finding
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+	return findings, rows.Err()
+}
+
+// handleGetProjectTrend returns the findings of a project's finished audits in the order they finished,
+// showing how the codebase's issues evolve across audit rounds
+func (a *App) handleGetProjectTrend(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /projects/{id}/trend
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing project ID")
+		return
+	}
+
+	if _, ok := a.projectForRead(w, id, address); !ok {
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT id, name, status, completed_at
+		FROM audits
+		WHERE project_id = ? AND status IN ('completed', 'partially_completed')
+		ORDER BY COALESCE(completed_at, created_at) ASC
+	`, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	trend := []ProjectTrendPoint{}
+	for rows.Next() {
+		var p ProjectTrendPoint
+		var completedAt sql.NullTime
+		if err := rows.Scan(&p.AuditID, &p.Name, &p.Status, &completedAt); err != nil {
+			rows.Close()
+			httpErr(w, 500, "scan")
+			return
+		}
+		if completedAt.Valid {
+			p.CompletedAt = &completedAt.Time
+		}
+		trend = append(trend, p)
+	}
+	// Release the connection before running the per-audit queries below
+	rows.Close()
+
+	for i := range trend {
+		p := &trend[i]
+		p.FindingsCount = a.getFindingsCount(p.AuditID)
+		p.Total = p.FindingsCount.Total()
+		err := a.DB.QueryRow(`
+			SELECT COUNT(*) FROM findings
+			WHERE audit_id = ? AND status = 'open' AND `+canonicalFinding,
+			p.AuditID).Scan(&p.Untriaged)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+	}
+
+	writeJSON(w, 200, map[string][]ProjectTrendPoint{"trend": trend})
+}
+
+// validateProject normalizes a project request and checks its deployments and default agents.
+// It returns a non-empty message if the request is invalid.
+func (a *App) validateProject(address string, req *CreateProjectRequest) (string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.GitHubURL = strings.TrimSpace(req.GitHubURL)
+	if req.Name == "" || len(req.Name) > 100 {
+		return "name must be 1-100 characters", nil
+	}
+	if req.GitHubURL != "" {
+		if _, err := source.ParseGitHubURL(req.GitHubURL, a.GitHub.WebHost); err != nil {
+			return "github_url: " + err.Error(), nil
+		}
+	}
+
+	if len(req.Deployments) > maxProjectDeployments {
+		return fmt.Sprintf("deployments can list at most %d contracts", maxProjectDeployments), nil
+	}
+	seen := map[ProjectDeployment]bool{}
+	deployments := []ProjectDeployment{}
+	for i, d := range req.Deployments {
+		d.Blockchain = strings.TrimSpace(d.Blockchain)
+		d.Label = strings.TrimSpace(d.Label)
+		if _, ok := a.Chains.Get(d.Blockchain); !ok {
+			return fmt.Sprintf("deployment %d: blockchain must be one of: %s", i, a.Chains.IDs()), nil
+		}
+		if !common.IsHexAddress(d.Address) {
+			return fmt.Sprintf("deployment %d: address must be a contract address", i), nil
+		}
+		d.Address = common.HexToAddress(d.Address).Hex()
+		key := ProjectDeployment{Blockchain: d.Blockchain, Address: d.Address}
+		if !seen[key] {
+			seen[key] = true
+			deployments = append(deployments, d)
+		}
+	}
+	req.Deployments = deployments
+
+	req.DefaultAgents = uniqueStrings(req.DefaultAgents)
+	for _, agentID := range req.DefaultAgents {
+		var ownerAddress, kind string
+		var archivedAt sql.NullTime
+		err := a.DB.QueryRow(`SELECT owner_address, archived_at, kind FROM agents WHERE id = ?`, agentID).Scan(&ownerAddress, &archivedAt, &kind)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && (ownerAddress != address || kind != "ai")) {
+			return "agent " + agentID + " not found", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if archivedAt.Valid {
+			return "agent " + agentID + " is archived", nil
+		}
+	}
+	return "", nil
+}
+
+// applyProjectDefaults fills in what an audit request leaves out from its project: the repository,
+// the chain of the first deployment, the contract when the project has exactly one on the audit's chain,
+// and the default agents when neither agents nor a pipeline are given
+func applyProjectDefaults(req *CreateAuditRequest, p *Project) {
+	if req.GitHubURL == "" {
+		req.GitHubURL = p.GitHubURL
+	}
+	if req.Blockchain == "" && len(p.Deployments) > 0 {
+		req.Blockchain = p.Deployments[0].Blockchain
+	}
+	if req.ContractAddress == "" {
+		var onChain []string
+		for _, d := range p.Deployments {
+			if d.Blockchain == req.Blockchain {
+				onChain = append(onChain, d.Address)
+			}
+		}
+		if len(onChain) == 1 {
+			req.ContractAddress = onChain[0]
+		}
+	}
+	if len(req.Agents) == 0 && req.PipelineID == "" {
+		req.Agents = p.DefaultAgents
+	}
+}
+
+// projectForRead loads a project the user owns, writing a 404 response otherwise
+func (a *App) projectForRead(w http.ResponseWriter, id, address string) (*Project, bool) {
+	p, err := a.getProject(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && p.OwnerAddress != address) {
+		httpErr(w, 404, "Project not found")
+		return nil, false
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return nil, false
+	}
+	return p, true
+}
+
+// getProject loads a project by ID
+func (a *App) getProject(id string) (*Project, error) {
+	return scanProject(a.DB.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE id = ?`, id))
+}
+
+// projectColumns lists the columns read by scanProject, in order
+const projectColumns = `id, owner_address, name, description, github_url, deployments, default_agents, created_at, updated_at`
+
+func scanProject(row rowScanner) (*Project, error) {
+	var p Project
+	var desc, githubURL sql.NullString
+	var deploymentsJSON, agentsJSON string
+
+	err := row.Scan(&p.ID, &p.OwnerAddress, &p.Name, &desc, &githubURL, &deploymentsJSON, &agentsJSON, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Description = desc.String
+	p.GitHubURL = githubURL.String
+	if err := json.Unmarshal([]byte(deploymentsJSON), &p.Deployments); err != nil {
+		p.Deployments = []ProjectDeployment{}
+	}
+	if err := json.Unmarshal([]byte(agentsJSON), &p.DefaultAgents); err != nil {
+		p.DefaultAgents = []string{}
+	}
+	return &p, nil
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"watson/internal/attest"
+)
+
+// maxVerifySize caps the size of a report file sent for verification
+const maxVerifySize = 50 << 20
+
+// attestTimeout bounds how long finalizing waits for the attestation transaction to be mined
+const attestTimeout = 2 * time.Minute
+
+// FinalizeReportRequest represents the request to finalize a report
+type FinalizeReportRequest struct {
+	Attest bool `json:"attest"` // also attest the report's hash on chain
+}
+
+// VerifyReportResponse is the outcome of checking a report file against its signature and attestation
+type VerifyReportResponse struct {
+	Valid       bool                    `json:"valid"`
+	SHA256      string                  `json:"sha256"`
+	ReportID    string                  `json:"report_id"`
+	FinalizedAt *time.Time              `json:"finalized_at"`
+	Signature   ReportSignatureCheck    `json:"signature"`
+	Attestation *ReportAttestationCheck `json:"attestation,omitempty"`
+}
+
+// ReportSignatureCheck is the outcome of recovering a finalized report's signer
+type ReportSignatureCheck struct {
+	Valid         bool   `json:"valid"`
+	Signature     string `json:"signature"`
+	Signer        string `json:"signer"`
+	CurrentSigner bool   `json:"current_signer"` // signed with the key the server uses now
+	Reason        string `json:"reason,omitempty"`
+}
+
+// ReportAttestationCheck is the outcome of looking up a finalized report's attestation on chain
+type ReportAttestationCheck struct {
+	ReportAttestation
+	attest.Check
+}
+
+// handleFinalizeReport signs a report's hash with the server key and optionally attests it on chain.
+// A finalized report can still be attested later; anything else about it is fixed.
+func (a *App) handleFinalizeReport(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /reports/{id}/finalize
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing report ID")
+		return
+	}
+
+	// The body is optional
+	var req FinalizeReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	if a.Signer == nil {
+		httpErr(w, 503, "Report signing is not configured")
+		return
+	}
+	if req.Attest && a.EAS == nil {
+		httpErr(w, 400, "on-chain attestation is not configured")
+		return
+	}
+
+	rep, owner, err := scanReport(a.DB.QueryRow(`SELECT `+reportColumns+` FROM reports WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner != address) {
+		httpErr(w, 404, "Report not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if rep.FinalizedAt != nil && !(req.Attest && rep.Attestation == nil) {
+		httpErr(w, 409, "Report is already finalized")
+		return
+	}
+
+	digest, err := hex.DecodeString(rep.SHA256)
+	if err != nil || len(digest) != sha256.Size {
+		httpErr(w, 500, "bad report digest")
+		return
+	}
+
+	if rep.FinalizedAt == nil {
+		sig, err := a.Signer.Sign(digest)
+		if err != nil {
+			httpErr(w, 500, "sign")
+			return
+		}
+		now := time.Now().UTC()
+		rep.Signature = "0x" + hex.EncodeToString(sig)
+		rep.Signer = a.Signer.Address().Hex()
+		rep.FinalizedAt = &now
+
+		res, err := a.DB.Exec(`
+			UPDATE reports SET signature = ?, signer = ?, finalized_at = ?
+			WHERE id = ? AND finalized_at IS NULL
+		`, rep.Signature, rep.Signer, now, id)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			httpErr(w, 409, "Report is already finalized")
+			return
+		}
+	}
+
+	if req.Attest {
+		ctx, cancel := context.WithTimeout(r.Context(), attestTimeout)
+		defer cancel()
+
+		chainID, err := a.EAS.ChainID(ctx)
+		if err != nil {
+			httpErr(w, 502, "attestation failed: "+err.Error())
+			return
+		}
+
+		// Concurrent requests would each send an attestation transaction; only the one claiming the report attests it
+		res, err := a.DB.Exec(`UPDATE reports SET attestation_uid = ? WHERE id = ? AND attestation_uid IS NULL`, attestationPending, id)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			httpErr(w, 409, "Report is already attested or being attested")
+			return
+		}
+
+		uid, txHash, err := a.EAS.Attest(ctx, [32]byte(digest))
+		if err != nil {
+			// The report stays signed; attesting can be retried
+			if _, err := a.DB.Exec(`UPDATE reports SET attestation_uid = NULL WHERE id = ? AND attestation_uid = ?`, id, attestationPending); err != nil {
+				log.Printf("report %s: release attestation claim: %v", id, err)
+			}
+			httpErr(w, 502, "attestation failed: "+err.Error())
+			return
+		}
+		rep.Attestation = &ReportAttestation{UID: uid.Hex(), TxHash: txHash.Hex(), ChainID: chainID}
+
+		_, err = a.DB.Exec(`
+			UPDATE reports SET attestation_uid = ?, attestation_tx = ?, attestation_chain_id = ?
+			WHERE id = ?
+		`, rep.Attestation.UID, rep.Attestation.TxHash, rep.Attestation.ChainID, id)
+		if err != nil {
+			// The claim is kept so the mined attestation isn't repeated
+			log.Printf("report %s: attestation %s in %s wasn't recorded: %v", id, rep.Attestation.UID, rep.Attestation.TxHash, err)
+			httpErr(w, 500, "db")
+			return
+		}
+	}
+
+	rep.ReportURL, rep.ExpiresAt = a.signedReportURL(rep.ID)
+	writeJSON(w, 200, rep)
+}
+
+// handleGetReportSigner returns the address finalized reports are signed by and the attestation setup
+func (a *App) handleGetReportSigner(w http.ResponseWriter, r *http.Request) {
+	if a.Signer == nil {
+		httpErr(w, 503, "Report signing is not configured")
+		return
+	}
+
+	resp := map[string]interface{}{
+		"address": a.Signer.Address().Hex(),
+		"scheme":  "eip191", // personal_sign over the raw 32-byte SHA-256 digest of the file
+		"eas":     nil,
+	}
+	if a.EAS != nil {
+		eas := map[string]interface{}{
+			"contract":   a.EAS.Contract().Hex(),
+			"schema_uid": a.EAS.Schema().Hex(),
+			"schema":     "bytes32 reportHash",
+		}
+		if chainID, err := a.EAS.ChainID(r.Context()); err == nil {
+			eas["chain_id"] = chainID
+		}
+		resp["eas"] = eas
+	}
+	writeJSON(w, 200, resp)
+}
+
+// handleVerifyReport checks a report file, sent as the request body, against the signature and
+// attestation recorded when it was finalized. It needs no session so anyone holding a report can check it.
+func (a *App) handleVerifyReport(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxVerifySize))
+	if err != nil {
+		httpErr(w, 413, "report must be at most 50MB")
+		return
+	}
+	if len(content) == 0 {
+		httpErr(w, 400, "send the report file as the request body")
+		return
+	}
+
+	digest := sha256.Sum256(content)
+	sum := hex.EncodeToString(digest[:])
+
+	// Identical reports can be finalized more than once; the first one is the reference
+	rep, _, err := scanReport(a.DB.QueryRow(`
+		SELECT `+reportColumns+`
+		FROM reports
+		WHERE sha256 = ? AND finalized_at IS NOT NULL
+		ORDER BY finalized_at ASC
+		LIMIT 1
+	`, sum))
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "No finalized report matches this file")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	resp := VerifyReportResponse{
+		SHA256:      sum,
+		ReportID:    rep.ID,
+		FinalizedAt: rep.FinalizedAt,
+		Signature:   ReportSignatureCheck{Signature: rep.Signature, Signer: rep.Signer},
+	}
+
+	sig, err := hex.DecodeString(strings.TrimPrefix(rep.Signature, "0x"))
+	if err != nil {
+		resp.Signature.Reason = "stored signature is malformed"
+	} else if recovered, err := attest.Recover(digest[:], sig); err != nil {
+		resp.Signature.Reason = "signature does not recover: " + err.Error()
+	} else if recovered != common.HexToAddress(rep.Signer) {
+		resp.Signature.Reason = "signature was made by " + recovered.Hex()
+	} else {
+		resp.Signature.Valid = true
+	}
+	resp.Signature.CurrentSigner = a.Signer != nil && a.Signer.Address() == common.HexToAddress(rep.Signer)
+	resp.Valid = resp.Signature.Valid
+
+	if rep.Attestation != nil {
+		check := &ReportAttestationCheck{ReportAttestation: *rep.Attestation}
+		if a.EAS == nil {
+			check.Reason = "on-chain attestation is not configured"
+		} else {
+			ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+			defer cancel()
+			c, err := a.EAS.Verify(ctx, common.HexToHash(rep.Attestation.UID), digest, common.HexToAddress(rep.Signer))
+			if err != nil {
+				check.Reason = "could not read the attestation: " + err.Error()
+			} else {
+				check.Check = *c
+			}
+		}
+		resp.Attestation = check
+		resp.Valid = resp.Valid && check.Valid
+	}
+
+	writeJSON(w, 200, resp)
+}
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"watson/internal/report"
+)
+
+// maxTemplateSize caps the size of a custom report template body
+const maxTemplateSize = 100 * 1024
+
+// ReportTemplate represents a user's custom report template
+type ReportTemplate struct {
+	ID           string    `json:"id"`
+	OwnerAddress string    `json:"owner_address"`
+	Name         string    `json:"name"`
+	Format       string    `json:"format"` // markdown (also used for pdf reports) or html
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CreateReportTemplateRequest represents the request to create or update a report template
+type CreateReportTemplateRequest struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Body   string `json:"body"`
+}
+
+// handleGetReportTemplates returns all report templates for the authenticated user
+func (a *App) handleGetReportTemplates(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT id, owner_address, name, format, body, created_at, updated_at
+		FROM report_templates
+		WHERE owner_address = ?
+		ORDER BY created_at DESC
+	`, address)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	templates := []ReportTemplate{}
+	for rows.Next() {
+		var t ReportTemplate
+		if err := rows.Scan(&t.ID, &t.OwnerAddress, &t.Name, &t.Format, &t.Body, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		templates = append(templates, t)
+	}
+
+	writeJSON(w, 200, map[string][]ReportTemplate{"templates": templates})
+}
+
+// handleGetReportTemplate returns a specific report template by ID
+func (a *App) handleGetReportTemplate(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /report-templates/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing template ID")
+		return
+	}
+
+	t, err := a.getReportTemplate(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && t.OwnerAddress != address) {
+		httpErr(w, 404, "Template not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, t)
+}
+
+// handleCreateReportTemplate creates a new report template
+func (a *App) handleCreateReportTemplate(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	var req CreateReportTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	if msg := validateReportTemplate(&req); msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	t := ReportTemplate{
+		ID:           uuid.NewString(),
+		OwnerAddress: address,
+		Name:         req.Name,
+		Format:       req.Format,
+		Body:         req.Body,
+		CreatedAt:    time.Now().UTC(),
+	}
+	t.UpdatedAt = t.CreatedAt
+
+	_, err := a.DB.Exec(`
+		INSERT INTO report_templates (id, owner_address, name, format, body, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.OwnerAddress, t.Name, t.Format, t.Body, t.CreatedAt, t.UpdatedAt)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 201, t)
+}
+
+// handleUpdateReportTemplate updates an existing report template. Reports already generated are not affected.
+func (a *App) handleUpdateReportTemplate(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /report-templates/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing template ID")
+		return
+	}
+
+	var req CreateReportTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	t, err := a.getReportTemplate(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Template not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if t.OwnerAddress != address {
+		httpErr(w, 403, "Not authorized to modify this template")
+		return
+	}
+
+	if msg := validateReportTemplate(&req); msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	now := time.Now().UTC()
+	_, err = a.DB.Exec(`
+		UPDATE report_templates
+		SET name = ?, format = ?, body = ?, updated_at = ?
+		WHERE id = ?
+	`, req.Name, req.Format, req.Body, now, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	t.Name = req.Name
+	t.Format = req.Format
+	t.Body = req.Body
+	t.UpdatedAt = now
+
+	writeJSON(w, 200, t)
+}
+
+// handleDeleteReportTemplate deletes a report template. Reports already generated are kept.
+func (a *App) handleDeleteReportTemplate(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /report-templates/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing template ID")
+		return
+	}
+
+	t, err := a.getReportTemplate(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Template not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if t.OwnerAddress != address {
+		httpErr(w, 403, "Not authorized to delete this template")
+		return
+	}
+
+	if _, err := a.DB.Exec(`DELETE FROM report_templates WHERE id = ?`, id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, map[string]bool{"success": true})
+}
+
+// validateReportTemplate normalizes req and returns a message describing the first invalid field
+func validateReportTemplate(req *CreateReportTemplateRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return "name must be 1-100 characters"
+	}
+	if req.Format != report.Markdown && req.Format != report.HTML {
+		return "format must be markdown or html"
+	}
+	if req.Body == "" || len(req.Body) > maxTemplateSize {
+		return "body must be 1-102400 characters"
+	}
+	if err := report.Validate(req.Format, req.Body); err != nil {
+		return "invalid template: " + err.Error()
+	}
+	return ""
+}
+
+func (a *App) getReportTemplate(id string) (*ReportTemplate, error) {
+	var t ReportTemplate
+	err := a.DB.QueryRow(`
+		SELECT id, owner_address, name, format, body, created_at, updated_at
+		FROM report_templates
+		WHERE id = ?
+	`, id).Scan(&t.ID, &t.OwnerAddress, &t.Name, &t.Format, &t.Body, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
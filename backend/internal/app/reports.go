@@ -0,0 +1,551 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"watson/internal/report"
+)
+
+// Report represents a generated audit report. Its content is downloaded through ReportURL,
+// a signed link that stops working at ExpiresAt.
+type Report struct {
+	ID          string             `json:"id"`
+	AuditID     string             `json:"audit_id"`
+	Format      string             `json:"format"`
+	TemplateID  string             `json:"template_id,omitempty"`
+	FindingIDs  []string           `json:"finding_ids"`
+	SHA256      string             `json:"sha256"`
+	Size        int64              `json:"size"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Signature   string             `json:"signature,omitempty"`
+	Signer      string             `json:"signer,omitempty"`
+	FinalizedAt *time.Time         `json:"finalized_at,omitempty"`
+	Attestation *ReportAttestation `json:"attestation,omitempty"`
+	ReportURL   string             `json:"report_url"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+}
+
+// ReportAttestation locates the on-chain attestation of a finalized report's hash
+type ReportAttestation struct {
+	UID     string `json:"uid"`
+	TxHash  string `json:"tx_hash"`
+	ChainID int64  `json:"chain_id"`
+}
+
+// CreateReportRequest represents the request to generate an audit report
+type CreateReportRequest struct {
+	FindingIDs []string `json:"finding_ids"`
+	IncludeAll bool     `json:"include_all"`
+	Format     string   `json:"format"`      // markdown, html or pdf (default)
+	TemplateID string   `json:"template_id"` // custom template; the built-in one when empty
+	DiffWith   string   `json:"diff_with"`   // earlier audit to add a changes section against
+}
+
+// handleCreateReport renders an audit and its selected findings into a stored report
+func (a *App) handleCreateReport(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/report
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	var req CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = report.PDF
+	}
+	templateFormat, err := report.TemplateFormat(req.Format)
+	if err != nil {
+		httpErr(w, 400, "format must be one of: markdown, html, pdf")
+		return
+	}
+	if !req.IncludeAll && len(uniqueStrings(req.FindingIDs)) == 0 {
+		httpErr(w, 400, "finding_ids is required unless include_all is true")
+		return
+	}
+
+	if req.DiffWith == id {
+		httpErr(w, 400, "diff_with must be another audit")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+	if req.DiffWith != "" && !a.checkAuditAccess(w, req.DiffWith, address) {
+		return
+	}
+
+	var body string
+	if req.TemplateID != "" {
+		t, err := a.getReportTemplate(req.TemplateID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && t.OwnerAddress != address) {
+			httpErr(w, 404, "Template not found")
+			return
+		}
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		if t.Format != templateFormat {
+			httpErr(w, 400, "a "+req.Format+" report needs a "+templateFormat+" template")
+			return
+		}
+		body = t.Body
+	}
+
+	data, findingIDs, msg, err := a.reportData(id, req)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	content, err := report.Render(req.Format, body, *data)
+	if err != nil {
+		// Only custom templates can fail on the caller's side
+		if body != "" {
+			httpErr(w, 400, "template: "+err.Error())
+			return
+		}
+		httpErr(w, 500, "render")
+		return
+	}
+
+	findingIDsJSON, err := json.Marshal(findingIDs)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	rep := Report{
+		ID:         uuid.NewString(),
+		AuditID:    id,
+		Format:     req.Format,
+		TemplateID: req.TemplateID,
+		FindingIDs: findingIDs,
+		SHA256:     hex.EncodeToString(sum[:]),
+		Size:       int64(len(content)),
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	var templateID interface{}
+	if rep.TemplateID != "" {
+		templateID = rep.TemplateID
+	}
+	_, err = a.DB.Exec(`
+		INSERT INTO reports (id, audit_id, owner_address, format, template_id, finding_ids, content, sha256, size, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rep.ID, rep.AuditID, address, rep.Format, templateID, string(findingIDsJSON), content, rep.SHA256, rep.Size, rep.CreatedAt)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	rep.ReportURL, rep.ExpiresAt = a.signedReportURL(rep.ID)
+	writeJSON(w, 200, rep)
+}
+
+// handleGetReports returns the reports generated for an audit, each with a fresh download link
+func (a *App) handleGetReports(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/reports
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT `+reportColumns+`
+		FROM reports
+		WHERE audit_id = ?
+		ORDER BY created_at DESC
+	`, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		rep, _, err := scanReport(rows)
+		if err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		rep.ReportURL, rep.ExpiresAt = a.signedReportURL(rep.ID)
+		reports = append(reports, *rep)
+	}
+
+	writeJSON(w, 200, map[string][]Report{"reports": reports})
+}
+
+// handleGetReport returns a specific report by ID with a fresh download link
+func (a *App) handleGetReport(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /reports/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing report ID")
+		return
+	}
+
+	rep, owner, err := scanReport(a.DB.QueryRow(`SELECT `+reportColumns+` FROM reports WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner != address) {
+		httpErr(w, 404, "Report not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	rep.ReportURL, rep.ExpiresAt = a.signedReportURL(rep.ID)
+	writeJSON(w, 200, rep)
+}
+
+// handleDownloadReport serves a report's content. It needs no session: the link's signature and expiry are the credential.
+func (a *App) handleDownloadReport(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /reports/{id}/download
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing report ID")
+		return
+	}
+
+	q := r.URL.Query()
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires || !a.validReportSignature(id, expires, q.Get("sig")) {
+		httpErr(w, 403, "Download link is invalid or expired")
+		return
+	}
+
+	var format, sum string
+	var content []byte
+	err = a.DB.QueryRow(`SELECT format, sha256, content FROM reports WHERE id = ?`, id).Scan(&format, &sum, &content)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Report not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	w.Header().Set("Content-Type", report.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="report-`+id+`.`+report.Extension(format)+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("ETag", `"`+sum+`"`)
+	w.WriteHeader(200)
+	w.Write(content)
+}
+
+// reportData collects the audit metadata and findings a report is rendered from.
+// A non-empty msg describes why the request can't be fulfilled.
+func (a *App) reportData(auditID string, req CreateReportRequest) (data *report.Data, findingIDs []string, msg string, err error) {
+	var audit report.Audit
+	var desc, contractAddr, githubURL, agentsJSON, verificationJSON sql.NullString
+	var completedAt sql.NullTime
+	err = a.DB.QueryRow(`
+		SELECT id, name, description, owner_address, status, blockchain, contract_address, github_url,
+		       agents_used, verification, created_at, completed_at
+		FROM audits
+		WHERE id = ?
+	`, auditID).Scan(&audit.ID, &audit.Name, &desc, &audit.Owner, &audit.Status, &audit.Blockchain,
+		&contractAddr, &githubURL, &agentsJSON, &verificationJSON, &audit.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	audit.Description = desc.String
+	audit.ContractAddress = contractAddr.String
+	audit.GitHubURL = githubURL.String
+	if completedAt.Valid {
+		audit.CompletedAt = &completedAt.Time
+	}
+	var v Verification
+	if verificationJSON.Valid && json.Unmarshal([]byte(verificationJSON.String), &v) == nil {
+		audit.Verification = &report.Verification{
+			Status:          v.Status,
+			CompilerVersion: v.CompilerVersion,
+			Warnings:        v.Warnings,
+			Error:           v.Error,
+			VerifiedAt:      v.VerifiedAt,
+		}
+		for _, t := range v.Targets {
+			audit.Verification.Targets = append(audit.Verification.Targets, report.VerificationTarget(t))
+		}
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT name FROM agents
+		WHERE id IN (SELECT value FROM json_each(?))
+		ORDER BY name
+	`, agentsJSON.String)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, nil, "", err
+		}
+		audit.Agents = append(audit.Agents, name)
+	}
+	rows.Close()
+
+	// include_all takes every distinct issue that wasn't dismissed as a false positive
+	query := `SELECT ` + findingColumns + ` FROM ` + findingsFrom + ` WHERE findings.audit_id = ?`
+	args := []interface{}{auditID}
+	ids := uniqueStrings(req.FindingIDs)
+	if req.IncludeAll {
+		query += ` AND findings.status != 'false_positive' AND ` + canonicalFinding
+	} else {
+		query, args = appendIn(query, args, "findings.id", ids)
+	}
+	query += ` ORDER BY ` + findingSorts["severity"] + ` DESC, findings.rowid ASC`
+
+	rows, err = a.DB.Query(query, args...)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var findings []*Finding
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			rows.Close()
+			return nil, nil, "", err
+		}
+		findings = append(findings, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, "", err
+	}
+
+	if !req.IncludeAll && len(findings) != len(ids) {
+		return nil, nil, "finding_ids must be findings of this audit", nil
+	}
+
+	clusterAgents, err := a.clusterAgentNames(auditID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	data = &report.Data{
+		Title:       "Security Audit Report: " + audit.Name,
+		Audit:       audit,
+		Findings:    []report.Finding{},
+		GeneratedAt: time.Now().UTC(),
+	}
+	findingIDs = []string{}
+	for _, f := range findings {
+		agents := []string{f.AgentName}
+		for _, name := range clusterAgents[f.ClusterID] {
+			if name != f.AgentName {
+				agents = append(agents, name)
+			}
+		}
+		data.Findings = append(data.Findings, report.Finding{
+			ID:             f.ID,
+			Title:          f.Title,
+			Description:    f.Description,
+			Severity:       f.Severity,
+			Status:         f.Status,
+			Location:       formatLocation(f.Location),
+			Recommendation: f.Recommendation,
+			CodeSnippet:    f.CodeSnippet,
+			Agents:         agents,
+		})
+		findingIDs = append(findingIDs, f.ID)
+	}
+	data.Summary = report.Summarize(data.Findings)
+
+	if req.DiffWith != "" {
+		if data.Diff, err = a.reportDiff(auditID, req.DiffWith); err != nil {
+			return nil, nil, "", err
+		}
+	}
+
+	return data, findingIDs, "", nil
+}
+
+// reportDiff renders the comparison of an audit with an earlier one for a report
+func (a *App) reportDiff(auditID, baseID string) (*report.Diff, error) {
+	d := &report.Diff{Changes: []report.Change{}}
+	err := a.DB.QueryRow(`SELECT id, name, status, created_at FROM audits WHERE id = ?`, baseID).
+		Scan(&d.Base.ID, &d.Base.Name, &d.Base.Status, &d.Base.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := a.auditDiff(auditID, baseID)
+	if err != nil {
+		return nil, err
+	}
+	d.Summary = report.DiffSummary{
+		New:             diff.Summary.New,
+		Resolved:        diff.Summary.Resolved,
+		Persisting:      diff.Summary.Persisting,
+		SeverityChanged: diff.Summary.SeverityChanged,
+	}
+	for _, c := range diff.Changes {
+		f := c.current()
+		rc := report.Change{Change: c.Change, Title: f.Title, Severity: f.Severity, Location: formatLocation(f.Location)}
+		if c.Change == ChangeSeverityChanged {
+			rc.PreviousSeverity = c.BaseFinding.Severity
+		}
+		d.Changes = append(d.Changes, rc)
+	}
+	return d, nil
+}
+
+// clusterAgentNames returns the distinct names of the agents that reported each cluster of an audit
+func (a *App) clusterAgentNames(auditID string) (map[string][]string, error) {
+	rows, err := a.DB.Query(`
+		SELECT findings.cluster_id, COALESCE(agents.name, '')
+		FROM `+findingsFrom+`
+		WHERE findings.audit_id = ? AND findings.cluster_id IS NOT NULL
+		ORDER BY findings.rowid ASC
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := map[string][]string{}
+	seen := map[string]bool{}
+	for rows.Next() {
+		var clusterID, name string
+		if err := rows.Scan(&clusterID, &name); err != nil {
+			return nil, err
+		}
+		if !seen[clusterID+"\x00"+name] {
+			seen[clusterID+"\x00"+name] = true
+			names[clusterID] = append(names[clusterID], name)
+		}
+	}
+	return names, rows.Err()
+}
+
+// signedReportURL returns a download link for a report valid for ReportURLTTL
+func (a *App) signedReportURL(id string) (string, time.Time) {
+	expiresAt := time.Now().UTC().Add(a.ReportURLTTL).Truncate(time.Second)
+	expires := expiresAt.Unix()
+	url := strings.TrimRight(a.PublicURL, "/") + "/reports/" + id + "/download?expires=" +
+		strconv.FormatInt(expires, 10) + "&sig=" + a.reportSignature(id, expires)
+	return url, expiresAt
+}
+
+func (a *App) reportSignature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, a.ReportSecret)
+	mac.Write([]byte(id + "." + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (a *App) validReportSignature(id string, expires int64, sig string) bool {
+	return hmac.Equal([]byte(sig), []byte(a.reportSignature(id, expires)))
+}
+
+// attestationPending is the attestation_uid of a report while its attestation transaction is sent
+const attestationPending = "pending"
+
+// reportColumns lists the columns read by scanReport, in order
+const reportColumns = `id, audit_id, owner_address, format, template_id, finding_ids, sha256, size, created_at,
+	signature, signer, finalized_at, attestation_uid, attestation_tx, attestation_chain_id`
+
+func scanReport(row rowScanner) (*Report, string, error) {
+	var rep Report
+	var owner, findingIDsJSON string
+	var templateID, signature, signer, attUID, attTx sql.NullString
+	var finalizedAt sql.NullTime
+	var attChainID sql.NullInt64
+	err := row.Scan(&rep.ID, &rep.AuditID, &owner, &rep.Format, &templateID, &findingIDsJSON, &rep.SHA256, &rep.Size, &rep.CreatedAt,
+		&signature, &signer, &finalizedAt, &attUID, &attTx, &attChainID)
+	if err != nil {
+		return nil, "", err
+	}
+	rep.TemplateID = templateID.String
+	rep.Signature = signature.String
+	rep.Signer = signer.String
+	if finalizedAt.Valid {
+		rep.FinalizedAt = &finalizedAt.Time
+	}
+	if attUID.Valid && attUID.String != attestationPending {
+		rep.Attestation = &ReportAttestation{UID: attUID.String, TxHash: attTx.String, ChainID: attChainID.Int64}
+	}
+	if err := json.Unmarshal([]byte(findingIDsJSON), &rep.FindingIDs); err != nil {
+		rep.FindingIDs = []string{}
+	}
+	return &rep, owner, nil
+}
+
+// formatLocation renders a finding location as file:line (function)
+func formatLocation(l FindingLocation) string {
+	s := l.File
+	if s != "" && l.Line > 0 {
+		s += ":" + strconv.Itoa(l.Line)
+	}
+	if l.Function != "" {
+		if s == "" {
+			return l.Function
+		}
+		s += " (" + l.Function + ")"
+	}
+	return s
+}
+
+// uniqueStrings returns the non-empty values of in, without duplicates, in their original order
+func uniqueStrings(in []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, s := range in {
+		if s = strings.TrimSpace(s); s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,603 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errRunNotQueued means a run was started that isn't waiting in the queue
+var errRunNotQueued = errors.New("agent run is not queued")
+
+// errRunNotRunning means a run was finished that isn't running
+var errRunNotRunning = errors.New("agent run is not running")
+
+// errRunWaiting means a run was started while runs of the stages it depends on are still queued or running
+var errRunWaiting = errors.New("agent run is waiting on upstream stages")
+
+// runEvent is the payload of agent.started, agent.finished and tool.call events
+type runEvent struct {
+	RunID   string `json:"run_id"`
+	AgentID string `json:"agent_id"`
+	Stage   int    `json:"stage"`
+	Status  string `json:"status,omitempty"`
+	Tool    string `json:"tool,omitempty"`
+}
+
+// AgentRun is an agent run waiting for the runner
+type AgentRun struct {
+	ID        string    `json:"id"`
+	AuditID   string    `json:"audit_id"`
+	AgentID   string    `json:"agent_id"`
+	Stage     int       `json:"stage"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ToolCallRequest reports an MCP tool call of a run
+type ToolCallRequest struct {
+	Tool string `json:"tool"`
+}
+
+// FinishRunRequest reports how a run ended, and the usage not reported yet
+type FinishRunRequest struct {
+	Failed bool   `json:"failed"`
+	Usage  *Usage `json:"usage,omitempty"`
+}
+
+// maxRunFindings caps the findings a run reports in one request
+const maxRunFindings = 500
+
+// RunFinding is a finding reported by an agent run
+type RunFinding struct {
+	Title          string          `json:"title"`
+	Description    string          `json:"description"`
+	Severity       string          `json:"severity"`
+	Location       FindingLocation `json:"location"`
+	Recommendation string          `json:"recommendation"`
+	CodeSnippet    string          `json:"code_snippet"`
+}
+
+// RunFindingsRequest reports findings of a run
+type RunFindingsRequest struct {
+	Findings []RunFinding `json:"findings"`
+}
+
+// VerdictRequest represents a verifier stage's verdict on a finding of a stage it depends on
+type VerdictRequest struct {
+	FindingID     string `json:"finding_id"`
+	Verdict       string `json:"verdict"` // confirmed or rejected
+	Justification string `json:"justification"`
+}
+
+// verdictStatuses maps the verdicts of a verifier stage to the triage statuses they set
+var verdictStatuses = map[string]string{
+	"confirmed": "confirmed",
+	"rejected":  "false_positive",
+}
+
+// handleGetQueuedRuns lists the queued agent runs of in_progress audits, oldest first and by stage. Runs of a
+// stage are left out while the stages it depends on still have queued or running runs.
+func (a *App) handleGetQueuedRuns(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.DB.Query(`
+		SELECT agent_runs.id, agent_runs.audit_id, agent_runs.agent_id, agent_runs.stage, agent_runs.status, agent_runs.created_at,
+		       audits.agents_used, audits.pipeline_stages
+		FROM agent_runs JOIN audits ON audits.id = agent_runs.audit_id
+		WHERE agent_runs.status = 'queued' AND audits.status = 'in_progress'
+		ORDER BY agent_runs.created_at ASC, agent_runs.stage ASC
+	`)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	var queued []AgentRun
+	stages := map[string][]PipelineStage{}
+	for rows.Next() {
+		var run AgentRun
+		var agentsJSON string
+		var stagesJSON sql.NullString
+		if err := rows.Scan(&run.ID, &run.AuditID, &run.AgentID, &run.Stage, &run.Status, &run.CreatedAt, &agentsJSON, &stagesJSON); err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		if _, ok := stages[run.AuditID]; !ok {
+			stages[run.AuditID] = auditStages(stagesJSON, agentsJSON)
+		}
+		queued = append(queued, run)
+	}
+	if err := rows.Err(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	rows.Close()
+
+	// Whether a stage waits is checked once per audit and stage
+	runs := []AgentRun{}
+	waiting := map[string]bool{}
+	for _, run := range queued {
+		key := fmt.Sprintf("%s/%d", run.AuditID, run.Stage)
+		held, ok := waiting[key]
+		if !ok {
+			held, err = stageWaiting(a.DB, run.AuditID, stages[run.AuditID], run.Stage)
+			if err != nil {
+				httpErr(w, 500, "db")
+				return
+			}
+			waiting[key] = held
+		}
+		if !held {
+			runs = append(runs, run)
+		}
+	}
+
+	writeJSON(w, 200, runs)
+}
+
+// handleStartRun marks a queued run as running
+func (a *App) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /runs/{id}/start
+	id := r.PathValue("id")
+	if err := a.startRun(id); err != nil {
+		runErr(w, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// handleRecordToolCall records an MCP tool call of a run
+func (a *App) handleRecordToolCall(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /runs/{id}/tool-calls
+	id := r.PathValue("id")
+
+	var req ToolCallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	if req.Tool == "" {
+		httpErr(w, 400, "tool is required")
+		return
+	}
+
+	if err := a.recordToolCall(id, req.Tool); err != nil {
+		runErr(w, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// handleRecordRunUsage adds token usage and tool calls to a run's ledger, as the runner reports them
+func (a *App) handleRecordRunUsage(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /runs/{id}/usage
+	id := r.PathValue("id")
+
+	var req Usage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	if msg := validateUsage(req); msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	if err := a.recordUsage(id, req); err != nil {
+		runErr(w, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// handleFinishRun marks a running run completed, or failed
+func (a *App) handleFinishRun(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /runs/{id}/finish
+	id := r.PathValue("id")
+
+	// The body is optional
+	var req FinishRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	if req.Usage != nil {
+		if msg := validateUsage(*req.Usage); msg != "" {
+			httpErr(w, 400, msg)
+			return
+		}
+	}
+
+	if err := a.finishRun(id, req.Failed, req.Usage); err != nil {
+		runErr(w, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// handleGetRunInput returns the input of a run's pipeline stage, as GET /audits/{id}/stages/{stage}/input does
+func (a *App) handleGetRunInput(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /runs/{id}/input
+	id := r.PathValue("id")
+
+	auditID, stages, stage, _, err := a.runStage(id)
+	if err != nil {
+		runErr(w, err)
+		return
+	}
+
+	input, err := a.stageInput(auditID, stages, stage)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	writeJSON(w, 200, input)
+}
+
+// handleRecordRunFindings stores the findings a running run reports, attributed to its agent and stage
+func (a *App) handleRecordRunFindings(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /runs/{id}/findings
+	id := r.PathValue("id")
+
+	var req RunFindingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	if len(req.Findings) == 0 || len(req.Findings) > maxRunFindings {
+		httpErr(w, 400, fmt.Sprintf("findings must contain 1-%d findings", maxRunFindings))
+		return
+	}
+	for i := range req.Findings {
+		f := &req.Findings[i]
+		f.Title = strings.TrimSpace(f.Title)
+		if f.Title == "" || len(f.Title) > 200 {
+			httpErr(w, 400, "title must be 1-200 characters")
+			return
+		}
+		if !validSeverities[f.Severity] {
+			httpErr(w, 400, "severity must be one of: critical, high, medium, low, info")
+			return
+		}
+		if f.Location.Line < 0 {
+			httpErr(w, 400, "location line can't be negative")
+			return
+		}
+	}
+
+	findings, err := a.recordRunFindings(id, req.Findings)
+	if err != nil {
+		runErr(w, err)
+		return
+	}
+	writeJSON(w, 201, map[string][]Finding{"findings": findings})
+}
+
+// handleRecordVerdict confirms or rejects a finding of an upstream stage on behalf of a running verifier
+// stage. The verdict sets the finding's triage status with the stage as actor, so rejected findings drop
+// out of the input of later stages.
+func (a *App) handleRecordVerdict(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from path: /runs/{id}/verdicts
+	id := r.PathValue("id")
+
+	var req VerdictRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	req.Verdict = strings.TrimSpace(req.Verdict)
+	req.Justification = strings.TrimSpace(req.Justification)
+
+	status, ok := verdictStatuses[req.Verdict]
+	if !ok {
+		httpErr(w, 400, "verdict must be one of: confirmed, rejected")
+		return
+	}
+	if justificationRequired[status] && req.Justification == "" {
+		httpErr(w, 400, "justification is required for "+req.Verdict)
+		return
+	}
+	if len(req.Justification) > 5000 {
+		httpErr(w, 400, "justification must be at most 5000 characters")
+		return
+	}
+
+	auditID, stages, stage, runStatus, err := a.runStage(id)
+	if err != nil {
+		runErr(w, err)
+		return
+	}
+	if runStatus != "running" {
+		runErr(w, errRunNotRunning)
+		return
+	}
+	if stages[stage].Role != "verifier" {
+		httpErr(w, 409, "Only a verifier stage can confirm or reject findings")
+		return
+	}
+
+	f, err := scanFinding(a.DB.QueryRow(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.id = ? AND findings.audit_id = ?
+	`, req.FindingID, auditID))
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Finding not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if !slices.Contains(upstreamStages(stages, stage), f.Stage) {
+		httpErr(w, 409, "Finding is not from a stage this one depends on")
+		return
+	}
+
+	if f.Status != status {
+		if !canTransitionFinding(f.Status, status) {
+			httpErr(w, 409, "Finding can't move from "+f.Status+" to "+status)
+			return
+		}
+		err = a.setFindingStatus(f.ID, f.Status, status, req.Justification, "stage:"+stages[stage].Name)
+		if errors.Is(err, errFindingStatusChanged) {
+			httpErr(w, 409, "Finding status changed concurrently")
+			return
+		}
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		f.Status = status
+	}
+
+	writeJSON(w, 200, f)
+}
+
+// validateUsage checks reported usage isn't negative
+func validateUsage(u Usage) string {
+	if u.PromptTokens < 0 || u.CompletionTokens < 0 || u.ToolCalls < 0 {
+		return "usage can't be negative"
+	}
+	return ""
+}
+
+// runErr writes the response for a failed change to a run
+func runErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		httpErr(w, 404, "Run not found")
+	case errors.Is(err, errRunNotQueued):
+		httpErr(w, 409, "Run is not queued")
+	case errors.Is(err, errRunNotRunning):
+		httpErr(w, 409, "Run is not running")
+	case errors.Is(err, errRunWaiting):
+		httpErr(w, 409, "Run is waiting on upstream stages")
+	default:
+		httpErr(w, 500, "db")
+	}
+}
+
+// startRun marks a queued agent run as running, when the runner picks it up. Runs of a stage can't start
+// until the runs of the stages it depends on completed or failed.
+func (a *App) startRun(runID string) error {
+	e, auditID, err := a.runEvent(runID)
+	if err != nil {
+		return err
+	}
+	_, stages, _, _, err := a.runStage(runID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	waiting, err := stageWaiting(tx, auditID, stages, e.Stage)
+	if err != nil {
+		return err
+	}
+	if waiting {
+		return errRunWaiting
+	}
+
+	res, err := tx.Exec(`
+		UPDATE agent_runs SET status = 'running', started_at = ?
+		WHERE id = ? AND status = 'queued'
+	`, time.Now().UTC(), runID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errRunNotQueued
+	}
+
+	e.Status = "running"
+	if err := recordAuditEvent(tx, auditID, EventAgentStarted, e); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	a.events.notify(auditID)
+	return nil
+}
+
+// finishRun marks a running agent run completed or failed and records its final usage, if any, in the same
+// transaction, then settles the audit if it was the last one running. The run finished whether or not the audit
+// could be settled, so settling failures are only logged.
+func (a *App) finishRun(runID string, failed bool, u *Usage) error {
+	e, auditID, err := a.runEvent(runID)
+	if err != nil {
+		return err
+	}
+	e.Status = "completed"
+	if failed {
+		e.Status = "failed"
+	}
+
+	var usage usageEntry
+	if u != nil {
+		if usage, err = a.priceUsage(runID, *u); err != nil {
+			return err
+		}
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE agent_runs SET status = ?, finished_at = ?
+		WHERE id = ? AND status = 'running'
+	`, e.Status, time.Now().UTC(), runID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errRunNotRunning
+	}
+
+	if u != nil {
+		if err := insertUsage(tx, usage); err != nil {
+			return err
+		}
+	}
+	if err := recordAuditEvent(tx, auditID, EventAgentFinished, e); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	a.events.notify(auditID)
+
+	if _, err := a.settleAudit(auditID); err != nil {
+		log.Printf("runs: settle audit %s: %v", auditID, err)
+	}
+	return nil
+}
+
+// recordToolCall records that an agent run called an MCP tool. It only streams a tool.call event: the call is
+// counted in the usage ledger through the tool_calls the runner reports with the run's usage.
+func (a *App) recordToolCall(runID, tool string) error {
+	e, auditID, err := a.runEvent(runID)
+	if err != nil {
+		return err
+	}
+	e.Tool = tool
+
+	if err := recordAuditEvent(a.DB, auditID, EventToolCall, e); err != nil {
+		return err
+	}
+	a.events.notify(auditID)
+	return nil
+}
+
+// recordRunFindings stores findings of a running agent run with the run's agent and stage, recording a
+// finding.created event for each in the same transaction
+func (a *App) recordRunFindings(runID string, reported []RunFinding) ([]Finding, error) {
+	e, auditID, err := a.runEvent(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM agent_runs WHERE id = ?`, runID).Scan(&status); err != nil {
+		return nil, err
+	}
+	if status != "running" {
+		return nil, errRunNotRunning
+	}
+
+	now := time.Now().UTC()
+	findings := make([]Finding, 0, len(reported))
+	for _, rf := range reported {
+		f := Finding{
+			ID:             uuid.NewString(),
+			AuditID:        auditID,
+			AgentID:        e.AgentID,
+			Stage:          e.Stage,
+			Title:          rf.Title,
+			Description:    rf.Description,
+			Severity:       rf.Severity,
+			Status:         "open",
+			Location:       rf.Location,
+			Recommendation: rf.Recommendation,
+			CodeSnippet:    rf.CodeSnippet,
+			CreatedAt:      now,
+		}
+		_, err := tx.Exec(`
+			INSERT INTO findings (id, audit_id, agent_id, stage, title, description, severity, location_file, location_line,
+			                      location_function, recommendation, code_snippet, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, f.ID, auditID, f.AgentID, f.Stage, f.Title, f.Description, f.Severity, nullString(f.Location.File),
+			nullInt(f.Location.Line), nullString(f.Location.Function), nullString(f.Recommendation),
+			nullString(f.CodeSnippet), now)
+		if err != nil {
+			return nil, err
+		}
+		err = recordAuditEvent(tx, auditID, EventFindingCreated, findingEvent{
+			FindingID: f.ID, AgentID: f.AgentID, Stage: f.Stage, Title: f.Title, Severity: f.Severity,
+		})
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	a.events.notify(auditID)
+	return findings, nil
+}
+
+// runStage returns a run's audit, the stages the audit runs, the run's stage among them and the run's status
+func (a *App) runStage(runID string) (string, []PipelineStage, int, string, error) {
+	var auditID, status, agentsJSON string
+	var stage int
+	var stagesJSON sql.NullString
+	err := a.DB.QueryRow(`
+		SELECT agent_runs.audit_id, agent_runs.stage, agent_runs.status, audits.agents_used, audits.pipeline_stages
+		FROM agent_runs JOIN audits ON audits.id = agent_runs.audit_id
+		WHERE agent_runs.id = ?
+	`, runID).Scan(&auditID, &stage, &status, &agentsJSON, &stagesJSON)
+	if err != nil {
+		return "", nil, 0, "", err
+	}
+
+	stages := auditStages(stagesJSON, agentsJSON)
+	if stage < 0 || stage >= len(stages) {
+		return "", nil, 0, "", fmt.Errorf("run %s: stage %d out of range", runID, stage)
+	}
+	return auditID, stages, stage, status, nil
+}
+
+// runEvent returns the event payload identifying an agent run, and the run's audit
+func (a *App) runEvent(runID string) (runEvent, string, error) {
+	e := runEvent{RunID: runID}
+	var auditID string
+	err := a.DB.QueryRow(`SELECT audit_id, agent_id, stage FROM agent_runs WHERE id = ?`, runID).
+		Scan(&auditID, &e.AgentID, &e.Stage)
+	return e, auditID, err
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"watson/internal/sarif"
+)
+
+// handleGetFindingsSARIF exports the findings of an audit as a SARIF 2.1.0 log with one run per agent
+func (a *App) handleGetFindingsSARIF(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/findings.sarif
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing audit ID")
+		return
+	}
+
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	sarifLog, err := a.findingsSARIF(id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	b, err := json.Marshal(sarifLog)
+	if err != nil {
+		httpErr(w, 500, "json marshal")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/sarif+json")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-`+id+`.sarif"`)
+	w.WriteHeader(200)
+	w.Write(b)
+}
+
+// findingsSARIF builds the SARIF log of an audit. Every agent of the audit is a run's tool driver,
+// so agents without findings still show up with no results.
+func (a *App) findingsSARIF(auditID string) (*sarif.Log, error) {
+	rows, err := a.DB.Query(`
+		SELECT id, name, model FROM agents
+		WHERE id IN (SELECT value FROM json_each((SELECT agents_used FROM audits WHERE id = ?)))
+		   OR id IN (SELECT agent_id FROM findings WHERE audit_id = ?)
+		ORDER BY name, id
+	`, auditID, auditID)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []sarif.Run
+	index := map[string]int{}
+	for rows.Next() {
+		var agentID, name, model string
+		if err := rows.Scan(&agentID, &name, &model); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[agentID] = len(runs)
+		runs = append(runs, sarif.Run{
+			Tool: sarif.Tool{Driver: sarif.ToolComponent{
+				Name:       name,
+				FullName:   "Watson agent " + name + " (" + model + ")",
+				Rules:      []sarif.ReportingDescriptor{},
+				Properties: map[string]interface{}{"agent_id": agentID, "model": model},
+			}},
+			AutomationDetails: &sarif.AutomationDetails{ID: "watson/" + agentID + "/" + auditID},
+			Results:           []sarif.Result{},
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	justifications, err := a.triageJustifications(auditID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = a.DB.Query(`
+		SELECT `+findingColumns+`
+		FROM `+findingsFrom+`
+		WHERE findings.audit_id = ?
+		ORDER BY findings.rowid ASC
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ruleIndex := map[string]int{}
+	ruleSeverity := map[string]string{}
+	for rows.Next() {
+		f, err := scanFinding(rows)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[f.AgentID]
+		if !ok {
+			continue
+		}
+		run := &runs[i]
+
+		ruleID := sarif.RuleID(f.Title)
+		key := f.AgentID + "/" + ruleID
+		ri, ok := ruleIndex[key]
+		if !ok {
+			ri = len(run.Tool.Driver.Rules)
+			ruleIndex[key] = ri
+			ruleSeverity[key] = f.Severity
+			rule := sarif.ReportingDescriptor{
+				ID:                   ruleID,
+				ShortDescription:     &sarif.Message{Text: f.Title},
+				FullDescription:      &sarif.Message{Text: f.Description},
+				DefaultConfiguration: &sarif.Configuration{Level: sarif.Level(f.Severity)},
+				Properties: map[string]interface{}{
+					"security-severity": sarif.SecuritySeverity(f.Severity),
+					"tags":              []string{"security"},
+				},
+			}
+			if f.Recommendation != "" {
+				rule.Help = &sarif.Message{Text: f.Recommendation}
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		} else if severityWeights[f.Severity] > severityWeights[ruleSeverity[key]] {
+			// A rule is as severe as its most severe result
+			ruleSeverity[key] = f.Severity
+			rule := &run.Tool.Driver.Rules[ri]
+			rule.Properties["security-severity"] = sarif.SecuritySeverity(f.Severity)
+			rule.DefaultConfiguration.Level = sarif.Level(f.Severity)
+		}
+
+		result := sarif.Result{
+			RuleID:       ruleID,
+			RuleIndex:    ri,
+			Level:        sarif.Level(f.Severity),
+			Message:      sarif.Message{Text: f.Title + "\n\n" + f.Description},
+			Fingerprints: map[string]string{"watsonFindingId/v1": f.ID},
+			Properties: map[string]interface{}{
+				"severity": f.Severity,
+				"status":   f.Status,
+			},
+		}
+		if f.Recommendation != "" {
+			result.Message.Text += "\n\nRecommendation: " + f.Recommendation
+		}
+
+		var loc sarif.Location
+		if f.Location.File != "" {
+			loc.PhysicalLocation = &sarif.PhysicalLocation{
+				ArtifactLocation: sarif.ArtifactLocation{URI: sarif.ArtifactURI(f.Location.File)},
+			}
+			if f.Location.Line > 0 {
+				loc.PhysicalLocation.Region = &sarif.Region{StartLine: f.Location.Line}
+			}
+		}
+		if f.Location.Function != "" {
+			loc.LogicalLocations = []sarif.LogicalLocation{{Name: f.Location.Function, Kind: "function"}}
+		}
+		if loc.PhysicalLocation != nil || loc.LogicalLocations != nil {
+			result.Locations = []sarif.Location{loc}
+		}
+
+		// Findings dismissed during triage are exported as suppressed results
+		if f.Status == "false_positive" || f.Status == "wont_fix" {
+			result.Suppressions = []sarif.Suppression{{Kind: "external", Status: "accepted", Justification: justifications[f.ID]}}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if runs == nil {
+		runs = []sarif.Run{}
+	}
+	return &sarif.Log{Schema: sarif.Schema, Version: sarif.Version, Runs: runs}, nil
+}
+
+// triageJustifications returns the justification of the latest status change of each finding of an audit
+func (a *App) triageJustifications(auditID string) (map[string]string, error) {
+	rows, err := a.DB.Query(`
+		SELECT h.finding_id, COALESCE(h.justification, '')
+		FROM finding_status_history h
+		JOIN findings ON findings.id = h.finding_id
+		WHERE findings.audit_id = ?
+		ORDER BY h.created_at ASC, h.rowid ASC
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	justifications := map[string]string{}
+	for rows.Next() {
+		var findingID, justification string
+		if err := rows.Scan(&findingID, &justification); err != nil {
+			return nil, err
+		}
+		justifications[findingID] = justification
+	}
+	return justifications, rows.Err()
+}
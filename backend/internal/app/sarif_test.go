@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"watson/internal/db"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testApp returns an App on a migrated in-memory database
+func testApp(t *testing.T) *App {
+	t.Helper()
+	conn := db.MustOpenSQLite("file::memory:?_foreign_keys=on")
+	t.Cleanup(func() { conn.Close() })
+	db.MustMigrate(conn)
+	return &App{DB: conn}
+}
+
+// exec runs fixture statements, failing the test on the first error
+func exec(t *testing.T, a *App, statements ...string) {
+	t.Helper()
+	for _, s := range statements {
+		if _, err := a.DB.Exec(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
+
+func TestFindingsSARIF(t *testing.T) {
+	a := testApp(t)
+	exec(t, a,
+		`INSERT INTO agents (id, owner_address, name, model, system_prompt) VALUES
+			('ag1', '0x1111111111111111111111111111111111111111', 'Reentrancy hunter', 'openai/gpt-4o', ''),
+			('ag2', '0x1111111111111111111111111111111111111111', 'Access control', 'anthropic/claude-3.5-sonnet', ''),
+			('ag3', '0x1111111111111111111111111111111111111111', 'Gas', 'openai/gpt-4o-mini', '')`,
+		`INSERT INTO audits (id, owner_address, name, blockchain, status, agents_used)
+			VALUES ('au1', '0x1111111111111111111111111111111111111111', 'Vault', 'ethereum', 'completed', '["ag1","ag2","ag3"]')`,
+		`INSERT INTO findings (id, audit_id, agent_id, title, description, severity, location_file, location_line, location_function, recommendation, status) VALUES
+			('f1', 'au1', 'ag1', 'Reentrancy in withdraw', 'withdraw sends ETH before updating the balance.', 'high', './src/Vault.sol', 42, 'withdraw', 'Update the balance first.', 'open'),
+			('f2', 'au1', 'ag1', 'Reentrancy in withdraw!', 'emergencyWithdraw has the same flaw.', 'critical', 'src\Vault Lib.sol', NULL, NULL, NULL, 'confirmed'),
+			('f3', 'au1', 'ag1', 'Missing event', 'setOwner emits no event.', 'low', 'src/Vault.sol', 10, NULL, NULL, 'false_positive'),
+			('f4', 'au1', 'ag2', 'Unchecked call', 'The low-level call result is ignored.', 'medium', NULL, NULL, NULL, NULL, 'wont_fix'),
+			('f5', 'au1', 'ag2', 'tx.origin used for auth', 'onlyOwner checks tx.origin.', 'info', NULL, NULL, 'onlyOwner', NULL, 'open')`,
+		`INSERT INTO finding_status_history (id, finding_id, from_status, to_status, justification, actor, created_at) VALUES
+			('h1', 'f3', 'open', 'confirmed', 'Looked real', '0x1111111111111111111111111111111111111111', '2026-01-01 00:00:00'),
+			('h2', 'f3', 'confirmed', 'false_positive', 'OwnerChanged is emitted by the parent', '0x1111111111111111111111111111111111111111', '2026-01-02 00:00:00'),
+			('h3', 'f4', 'open', 'wont_fix', NULL, '0x1111111111111111111111111111111111111111', '2026-01-02 00:00:00')`,
+	)
+
+	got, err := a.findingsSARIF("au1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every result refers to the rule it's reported under
+	for _, run := range got.Runs {
+		for _, res := range run.Results {
+			if rules := run.Tool.Driver.Rules; res.RuleIndex >= len(rules) || rules[res.RuleIndex].ID != res.RuleID {
+				t.Errorf("%s: result %s has rule index %d", run.Tool.Driver.Name, res.RuleID, res.RuleIndex)
+			}
+		}
+	}
+
+	b, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, '\n')
+	golden := filepath.Join("testdata", "findings.sarif")
+	if *update {
+		if err := os.WriteFile(golden, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("SARIF log differs from %s (rerun with -update to accept):\n%s", golden, b)
+	}
+}
+
+func TestFindingsSARIFNoAgents(t *testing.T) {
+	a := testApp(t)
+	exec(t, a, `INSERT INTO audits (id, owner_address, name, blockchain) VALUES ('au1', '0x1111111111111111111111111111111111111111', 'Vault', 'ethereum')`)
+
+	got, err := a.findingsSARIF("au1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"$schema":"https://json.schemastore.org/sarif-2.1.0.json","version":"2.1.0","runs":[]}`; string(b) != want {
+		t.Errorf("log = %s, want %s", b, want)
+	}
+}
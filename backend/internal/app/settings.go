@@ -0,0 +1,92 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// UserSettings represents per-user preferences
+type UserSettings struct {
+	CostCeilingUSD *float64   `json:"cost_ceiling_usd"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateSettingsRequest represents the request to update user settings
+type UpdateSettingsRequest struct {
+	CostCeilingUSD *float64 `json:"cost_ceiling_usd"`
+}
+
+// getUserSettings returns the settings for an address, or defaults if none were saved
+func (a *App) getUserSettings(address string) (*UserSettings, error) {
+	var settings UserSettings
+	var ceiling sql.NullFloat64
+	var updatedAt time.Time
+	err := a.DB.QueryRow(`
+		SELECT cost_ceiling_usd, updated_at FROM user_settings WHERE address = ?
+	`, address).Scan(&ceiling, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &UserSettings{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ceiling.Valid {
+		settings.CostCeilingUSD = &ceiling.Float64
+	}
+	settings.UpdatedAt = &updatedAt
+	return &settings, nil
+}
+
+// handleGetSettings returns the authenticated user's settings
+func (a *App) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	settings, err := a.getUserSettings(address)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, settings)
+}
+
+// handleUpdateSettings replaces the authenticated user's settings
+func (a *App) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	var req UpdateSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	// Validation
+	if req.CostCeilingUSD != nil && *req.CostCeilingUSD < 0 {
+		httpErr(w, 400, "cost_ceiling_usd must not be negative")
+		return
+	}
+
+	now := time.Now().UTC()
+	_, err := a.DB.Exec(`
+		INSERT INTO user_settings (address, cost_ceiling_usd, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (address) DO UPDATE SET cost_ceiling_usd = excluded.cost_ceiling_usd, updated_at = excluded.updated_at
+	`, address, req.CostCeilingUSD, now)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, UserSettings{CostCeilingUSD: req.CostCeilingUSD, UpdatedAt: &now})
+}
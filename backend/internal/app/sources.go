@@ -0,0 +1,385 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"watson/internal/proxy"
+	"watson/internal/source"
+)
+
+// githubSnapshot is the source of an audit fetched from GitHub
+type githubSnapshot struct {
+	Ref       string // as asked for, from github_ref or the URL
+	CommitSHA string
+	Files     []source.File
+}
+
+// snapshotGitHub resolves a repository URL and ref to a commit and downloads its Solidity and Vyper files.
+// A non-empty msg describes why the repository can't be used; err is set when GitHub couldn't be reached.
+func (a *App) snapshotGitHub(ctx context.Context, githubURL, ref string) (snap *githubSnapshot, msg string, err error) {
+	repo, err := source.ParseGitHubURL(githubURL, a.GitHub.WebHost)
+	if err != nil {
+		return nil, "github_url: " + err.Error(), nil
+	}
+	if ref == "" {
+		ref = repo.Ref
+	}
+
+	snap = &githubSnapshot{Ref: ref}
+	snap.CommitSHA, err = a.GitHub.ResolveCommit(ctx, repo, ref)
+	if errors.Is(err, source.ErrNotFound) {
+		return nil, "github_url: repository or ref not found", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	snap.Files, err = a.GitHub.Snapshot(ctx, repo, snap.CommitSHA)
+	if errors.Is(err, source.ErrTooLarge) || errors.Is(err, source.ErrNotFound) || errors.Is(err, source.ErrDuplicatePath) {
+		return nil, "github_url: " + err.Error(), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(snap.Files) == 0 {
+		return nil, "github_url: repository has no Solidity or Vyper files", nil
+	}
+	return snap, "", nil
+}
+
+// fetchVerifiedSource downloads from its chain's block explorer the verified source of the code an audit
+// of contractAddress analyzes: the contract itself or, for a proxy, the implementations behind it.
+// Sources of several implementations, such as diamond facets, are put under a directory per address.
+// A non-empty msg describes why the contract can't be used; err is set when the explorer couldn't be reached,
+// or wraps source.ErrNotVerified when a contract's source isn't verified.
+func (a *App) fetchVerifiedSource(ctx context.Context, chain *Chain, contractAddress string, topology *proxy.Topology) (merged *source.Verified, msg string, err error) {
+	if chain.ExplorerAPIURL == "" {
+		return nil, "contract_address: no block explorer is configured for " + chain.ID, nil
+	}
+	addresses := []string{contractAddress}
+	if topology != nil {
+		addresses = topology.Implementations
+	}
+
+	for _, addr := range addresses {
+		label := "contract_address"
+		if topology != nil {
+			label = "contract_address: implementation " + addr
+		}
+
+		v, err := a.Explorer.SourceCode(ctx, chain.ExplorerAPIURL, chain.ChainID, addr)
+		if errors.Is(err, source.ErrNotVerified) {
+			return nil, "", fmt.Errorf("%s: %w on %s", label, err, chain.ID)
+		}
+		if errors.Is(err, source.ErrTooLarge) {
+			return nil, label + ": " + err.Error(), nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if len(v.Files) == 0 {
+			return nil, label + ": verified source has no files", nil
+		}
+
+		if len(addresses) > 1 {
+			for i := range v.Files {
+				v.Files[i].Path = addr + "/" + v.Files[i].Path
+			}
+			for i := range v.Skipped {
+				v.Skipped[i] = addr + ": " + v.Skipped[i]
+			}
+		}
+		merged = mergeVerified(merged, v)
+	}
+
+	total := 0
+	for _, f := range merged.Files {
+		total += len(f.Content)
+	}
+	if len(merged.Files) > source.MaxFiles || total > source.MaxTotalSize {
+		return nil, "contract_address: " + source.ErrTooLarge.Error(), nil
+	}
+	return merged, "", nil
+}
+
+// mergeVerified adds the source of another implementation to merged.
+// The compiler is kept only while every implementation shares it.
+func mergeVerified(merged, v *source.Verified) *source.Verified {
+	if merged == nil {
+		return v
+	}
+	merged.ContractName += ", " + v.ContractName
+	merged.Files = append(merged.Files, v.Files...)
+	merged.Skipped = append(merged.Skipped, v.Skipped...)
+	if merged.CompilerVersion != v.CompilerVersion {
+		merged.CompilerVersion = ""
+	}
+	if !bytes.Equal(merged.Settings, v.Settings) {
+		merged.Settings = nil
+	}
+	return merged
+}
+
+// resolveProxy follows contractAddress to its implementations through the chain's RPC.
+// It returns nil when the address isn't a proxy, or no RPC is configured for the chain.
+func (a *App) resolveProxy(ctx context.Context, chain *Chain, contractAddress string) (*proxy.Topology, error) {
+	client, err := a.Chains.Client(chain.ID)
+	if client == nil || err != nil || contractAddress == "" {
+		return nil, err
+	}
+	return proxy.Resolve(ctx, client, common.HexToAddress(contractAddress))
+}
+
+// singleSourcePath is the file a source_code-only audit is stored as, as the 0020 migration does
+const singleSourcePath = "Contract.sol"
+
+// maxUploadSize bounds a multipart POST /audits, archive included
+const maxUploadSize = 50 << 20 // 50MB
+
+// SourceFileInput is a source file given inline when creating an audit
+type SourceFileInput struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// SourceFile describes a file of an audit's source snapshot
+type SourceFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// readAuditUpload reads a multipart POST /audits: the audit JSON in the "audit" field and an
+// optional zip, tar or tar.gz in the "archive" file. A non-empty msg describes what's wrong with the upload.
+func readAuditUpload(w http.ResponseWriter, r *http.Request, req *CreateAuditRequest) (archive []byte, msg string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, "upload must be at most " + strconv.Itoa(maxUploadSize>>20) + "MB"
+		}
+		return nil, "bad multipart form"
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	if err := json.Unmarshal([]byte(r.FormValue("audit")), req); err != nil {
+		return nil, "audit field must be the audit as JSON"
+	}
+
+	f, _, err := r.FormFile("archive")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, ""
+	}
+	if err != nil {
+		return nil, "bad multipart form"
+	}
+	defer f.Close()
+	archive, err = io.ReadAll(f)
+	if err != nil {
+		return nil, "bad multipart form"
+	}
+	return archive, ""
+}
+
+// requestSources collects the files an audit is created with: an uploaded archive, inline files,
+// or source_code as a single file. A non-empty msg describes why they can't be used.
+func requestSources(req *CreateAuditRequest, archive []byte) ([]source.File, string) {
+	given := 0
+	for _, ok := range []bool{archive != nil, len(req.Files) > 0, req.SourceCode != ""} {
+		if ok {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, "archive, files and source_code are mutually exclusive"
+	}
+
+	switch {
+	case archive != nil:
+		files, err := source.ExtractArchive(archive)
+		if errors.Is(err, source.ErrUnknownArchive) {
+			return nil, err.Error()
+		}
+		if errors.Is(err, source.ErrTooLarge) || errors.Is(err, source.ErrDuplicatePath) {
+			return nil, "archive: " + err.Error()
+		}
+		if err != nil {
+			return nil, "archive could not be read"
+		}
+		if len(files) == 0 {
+			return nil, "archive has no Solidity or Vyper files"
+		}
+		return files, ""
+
+	case len(req.Files) > 0:
+		if len(req.Files) > source.MaxFiles {
+			return nil, "files: " + source.ErrTooLarge.Error()
+		}
+		files := make([]source.File, 0, len(req.Files))
+		seen := map[string]bool{}
+		total := 0
+		for _, in := range req.Files {
+			p := source.CleanPath(in.Path, 0)
+			switch {
+			case p == "":
+				return nil, "files: invalid path " + strconv.Quote(in.Path)
+			case !source.Wanted(p):
+				return nil, "files: " + p + " is not a Solidity, Vyper or import config file"
+			case seen[p]:
+				return nil, "files: duplicate path " + p
+			case len(in.Content) > source.MaxFileSize:
+				return nil, "files: " + p + " is larger than 1MB"
+			}
+			seen[p] = true
+			total += len(in.Content)
+			files = append(files, source.File{Path: p, Content: []byte(in.Content)})
+		}
+		if total > source.MaxTotalSize {
+			return nil, "files: " + source.ErrTooLarge.Error()
+		}
+		return files, ""
+
+	case req.SourceCode != "":
+		if len(req.SourceCode) > source.MaxFileSize {
+			return nil, "source_code must be at most 1MB"
+		}
+		return []source.File{{Path: singleSourcePath, Content: []byte(req.SourceCode)}}, ""
+	}
+	return nil, ""
+}
+
+// insertSourceFiles stores the snapshot of an audit's source files. Contents are stored once
+// in source_blobs, so audits of the same code share them.
+func insertSourceFiles(tx *sql.Tx, auditID string, files []source.File, now time.Time) error {
+	for _, f := range files {
+		sum := sha256.Sum256(f.Content)
+		hash := hex.EncodeToString(sum[:])
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO source_blobs (sha256, content, size, created_at)
+			VALUES (?, ?, ?, ?)
+		`, hash, f.Content, len(f.Content), now)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO source_files (audit_id, path, sha256, size, created_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, auditID, f.Path, hash, len(f.Content), now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneSourceBlobs deletes the contents no audit's source files refer to anymore
+func pruneSourceBlobs(q dbtx) error {
+	_, err := q.Exec(`
+		DELETE FROM source_blobs
+		WHERE NOT EXISTS (SELECT 1 FROM source_files WHERE source_files.sha256 = source_blobs.sha256)
+	`)
+	return err
+}
+
+// countSourceFiles returns the number of files in an audit's source snapshot
+func (a *App) countSourceFiles(auditID string) int {
+	var n int
+	a.DB.QueryRow(`SELECT COUNT(*) FROM source_files WHERE audit_id = ?`, auditID).Scan(&n)
+	return n
+}
+
+// handleGetAuditFiles lists the files of an audit's source snapshot
+func (a *App) handleGetAuditFiles(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/files
+	id := r.PathValue("id")
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	rows, err := a.DB.Query(`SELECT path, size, sha256 FROM source_files WHERE audit_id = ?`, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	files := []SourceFile{}
+	var total int64
+	for rows.Next() {
+		var f SourceFile
+		if err := rows.Scan(&f.Path, &f.Size, &f.SHA256); err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		total += f.Size
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	writeJSON(w, 200, map[string]interface{}{
+		"files":      files,
+		"total":      len(files),
+		"total_size": total,
+	})
+}
+
+// handleGetAuditFile returns the raw content of a file of an audit's source snapshot
+func (a *App) handleGetAuditFile(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID and file path from path: /audits/{id}/files/{path...}
+	id := r.PathValue("id")
+	if !a.checkAuditAccess(w, id, address) {
+		return
+	}
+
+	var sum string
+	var content []byte
+	err := a.DB.QueryRow(`
+		SELECT source_blobs.sha256, source_blobs.content
+		FROM source_files JOIN source_blobs ON source_blobs.sha256 = source_files.sha256
+		WHERE source_files.audit_id = ? AND source_files.path = ?
+	`, id, r.PathValue("path")).Scan(&sum, &content)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "File not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("ETag", `"`+sum+`"`)
+	w.WriteHeader(200)
+	w.Write(content)
+}
@@ -0,0 +1,256 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// AgentStats represents performance metrics for an agent derived from its findings and runs
+type AgentStats struct {
+	AgentID           string        `json:"agent_id"`
+	Name              string        `json:"name"`
+	Model             string        `json:"model"`
+	Rank              int           `json:"rank,omitempty"`
+	Score             float64       `json:"score"`
+	AuditsUsedIn      int           `json:"audits_used_in"`
+	Runs              int           `json:"runs"`
+	TotalFindings     int           `json:"total_findings"`
+	Findings          FindingsCount `json:"findings"`
+	TriagedFindings   int           `json:"triaged_findings"`
+	ConfirmedRate     *float64      `json:"confirmed_rate"`      // share of triaged findings that are real issues
+	FalsePositiveRate *float64      `json:"false_positive_rate"` // share of triaged findings that are false positives
+	AvgRuntimeSeconds *float64      `json:"avg_runtime_seconds"`
+	PromptTokens      int64         `json:"prompt_tokens"`
+	CompletionTokens  int64         `json:"completion_tokens"`
+	CostUSD           float64       `json:"cost_usd"`
+}
+
+// severityWeights is used to score agents by the impact of what they find
+var severityWeights = map[string]float64{
+	"critical": 10,
+	"high":     5,
+	"medium":   2,
+	"low":      1,
+	"info":     0,
+}
+
+// handleGetAgentStats returns performance metrics for a specific agent
+func (a *App) handleGetAgentStats(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /agents/{id}/stats
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing agent ID")
+		return
+	}
+
+	var ownerAddress, name, model string
+	err := a.DB.QueryRow(`SELECT owner_address, name, model FROM agents WHERE id = ?`, id).Scan(&ownerAddress, &name, &model)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Agent not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	// Check ownership (users can only view stats of their own agents)
+	if ownerAddress != address {
+		httpErr(w, 404, "Agent not found")
+		return
+	}
+
+	stats, err := a.agentStats(id, name, model)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, stats)
+}
+
+// handleGetAgentRanking returns stats for all of the user's agents, ranked against each other.
+// Archived agents are only included with ?include_archived=true.
+func (a *App) handleGetAgentRanking(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "score"
+	}
+	less, ok := agentRankings[sortBy]
+	if !ok {
+		httpErr(w, 400, "sort must be one of: score, findings, audits, runtime, tokens")
+		return
+	}
+
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+	rows, err := a.DB.Query(`
+		SELECT id, name, model
+		FROM agents
+		WHERE owner_address = ? AND (archived_at IS NULL OR ?)
+		ORDER BY created_at DESC
+	`, address, includeArchived)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	type agentRef struct{ id, name, model string }
+	var refs []agentRef
+	for rows.Next() {
+		var ref agentRef
+		if err := rows.Scan(&ref.id, &ref.name, &ref.model); err != nil {
+			rows.Close()
+			httpErr(w, 500, "scan")
+			return
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+
+	ranking := []AgentStats{}
+	for _, ref := range refs {
+		stats, err := a.agentStats(ref.id, ref.name, ref.model)
+		if err != nil {
+			httpErr(w, 500, "db")
+			return
+		}
+		ranking = append(ranking, *stats)
+	}
+
+	sort.SliceStable(ranking, func(i, j int) bool { return less(&ranking[i], &ranking[j]) })
+	for i := range ranking {
+		ranking[i].Rank = i + 1
+	}
+
+	writeJSON(w, 200, map[string]interface{}{
+		"agents": ranking,
+		"sort":   sortBy,
+	})
+}
+
+// agentRankings orders agents best-first for each supported sort key
+var agentRankings = map[string]func(x, y *AgentStats) bool{
+	"score":    func(x, y *AgentStats) bool { return x.Score > y.Score },
+	"findings": func(x, y *AgentStats) bool { return x.TotalFindings > y.TotalFindings },
+	"audits":   func(x, y *AgentStats) bool { return x.AuditsUsedIn > y.AuditsUsedIn },
+	"runtime": func(x, y *AgentStats) bool {
+		// Agents without completed runs sort last
+		if x.AvgRuntimeSeconds == nil || y.AvgRuntimeSeconds == nil {
+			return x.AvgRuntimeSeconds != nil
+		}
+		return *x.AvgRuntimeSeconds < *y.AvgRuntimeSeconds
+	},
+	"tokens": func(x, y *AgentStats) bool {
+		return x.PromptTokens+x.CompletionTokens < y.PromptTokens+y.CompletionTokens
+	},
+}
+
+// agentStats computes performance metrics for an agent
+func (a *App) agentStats(agentID, name, model string) (*AgentStats, error) {
+	stats := &AgentStats{
+		AgentID: agentID,
+		Name:    name,
+		Model:   model,
+	}
+
+	stats.Findings = *a.countFindings(`agent_id = ?`, agentID)
+	stats.TotalFindings = stats.Findings.Total()
+
+	// Rates only consider findings a reviewer has already triaged
+	var confirmed, falsePositives int
+	err := a.DB.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(status IN `+confirmedStatuses+`), 0),
+			COALESCE(SUM(status = 'false_positive'), 0)
+		FROM findings
+		WHERE agent_id = ? AND status != 'open'
+	`, agentID).Scan(&stats.TriagedFindings, &confirmed, &falsePositives)
+	if err != nil {
+		return nil, err
+	}
+	if stats.TriagedFindings > 0 {
+		confirmedRate := float64(confirmed) / float64(stats.TriagedFindings)
+		falsePositiveRate := float64(falsePositives) / float64(stats.TriagedFindings)
+		stats.ConfirmedRate = &confirmedRate
+		stats.FalsePositiveRate = &falsePositiveRate
+	}
+
+	// An agent counts as used in an audit if it was selected for it or produced findings in it
+	err = a.DB.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT audits.id FROM audits, json_each(audits.agents_used) WHERE json_each.value = ?
+			UNION
+			SELECT audit_id FROM findings WHERE agent_id = ?
+		)
+	`, agentID, agentID).Scan(&stats.AuditsUsedIn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.DB.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM agent_runs
+		WHERE agent_id = ?
+	`, agentID).Scan(&stats.Runs, &stats.PromptTokens, &stats.CompletionTokens, &stats.CostUSD)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT started_at, finished_at
+		FROM agent_runs
+		WHERE agent_id = ? AND status = 'completed' AND started_at IS NOT NULL AND finished_at IS NOT NULL
+	`, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var total time.Duration
+	var completed int
+	for rows.Next() {
+		var startedAt, finishedAt time.Time
+		if err := rows.Scan(&startedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		total += finishedAt.Sub(startedAt)
+		completed++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if completed > 0 {
+		avg := total.Seconds() / float64(completed)
+		stats.AvgRuntimeSeconds = &avg
+	}
+
+	// Score is the severity-weighted number of findings per audit the agent was used in
+	if stats.AuditsUsedIn > 0 {
+		f := stats.Findings
+		weighted := float64(f.Critical)*severityWeights["critical"] +
+			float64(f.High)*severityWeights["high"] +
+			float64(f.Medium)*severityWeights["medium"] +
+			float64(f.Low)*severityWeights["low"] +
+			float64(f.Info)*severityWeights["info"]
+		stats.Score = weighted / float64(stats.AuditsUsedIn)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,390 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// findingTransitions lists the statuses a finding can move to from each status
+var findingTransitions = map[string][]string{
+	"open":           {"confirmed", "false_positive", "wont_fix"},
+	"confirmed":      {"open", "false_positive", "wont_fix", "fixed"},
+	"false_positive": {"open"},
+	"wont_fix":       {"open", "confirmed"},
+	"fixed":          {"open"},
+}
+
+// justificationRequired lists the statuses that can only be set with a justification
+var justificationRequired = map[string]bool{
+	"false_positive": true,
+	"wont_fix":       true,
+}
+
+// confirmedStatuses are the statuses of findings triaged as real issues
+const confirmedStatuses = `('confirmed', 'wont_fix', 'fixed')`
+
+// errFindingStatusChanged means a finding's status changed between reading and updating it
+var errFindingStatusChanged = errors.New("finding status changed concurrently")
+
+// StatusChange represents one triage status transition of a finding. The actor is the address of the
+// user who made it, or "stage:" and the name of the verifier stage that did.
+type StatusChange struct {
+	ID            string    `json:"id"`
+	FindingID     string    `json:"finding_id"`
+	FromStatus    string    `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	Justification string    `json:"justification,omitempty"`
+	Actor         string    `json:"actor"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Comment represents a message in a finding's discussion thread
+type Comment struct {
+	ID        string    `json:"id"`
+	FindingID string    `json:"finding_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UpdateFindingStatusRequest represents the request to change a finding's triage status
+type UpdateFindingStatusRequest struct {
+	Status        string `json:"status"`
+	Justification string `json:"justification"`
+}
+
+// UpdateFindingAssigneeRequest represents the request to assign a finding; an empty assignee unassigns it
+type UpdateFindingAssigneeRequest struct {
+	Assignee string `json:"assignee"`
+}
+
+// CreateCommentRequest represents the request to comment on a finding
+type CreateCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// handleUpdateFindingStatus moves a finding to a new triage status and records the change
+func (a *App) handleUpdateFindingStatus(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /findings/{id}/status
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing finding ID")
+		return
+	}
+
+	var req UpdateFindingStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	req.Status = strings.TrimSpace(req.Status)
+	req.Justification = strings.TrimSpace(req.Justification)
+
+	f, owned, err := a.getFinding(id, address)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Finding not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if !owned {
+		httpErr(w, 403, "Not authorized to triage this finding")
+		return
+	}
+
+	if _, ok := findingTransitions[req.Status]; !ok {
+		httpErr(w, 400, "status must be one of: open, confirmed, false_positive, wont_fix, fixed")
+		return
+	}
+	if !canTransitionFinding(f.Status, req.Status) {
+		httpErr(w, 409, "Finding can't move from "+f.Status+" to "+req.Status)
+		return
+	}
+	if justificationRequired[req.Status] && req.Justification == "" {
+		httpErr(w, 400, "justification is required for "+req.Status)
+		return
+	}
+	if len(req.Justification) > 5000 {
+		httpErr(w, 400, "justification must be at most 5000 characters")
+		return
+	}
+
+	err = a.setFindingStatus(id, f.Status, req.Status, req.Justification, address)
+	if errors.Is(err, errFindingStatusChanged) {
+		httpErr(w, 409, "Finding status changed concurrently")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	f.Status = req.Status
+	writeJSON(w, 200, f)
+}
+
+// handleUpdateFindingAssignee assigns a finding to a teammate's address
+func (a *App) handleUpdateFindingAssignee(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /findings/{id}/assignee
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing finding ID")
+		return
+	}
+
+	var req UpdateFindingAssigneeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	req.Assignee = strings.TrimSpace(req.Assignee)
+
+	var assignee interface{}
+	if req.Assignee != "" {
+		if !common.IsHexAddress(req.Assignee) {
+			httpErr(w, 400, "assignee must be an ethereum address")
+			return
+		}
+		req.Assignee = strings.ToLower(common.HexToAddress(req.Assignee).Hex())
+		assignee = req.Assignee
+	}
+
+	f, owned, err := a.getFinding(id, address)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Finding not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if !owned {
+		httpErr(w, 403, "Not authorized to assign this finding")
+		return
+	}
+
+	if _, err := a.DB.Exec(`UPDATE findings SET assignee = ? WHERE id = ?`, assignee, id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	f.Assignee = req.Assignee
+	writeJSON(w, 200, f)
+}
+
+// handleGetFindingHistory returns the triage status changes of a finding, oldest first. The finding's
+// assignee can read it too.
+func (a *App) handleGetFindingHistory(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /findings/{id}/history
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing finding ID")
+		return
+	}
+
+	if _, _, err := a.getFinding(id, address); errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Finding not found")
+		return
+	} else if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT id, finding_id, from_status, to_status, justification, actor, created_at
+		FROM finding_status_history
+		WHERE finding_id = ?
+		ORDER BY created_at ASC, rowid ASC
+	`, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	history := []StatusChange{}
+	for rows.Next() {
+		var c StatusChange
+		var justification sql.NullString
+		if err := rows.Scan(&c.ID, &c.FindingID, &c.FromStatus, &c.ToStatus, &justification, &c.Actor, &c.CreatedAt); err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		c.Justification = justification.String
+		history = append(history, c)
+	}
+
+	writeJSON(w, 200, map[string][]StatusChange{"history": history})
+}
+
+// handleGetFindingComments returns the discussion thread of a finding, oldest first. The finding's
+// assignee can read it too.
+func (a *App) handleGetFindingComments(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /findings/{id}/comments
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing finding ID")
+		return
+	}
+
+	if _, _, err := a.getFinding(id, address); errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Finding not found")
+		return
+	} else if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT id, finding_id, author, body, created_at
+		FROM finding_comments
+		WHERE finding_id = ?
+		ORDER BY created_at ASC, rowid ASC
+	`, id)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.FindingID, &c.Author, &c.Body, &c.CreatedAt); err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		comments = append(comments, c)
+	}
+
+	writeJSON(w, 200, map[string][]Comment{"comments": comments})
+}
+
+// handleCreateFindingComment adds a comment to a finding's discussion thread, as the owner of its audit
+// or its assignee
+func (a *App) handleCreateFindingComment(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /findings/{id}/comments
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing finding ID")
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" || len(req.Body) > 10000 {
+		httpErr(w, 400, "body must be 1-10000 characters")
+		return
+	}
+
+	if _, _, err := a.getFinding(id, address); errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Finding not found")
+		return
+	} else if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	c := Comment{
+		ID:        uuid.NewString(),
+		FindingID: id,
+		Author:    address,
+		Body:      req.Body,
+		CreatedAt: time.Now().UTC(),
+	}
+	_, err := a.DB.Exec(`
+		INSERT INTO finding_comments (id, finding_id, author, body, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, c.ID, c.FindingID, c.Author, c.Body, c.CreatedAt)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 201, c)
+}
+
+// setFindingStatus moves a finding from one triage status to another and records the change by actor.
+// The canonical finding of its cluster is picked again, as the status ranks the cluster's members.
+func (a *App) setFindingStatus(id, from, to, justification, actor string) error {
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Guard against a concurrent change between reading and updating the status
+	res, err := tx.Exec(`UPDATE findings SET status = ? WHERE id = ? AND status = ?`, to, id, from)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errFindingStatusChanged
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO finding_status_history (id, finding_id, from_status, to_status, justification, actor, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, uuid.NewString(), id, from, to, justification, actor, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := repickCanonical(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// canTransitionFinding reports whether a finding may move between two triage statuses
+func canTransitionFinding(from, to string) bool {
+	for _, s := range findingTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Usage is the resource consumption reported by an agent run
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	ToolCalls        int64 `json:"tool_calls"`
+}
+
+// UsageSummary represents aggregated token usage and cost
+type UsageSummary struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	ToolCalls        int64   `json:"tool_calls"`
+	CostUSD          float64 `json:"cost_usd"`
+	UnpricedEntries  int64   `json:"unpriced_entries,omitempty"`
+}
+
+// AgentUsage represents spend attributed to one agent
+type AgentUsage struct {
+	AgentID   string `json:"agent_id"`
+	AgentName string `json:"agent_name,omitempty"`
+	UsageSummary
+}
+
+// ModelUsage represents spend attributed to one model
+type ModelUsage struct {
+	Model string `json:"model"`
+	UsageSummary
+}
+
+// MonthlyUsage represents a user's spend in one calendar month (UTC)
+type MonthlyUsage struct {
+	Month string `json:"month"` // YYYY-MM
+	UsageSummary
+}
+
+// usageColumns aggregates usage_ledger rows into the UsageSummary fields
+const usageColumns = `
+	COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(tool_calls), 0),
+	COALESCE(SUM(cost_usd), 0), COALESCE(SUM(1 - priced), 0)`
+
+func (s *UsageSummary) scanArgs() []interface{} {
+	return []interface{}{&s.PromptTokens, &s.CompletionTokens, &s.ToolCalls, &s.CostUSD, &s.UnpricedEntries}
+}
+
+// usageEntry is a usage ledger entry of an agent run, priced and ready to be recorded
+type usageEntry struct {
+	Usage
+	RunID, AuditID, AgentID, OwnerAddress, Model string
+	CostUSD                                      float64
+	Priced                                       bool
+}
+
+// recordUsage appends a ledger entry for an agent run and adds it to the run's totals.
+// Cost is computed from the pricing of the agent's model at the time of recording.
+func (a *App) recordUsage(runID string, u Usage) error {
+	e, err := a.priceUsage(runID, u)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertUsage(tx, e); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	a.events.notify(e.AuditID)
+	return nil
+}
+
+// priceUsage prices usage of an agent run with its agent's model. It runs before the entry's transaction, as
+// looking up the pricing may fetch the model list.
+func (a *App) priceUsage(runID string, u Usage) (usageEntry, error) {
+	e := usageEntry{Usage: u, RunID: runID}
+	err := a.DB.QueryRow(`
+		SELECT agent_runs.audit_id, agent_runs.agent_id, audits.owner_address, agents.model
+		FROM agent_runs
+		JOIN audits ON audits.id = agent_runs.audit_id
+		JOIN agents ON agents.id = agent_runs.agent_id
+		WHERE agent_runs.id = ?
+	`, runID).Scan(&e.AuditID, &e.AgentID, &e.OwnerAddress, &e.Model)
+	if err != nil {
+		return e, err
+	}
+
+	if m, ok := a.lookupModel(e.Model); ok {
+		e.CostUSD, e.Priced = m.Pricing.Cost(u.PromptTokens, u.CompletionTokens)
+	}
+	return e, nil
+}
+
+// insertUsage appends a priced entry to the ledger, adds it to the run's totals and records a cost.updated
+// event with the audit's new running total; notify a.events after committing
+func insertUsage(tx *sql.Tx, e usageEntry) error {
+	_, err := tx.Exec(`
+		INSERT INTO usage_ledger (id, owner_address, audit_id, run_id, agent_id, model,
+		                          prompt_tokens, completion_tokens, tool_calls, cost_usd, priced, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.NewString(), e.OwnerAddress, e.AuditID, e.RunID, e.AgentID, e.Model,
+		e.PromptTokens, e.CompletionTokens, e.ToolCalls, e.CostUSD, e.Priced, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE agent_runs
+		SET prompt_tokens = prompt_tokens + ?, completion_tokens = completion_tokens + ?,
+		    tool_calls = tool_calls + ?, cost_usd = cost_usd + ?
+		WHERE id = ?
+	`, e.PromptTokens, e.CompletionTokens, e.ToolCalls, e.CostUSD, e.RunID)
+	if err != nil {
+		return err
+	}
+
+	// Stream the audit's new running total along with this entry
+	ce := costEvent{RunID: e.RunID, AgentID: e.AgentID, CostUSD: e.CostUSD}
+	err = tx.QueryRow(`SELECT `+usageColumns+` FROM usage_ledger WHERE audit_id = ?`, e.AuditID).Scan(ce.Total.scanArgs()...)
+	if err != nil {
+		return err
+	}
+	return recordAuditEvent(tx, e.AuditID, EventCostUpdated, ce)
+}
+
+// costEvent is the payload of cost.updated events
+type costEvent struct {
+	RunID   string       `json:"run_id"`
+	AgentID string       `json:"agent_id"`
+	CostUSD float64      `json:"cost_usd"`
+	Total   UsageSummary `json:"total"`
+}
+
+// getAuditUsage returns the usage rollup for an audit
+func (a *App) getAuditUsage(auditID string) *UsageSummary {
+	usage := &UsageSummary{}
+	err := a.DB.QueryRow(`SELECT `+usageColumns+` FROM usage_ledger WHERE audit_id = ?`, auditID).Scan(usage.scanArgs()...)
+	if err != nil {
+		return &UsageSummary{}
+	}
+	return usage
+}
+
+// handleGetUsage returns the user's spend for a month broken down by agent and model
+func (a *App) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	month := r.URL.Query().Get("month")
+	var start time.Time
+	if month == "" {
+		now := time.Now().UTC()
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	} else {
+		t, err := time.Parse("2006-01", month)
+		if err != nil {
+			httpErr(w, 400, "month must be in YYYY-MM format")
+			return
+		}
+		start = t
+	}
+	end := start.AddDate(0, 1, 0)
+
+	var total UsageSummary
+	err := a.DB.QueryRow(`
+		SELECT `+usageColumns+`
+		FROM usage_ledger
+		WHERE owner_address = ? AND created_at >= ? AND created_at < ?
+	`, address, start, end).Scan(total.scanArgs()...)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT usage_ledger.agent_id, COALESCE(agents.name, ''), `+usageColumns+`
+		FROM usage_ledger
+		LEFT JOIN agents ON agents.id = usage_ledger.agent_id
+		WHERE usage_ledger.owner_address = ? AND usage_ledger.created_at >= ? AND usage_ledger.created_at < ?
+		GROUP BY usage_ledger.agent_id
+		ORDER BY 7 DESC
+	`, address, start, end)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	byAgent := []AgentUsage{}
+	for rows.Next() {
+		var u AgentUsage
+		if err := rows.Scan(append([]interface{}{&u.AgentID, &u.AgentName}, u.scanArgs()...)...); err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		byAgent = append(byAgent, u)
+	}
+
+	modelRows, err := a.DB.Query(`
+		SELECT model, `+usageColumns+`
+		FROM usage_ledger
+		WHERE owner_address = ? AND created_at >= ? AND created_at < ?
+		GROUP BY model
+		ORDER BY 5 DESC
+	`, address, start, end)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer modelRows.Close()
+
+	byModel := []ModelUsage{}
+	for modelRows.Next() {
+		var u ModelUsage
+		if err := modelRows.Scan(append([]interface{}{&u.Model}, u.scanArgs()...)...); err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		byModel = append(byModel, u)
+	}
+
+	writeJSON(w, 200, map[string]interface{}{
+		"month":    start.Format("2006-01"),
+		"total":    total,
+		"by_agent": byAgent,
+		"by_model": byModel,
+	})
+}
+
+// handleGetMonthlyUsage returns the user's spend totals per month, most recent first
+func (a *App) handleGetMonthlyUsage(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	months := 12
+	if v := r.URL.Query().Get("months"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 120 {
+			months = n
+		}
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT strftime('%Y-%m', created_at) AS month, `+usageColumns+`
+		FROM usage_ledger
+		WHERE owner_address = ?
+		GROUP BY month
+		ORDER BY month DESC
+		LIMIT ?
+	`, address, months)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	totals := []MonthlyUsage{}
+	for rows.Next() {
+		var u MonthlyUsage
+		var month sql.NullString
+		if err := rows.Scan(append([]interface{}{&month}, u.scanArgs()...)...); err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		u.Month = month.String
+		totals = append(totals, u)
+	}
+
+	writeJSON(w, 200, map[string][]MonthlyUsage{"months": totals})
+}
@@ -0,0 +1,373 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"watson/internal/bytecode"
+	"watson/internal/proxy"
+)
+
+// verificationFailed is the status of a verification that couldn't compare bytecode, e.g. as the snapshot didn't compile
+const verificationFailed = "failed"
+
+// verificationTimeout bounds a whole verification: compiling the snapshot and reading the deployed code
+const verificationTimeout = 10 * time.Minute
+
+// Verification is the outcome of compiling an audit's source snapshot and comparing it with the
+// runtime bytecode of contract_address, or of the implementations behind it
+type Verification struct {
+	Status          string               `json:"status"` // match, partial, mismatch or failed
+	Targets         []VerificationTarget `json:"targets,omitempty"`
+	CompilerVersion string               `json:"compiler_version,omitempty"` // of the local solc
+	Warnings        []string             `json:"warnings,omitempty"`
+	Error           string               `json:"error,omitempty"` // why it failed
+	VerifiedAt      time.Time            `json:"verified_at"`
+}
+
+// VerificationTarget is the outcome for one deployed address
+type VerificationTarget struct {
+	Address  string `json:"address"`
+	Status   string `json:"status"`
+	Contract string `json:"contract,omitempty"` // the compiled contract closest to it, as path:Name
+}
+
+// VerifyAuditRequest overrides what POST /audits/{id}/verification compiles with
+type VerifyAuditRequest struct {
+	Settings     json.RawMessage `json:"settings,omitempty"`      // solc standard JSON settings; defaults to compiler_settings
+	ContractName string          `json:"contract_name,omitempty"` // only compare with contracts of this name
+}
+
+// handleVerifyAudit compiles an audit's source snapshot and compares it with the deployed bytecode
+func (a *App) handleVerifyAudit(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /audits/{id}/verification
+	id := r.PathValue("id")
+	if _, ok := a.auditStatusForUpdate(w, id, address, "verify"); !ok {
+		return
+	}
+
+	// The body is optional
+	var req VerifyAuditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		httpErr(w, 400, "bad json")
+		return
+	}
+	if len(req.Settings) > 0 && req.Settings[0] != '{' {
+		httpErr(w, 400, "settings must be an object")
+		return
+	}
+
+	if a.Solc == nil {
+		httpErr(w, 503, "Bytecode verification is not configured")
+		return
+	}
+
+	v, msg, err := a.verifyAudit(r.Context(), id, req)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	writeJSON(w, 200, v)
+}
+
+// runVerification verifies an audit's bytecode in the background once it starts, when a compiler is configured
+// and the audit has a deployed contract to compare with
+func (a *App) runVerification(auditID string) {
+	if a.Solc == nil {
+		return
+	}
+	_, msg, err := a.verifyAudit(context.Background(), auditID, VerifyAuditRequest{})
+	if err != nil {
+		log.Printf("verification: audit %s: %v", auditID, err)
+	}
+	if msg != "" && msg != errNothingToVerify {
+		log.Printf("verification: audit %s: skipped: %s", auditID, msg)
+	}
+}
+
+// errNothingToVerify is the msg of verifyAudit for audits of source alone
+const errNothingToVerify = "audit has no contract_address to verify"
+
+// verifyAudit compiles an audit's Solidity snapshot with a.Solc and compares each deployed address with
+// the compiled contracts, recording the verification on the audit. A non-empty msg describes why the audit
+// can't be verified; failures to compile or reach the chain are recorded as a failed verification.
+func (a *App) verifyAudit(ctx context.Context, auditID string, req VerifyAuditRequest) (v *Verification, msg string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, verificationTimeout)
+	defer cancel()
+
+	var blockchain string
+	var contractAddr, contractName, compilerVersion, compilerSettings, proxyJSON sql.NullString
+	err = a.DB.QueryRow(`
+		SELECT blockchain, contract_address, contract_name, compiler_version, compiler_settings, proxy_topology
+		FROM audits
+		WHERE id = ?
+	`, auditID).Scan(&blockchain, &contractAddr, &contractName, &compilerVersion, &compilerSettings, &proxyJSON)
+	if err != nil {
+		return nil, "", err
+	}
+	if contractAddr.String == "" {
+		return nil, errNothingToVerify, nil
+	}
+	if strings.HasPrefix(strings.ToLower(compilerVersion.String), "vyper") {
+		return nil, "only Solidity sources can be verified", nil
+	}
+	client, err := a.Chains.Client(blockchain)
+	if client == nil && err == nil {
+		return nil, "no RPC is configured for " + blockchain, nil
+	}
+	var failure error
+	if err != nil {
+		failure = fmt.Errorf("chain RPC: %w", err)
+	}
+
+	files, err := a.solidityFiles(auditID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	settings := req.Settings
+	if len(settings) == 0 && compilerSettings.Valid {
+		settings = json.RawMessage(compilerSettings.String)
+	}
+	addresses := []string{contractAddr.String}
+	var topology proxy.Topology
+	if proxyJSON.Valid && json.Unmarshal([]byte(proxyJSON.String), &topology) == nil && len(topology.Implementations) > 0 {
+		addresses = topology.Implementations
+	}
+	units := compileUnits(files, addresses)
+	if len(units) == 0 {
+		return nil, "source snapshot has no Solidity files", nil
+	}
+
+	v = &Verification{VerifiedAt: time.Now().UTC()}
+	if failure == nil {
+		failure = a.compareBytecode(ctx, client, v, units, settings, req.ContractName, contractName.String, compilerVersion.String)
+	}
+	if failure != nil {
+		v.Status = verificationFailed
+		v.Error = failure.Error()
+	}
+
+	if err := a.recordVerification(auditID, v); err != nil {
+		return nil, "", err
+	}
+	return v, "", nil
+}
+
+// compileUnit is a set of sources solc compiles together, and the deployed addresses compared with its contracts
+type compileUnit struct {
+	Prefix     string // directory the sources were stored under, e.g. "<address>/"; stripped while compiling
+	Sources    map[string]string
+	Remappings []string
+	Addresses  []string
+}
+
+// compileUnits splits an audit's files into what is compiled together. The sources of several implementations,
+// each stored under a directory named after its address, are compiled separately with that directory stripped,
+// so their non-relative imports resolve as when they were verified. Anything else is compiled as one tree.
+func compileUnits(files map[string]string, addresses []string) []compileUnit {
+	if len(addresses) > 1 {
+		units := make([]compileUnit, 0, len(addresses))
+		kept := 0
+		for _, addr := range addresses {
+			u := subtreeUnit(files, addr+"/")
+			if len(u.Sources) == 0 {
+				break
+			}
+			u.Addresses = []string{addr}
+			units = append(units, u)
+			kept += len(u.Sources)
+		}
+		if len(units) == len(addresses) && kept == countSolidity(files) {
+			return units
+		}
+	}
+
+	u := subtreeUnit(files, "")
+	if len(u.Sources) == 0 {
+		return nil
+	}
+	u.Addresses = addresses
+	return []compileUnit{u}
+}
+
+// subtreeUnit collects the Solidity files under prefix, and the remappings of the remappings.txt at its root
+func subtreeUnit(files map[string]string, prefix string) compileUnit {
+	u := compileUnit{Prefix: prefix, Sources: map[string]string{}}
+	for p, content := range files {
+		rel, ok := strings.CutPrefix(p, prefix)
+		switch {
+		case !ok:
+		case rel == "remappings.txt":
+			u.Remappings = bytecode.ParseRemappings(content)
+		case path.Ext(rel) == ".sol":
+			u.Sources[rel] = content
+		}
+	}
+	return u
+}
+
+// countSolidity counts the Solidity files of an audit's files
+func countSolidity(files map[string]string) int {
+	n := 0
+	for p := range files {
+		if path.Ext(p) == ".sol" {
+			n++
+		}
+	}
+	return n
+}
+
+// compareBytecode compiles each unit and compares the code deployed at its addresses with the compiled
+// contracts, filling in v. It returns why the comparison couldn't be made.
+func (a *App) compareBytecode(ctx context.Context, client *ethclient.Client, v *Verification, units []compileUnit,
+	settings json.RawMessage, onlyName, verifiedName, verifiedCompiler string) error {
+	version, err := a.Solc.Version(ctx)
+	if err != nil {
+		return err
+	}
+	v.CompilerVersion = version
+	// Another compiler build changes the metadata hash at least
+	if recorded := strings.TrimPrefix(verifiedCompiler, "v"); recorded != "" && recorded != version {
+		v.Warnings = append(v.Warnings, "local solc "+version+" differs from the verified compiler "+recorded)
+	}
+
+	v.Status = bytecode.StatusMatch
+	preferred := strings.Split(verifiedName, ", ")
+	for _, u := range units {
+		contracts, err := a.Solc.Compile(ctx, u.Sources, settings, u.Remappings)
+		if err != nil {
+			return err
+		}
+		if onlyName != "" {
+			contracts = contractsNamed(contracts, onlyName)
+			if len(contracts) == 0 {
+				return errors.New("no contract named " + onlyName + " was compiled")
+			}
+		}
+		if len(contracts) == 0 {
+			return errors.New("no deployable contract was compiled")
+		}
+		for i := range contracts {
+			contracts[i].File = u.Prefix + contracts[i].File
+		}
+
+		for _, addr := range u.Addresses {
+			code, err := client.CodeAt(ctx, common.HexToAddress(addr), nil)
+			if err != nil {
+				return fmt.Errorf("chain RPC: %w", err)
+			}
+			t := closestContract(code, contracts, preferred)
+			t.Address = addr
+			v.Targets = append(v.Targets, t)
+			v.Status = bytecode.Worst(v.Status, t.Status)
+		}
+	}
+	return nil
+}
+
+// solidityFiles reads the Solidity files and remappings.txt files of an audit's source snapshot, by path
+func (a *App) solidityFiles(auditID string) (map[string]string, error) {
+	rows, err := a.DB.Query(`
+		SELECT source_files.path, source_blobs.content
+		FROM source_files JOIN source_blobs ON source_blobs.sha256 = source_files.sha256
+		WHERE source_files.audit_id = ?
+		  AND (source_files.path LIKE '%.sol' OR source_files.path = 'remappings.txt' OR source_files.path LIKE '%/remappings.txt')
+	`, auditID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := map[string]string{}
+	for rows.Next() {
+		var p string
+		var content []byte
+		if err := rows.Scan(&p, &content); err != nil {
+			return nil, err
+		}
+		files[p] = string(content)
+	}
+	return files, rows.Err()
+}
+
+// contractsNamed filters compiled contracts by name
+func contractsNamed(contracts []bytecode.Contract, name string) []bytecode.Contract {
+	var named []bytecode.Contract
+	for _, c := range contracts {
+		if c.Name == name {
+			named = append(named, c)
+		}
+	}
+	return named
+}
+
+// closestContract compares deployed code with every compiled contract and returns the best outcome.
+// Among contracts with the same outcome, the ones named like the verified contract win.
+func closestContract(code []byte, contracts []bytecode.Contract, preferred []string) VerificationTarget {
+	var best VerificationTarget
+	bestPreferred := false
+	for _, c := range contracts {
+		status := bytecode.Compare(code, c.Runtime, c.Masks)
+		isPreferred := containsString(preferred, c.Name)
+		if best.Status == "" || bytecode.Better(status, best.Status) || status == best.Status && isPreferred && !bestPreferred {
+			best = VerificationTarget{Status: status, Contract: c.File + ":" + c.Name}
+			bestPreferred = isPreferred
+		}
+	}
+	// A mismatch with everything isn't attributed to any contract
+	if best.Status == bytecode.StatusMismatch {
+		best.Contract = ""
+	}
+	return best
+}
+
+// recordVerification stores a verification on its audit and records it in the audit's event log
+func (a *App) recordVerification(auditID string, v *Verification) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE audits SET verification_status = ?, verification = ? WHERE id = ?`, v.Status, string(b), auditID)
+	if err != nil {
+		return err
+	}
+	if err := recordAuditEvent(tx, auditID, EventVerificationFinished, v); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	a.events.notify(auditID)
+	return nil
+}
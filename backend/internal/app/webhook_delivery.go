@@ -0,0 +1,332 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// webhookPollInterval is how often the worker looks for deliveries that are due
+	webhookPollInterval = 2 * time.Second
+	// webhookTimeout bounds one delivery attempt
+	webhookTimeout = 10 * time.Second
+	// webhookMaxAttempts is how many times a delivery is tried before it's marked failed
+	webhookMaxAttempts = 8
+	// webhookBackoff is the delay before the first retry; it doubles with each attempt up to webhookMaxBackoff
+	webhookBackoff    = 30 * time.Second
+	webhookMaxBackoff = time.Hour
+	// webhookBatchSize caps how many deliveries are sent concurrently
+	webhookBatchSize = 20
+)
+
+// errWebhookPrivateAddress means a webhook URL resolved to an address of the server's own network
+var errWebhookPrivateAddress = errors.New("webhook address is private, loopback or link-local")
+
+// WebhookPayload is the JSON body POSTed to webhooks
+type WebhookPayload struct {
+	ID        string       `json:"id"` // same for every webhook notified of the event
+	Event     string       `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+	Audit     webhookAudit `json:"audit"`
+	Data      interface{}  `json:"data"`
+}
+
+type webhookAudit struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// webhookEventName maps an audit event to the webhook event it triggers, if any.
+// severity is set for finding events so subscriptions can filter on it.
+func webhookEventName(typ string, data interface{}) (name, severity string) {
+	switch d := data.(type) {
+	case map[string]string:
+		if typ != EventStatusChanged {
+			return "", ""
+		}
+		if d["to"] == "in_progress" {
+			return "audit.started", ""
+		}
+		if name := "audit." + d["to"]; webhookEvents[name] {
+			return name, ""
+		}
+	case findingEvent:
+		if typ == EventFindingCreated {
+			return "finding.created", d.Severity
+		}
+	}
+	return "", ""
+}
+
+// enqueueWebhooks queues a delivery of an audit event to each of the audit owner's active webhooks subscribed to it.
+// It runs in the transaction recording the event; the worker sends the deliveries.
+func enqueueWebhooks(q dbtx, auditID, typ string, data interface{}) error {
+	name, severity := webhookEventName(typ, data)
+	if name == "" {
+		return nil
+	}
+
+	var owner string
+	payload := WebhookPayload{ID: uuid.NewString(), Event: name, CreatedAt: time.Now().UTC(), Data: data}
+	err := q.QueryRow(`SELECT owner_address, id, name FROM audits WHERE id = ?`, auditID).
+		Scan(&owner, &payload.Audit.ID, &payload.Audit.Name)
+	if err != nil {
+		return err
+	}
+
+	rows, err := q.Query(`
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE owner_address = ? AND active = 1
+	`, owner)
+	if err != nil {
+		return err
+	}
+	var targets []string
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if containsString(wh.Events, name) && (severity == "" || len(wh.Severities) == 0 || containsString(wh.Severities, severity)) {
+			targets = append(targets, wh.ID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for _, webhookID := range targets {
+		d := WebhookDelivery{
+			ID:            uuid.NewString(),
+			WebhookID:     webhookID,
+			Event:         name,
+			Payload:       body,
+			Status:        "pending",
+			NextAttemptAt: &payload.CreatedAt,
+			CreatedAt:     payload.CreatedAt,
+		}
+		if err := insertDelivery(q, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertDelivery(q dbtx, d WebhookDelivery) error {
+	_, err := q.Exec(`
+		INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, d.ID, d.WebhookID, d.Event, string(d.Payload), d.Status, d.NextAttemptAt, d.CreatedAt)
+	return err
+}
+
+// RunWebhookWorker sends due webhook deliveries until ctx is done. Failed attempts are retried
+// with exponential backoff; after webhookMaxAttempts the delivery is marked failed.
+func (a *App) RunWebhookWorker(ctx context.Context) {
+	client := webhookClient(a.WebhookAllowPrivate)
+
+	t := time.NewTicker(webhookPollInterval)
+	defer t.Stop()
+	for {
+		if err := a.sendDueWebhooks(ctx, client); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// webhookClient returns the client deliveries are sent with. Unless allowPrivate is set, it refuses to connect to
+// private, loopback and link-local addresses. The check runs on the address actually dialed, after DNS resolution,
+// so a hostname that resolves to an internal address, or is rebound to one after validation, is refused too.
+func webhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || !publicAddr(ip) {
+				return errWebhookPrivateAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		// A proxy would be dialed instead of the webhook's host, bypassing the address check
+		Transport: &http.Transport{Proxy: nil, DialContext: dialer.DialContext, TLSHandshakeTimeout: webhookTimeout},
+		// A redirect would resend the signed payload somewhere else
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// publicAddr reports whether ip is routable on the internet, i.e. not private, loopback, link-local,
+// multicast or unspecified
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// dueDelivery is a pending delivery with what's needed to send it
+type dueDelivery struct {
+	id, event, payload, url, secret string
+	attempts                        int
+}
+
+func (a *App) sendDueWebhooks(ctx context.Context, client *http.Client) error {
+	rows, err := a.DB.Query(`
+		SELECT webhook_deliveries.id, webhook_deliveries.event, webhook_deliveries.payload, webhook_deliveries.attempts,
+		       webhooks.url, webhooks.secret
+		FROM webhook_deliveries
+		JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+		WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= ?
+		ORDER BY webhook_deliveries.next_attempt_at ASC
+		LIMIT ?
+	`, time.Now().UTC(), webhookBatchSize)
+	if err != nil {
+		return err
+	}
+	var due []dueDelivery
+	for rows.Next() {
+		var d dueDelivery
+		if err := rows.Scan(&d.id, &d.event, &d.payload, &d.attempts, &d.url, &d.secret); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func(d dueDelivery) {
+			defer wg.Done()
+			if err := a.attemptDelivery(ctx, client, d); err != nil {
+				log.Printf("webhooks: delivery %s: %v", d.id, err)
+			}
+		}(d)
+	}
+	wg.Wait()
+	return nil
+}
+
+// attemptDelivery sends a delivery once and records the outcome
+func (a *App) attemptDelivery(ctx context.Context, client *http.Client, d dueDelivery) error {
+	now := time.Now().UTC()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	var code int
+	var statusLine, errMsg string
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, bytes.NewReader([]byte(d.payload)))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Watson-Webhooks/1")
+		req.Header.Set("X-Watson-Event", d.event)
+		req.Header.Set("X-Watson-Delivery", d.id)
+		req.Header.Set("X-Watson-Timestamp", timestamp)
+		req.Header.Set("X-Watson-Signature", "sha256="+webhookSignature(d.secret, timestamp, []byte(d.payload)))
+
+		var resp *http.Response
+		resp, err = client.Do(req)
+		if err == nil {
+			// Only the status line is kept: echoing the body would expose whatever the URL points at
+			resp.Body.Close()
+			code, statusLine = resp.StatusCode, resp.Status
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// Shutting down; the attempt is retried after restart
+			return nil
+		}
+		errMsg = err.Error()
+	}
+
+	attempts := d.attempts + 1
+	status := "pending"
+	var deliveredAt, nextAttemptAt interface{}
+	switch {
+	case err == nil && code >= 200 && code < 300:
+		status = "succeeded"
+		deliveredAt = now
+	case attempts >= webhookMaxAttempts:
+		status = "failed"
+	default:
+		nextAttemptAt = now.Add(webhookRetryDelay(attempts))
+	}
+
+	var responseCode interface{}
+	if err == nil {
+		responseCode = code
+	}
+	_, err = a.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, response_code = ?, response_status = ?, error = ?, delivered_at = ?
+		WHERE id = ?
+	`, status, attempts, nextAttemptAt, responseCode, nullString(statusLine), nullString(errMsg), deliveredAt, d.id)
+	return err
+}
+
+// webhookRetryDelay returns how long to wait after the given number of failed attempts
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookBackoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
+
+// webhookSignature is the hex HMAC-SHA256 of "timestamp.body" under the webhook's secret
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
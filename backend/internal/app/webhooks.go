@@ -0,0 +1,499 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// webhookEvents are the events a webhook can subscribe to
+var webhookEvents = map[string]bool{
+	"audit.started":             true,
+	"audit.completed":           true,
+	"audit.partially_completed": true,
+	"audit.failed":              true,
+	"audit.cancelled":           true,
+	"finding.created":           true,
+}
+
+// Webhook represents a user's subscription to audit lifecycle events
+type Webhook struct {
+	ID           string    `json:"id"`
+	OwnerAddress string    `json:"owner_address"`
+	URL          string    `json:"url"`
+	Events       []string  `json:"events"`
+	Severities   []string  `json:"severities"`       // finding.created is only sent for these; all when empty
+	Secret       string    `json:"secret,omitempty"` // only returned when created or rotated
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery represents one event sent, or to be sent, to a webhook
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // pending, succeeded, failed
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseCode   *int            `json:"response_code,omitempty"`
+	ResponseStatus string          `json:"response_status,omitempty"` // status line of the last response, e.g. 404 Not Found
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// CreateWebhookRequest represents the request to create or update a webhook
+type CreateWebhookRequest struct {
+	URL          string   `json:"url"`
+	Events       []string `json:"events"`
+	Severities   []string `json:"severities"`
+	Active       *bool    `json:"active"`        // defaults to true
+	RotateSecret bool     `json:"rotate_secret"` // updates only
+}
+
+// handleGetWebhooks returns all webhooks of the authenticated user
+func (a *App) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	rows, err := a.DB.Query(`
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE owner_address = ?
+		ORDER BY created_at DESC
+	`, address)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		webhooks = append(webhooks, *wh)
+	}
+
+	writeJSON(w, 200, map[string][]Webhook{"webhooks": webhooks})
+}
+
+// handleGetWebhook returns a specific webhook by ID
+func (a *App) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /webhooks/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing webhook ID")
+		return
+	}
+
+	wh, err := a.getWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && wh.OwnerAddress != address) {
+		httpErr(w, 404, "Webhook not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, wh)
+}
+
+// handleCreateWebhook creates a webhook. The signing secret is only returned in this response.
+func (a *App) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	if msg := validateWebhook(&req, a.WebhookAllowPrivate); msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	wh := Webhook{
+		ID:           uuid.NewString(),
+		OwnerAddress: address,
+		URL:          req.URL,
+		Events:       req.Events,
+		Severities:   req.Severities,
+		Secret:       randHex(32),
+		Active:       req.Active == nil || *req.Active,
+		CreatedAt:    time.Now().UTC(),
+	}
+	wh.UpdatedAt = wh.CreatedAt
+
+	eventsJSON, _ := json.Marshal(wh.Events)
+	severitiesJSON, _ := json.Marshal(wh.Severities)
+	_, err := a.DB.Exec(`
+		INSERT INTO webhooks (id, owner_address, url, secret, events, severities, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, wh.ID, wh.OwnerAddress, wh.URL, wh.Secret, string(eventsJSON), string(severitiesJSON), wh.Active, wh.CreatedAt, wh.UpdatedAt)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 201, wh)
+}
+
+// handleUpdateWebhook updates a webhook; rotate_secret replaces its signing secret and returns the new one
+func (a *App) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /webhooks/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing webhook ID")
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpErr(w, 400, "bad json")
+		return
+	}
+
+	wh, err := a.getWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Webhook not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if wh.OwnerAddress != address {
+		httpErr(w, 403, "Not authorized to modify this webhook")
+		return
+	}
+
+	if msg := validateWebhook(&req, a.WebhookAllowPrivate); msg != "" {
+		httpErr(w, 400, msg)
+		return
+	}
+
+	wh.URL = req.URL
+	wh.Events = req.Events
+	wh.Severities = req.Severities
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+	wh.UpdatedAt = time.Now().UTC()
+
+	query := `UPDATE webhooks SET url = ?, events = ?, severities = ?, active = ?, updated_at = ?`
+	eventsJSON, _ := json.Marshal(wh.Events)
+	severitiesJSON, _ := json.Marshal(wh.Severities)
+	args := []interface{}{wh.URL, string(eventsJSON), string(severitiesJSON), wh.Active, wh.UpdatedAt}
+	if req.RotateSecret {
+		wh.Secret = randHex(32)
+		query += `, secret = ?`
+		args = append(args, wh.Secret)
+	}
+	query += ` WHERE id = ?`
+	args = append(args, id)
+
+	if _, err := a.DB.Exec(query, args...); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, wh)
+}
+
+// handleDeleteWebhook deletes a webhook with its delivery log. Pending deliveries are dropped.
+func (a *App) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /webhooks/{id}
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing webhook ID")
+		return
+	}
+
+	wh, err := a.getWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Webhook not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	if wh.OwnerAddress != address {
+		httpErr(w, 403, "Not authorized to delete this webhook")
+		return
+	}
+
+	if _, err := a.DB.Exec(`DELETE FROM webhooks WHERE id = ?`, id); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 200, map[string]bool{"success": true})
+}
+
+// handleGetWebhookDeliveries returns a webhook's delivery log, newest first
+func (a *App) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract ID from path: /webhooks/{id}/deliveries
+	id := r.PathValue("id")
+	if id == "" {
+		httpErr(w, 400, "missing webhook ID")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "pending" && status != "succeeded" && status != "failed" {
+		httpErr(w, 400, "status must be one of: pending, succeeded, failed")
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	wh, err := a.getWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && wh.OwnerAddress != address) {
+		httpErr(w, 404, "Webhook not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = ?`
+	args := []interface{}{id}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC, rowid DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := a.DB.Query(query, args...)
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			httpErr(w, 500, "scan")
+			return
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	writeJSON(w, 200, map[string]interface{}{
+		"deliveries": deliveries,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// handleRedeliverWebhook queues a delivery's payload to be sent again as a new delivery
+func (a *App) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	address, ok := getAuthAddress(r)
+	if !ok {
+		httpErr(w, 401, "Not authenticated")
+		return
+	}
+
+	// Extract IDs from path: /webhooks/{id}/deliveries/{deliveryId}/redeliver
+	id := r.PathValue("id")
+	deliveryID := r.PathValue("deliveryId")
+	if id == "" || deliveryID == "" {
+		httpErr(w, 400, "missing webhook or delivery ID")
+		return
+	}
+
+	wh, err := a.getWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Webhook not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+	if wh.OwnerAddress != address {
+		httpErr(w, 403, "Not authorized to modify this webhook")
+		return
+	}
+
+	orig, err := scanDelivery(a.DB.QueryRow(`
+		SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ? AND webhook_id = ?
+	`, deliveryID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		httpErr(w, 404, "Delivery not found")
+		return
+	}
+	if err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	now := time.Now().UTC()
+	d := WebhookDelivery{
+		ID:            uuid.NewString(),
+		WebhookID:     id,
+		Event:         orig.Event,
+		Payload:       orig.Payload,
+		Status:        "pending",
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+	if err := insertDelivery(a.DB, d); err != nil {
+		httpErr(w, 500, "db")
+		return
+	}
+
+	writeJSON(w, 202, d)
+}
+
+// validateWebhook normalizes req and returns a message describing the first invalid field. Unless allowPrivate
+// is set, URLs of local hosts and private addresses are rejected; hostnames are checked again as they're dialed.
+func validateWebhook(req *CreateWebhookRequest, allowPrivate bool) string {
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(req.URL) > 2048 {
+		return "url must be an http(s) URL"
+	}
+	if !allowPrivate {
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		ip, err := netip.ParseAddr(host)
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") || err == nil && !publicAddr(ip) {
+			return "url must not point at a private, loopback or link-local address"
+		}
+	}
+
+	req.Events = uniqueStrings(req.Events)
+	if len(req.Events) == 0 {
+		return "events is required"
+	}
+	for _, e := range req.Events {
+		if !webhookEvents[e] {
+			return "unknown event " + e + "; events are audit.started, audit.completed, audit.partially_completed, audit.failed, audit.cancelled, finding.created"
+		}
+	}
+
+	req.Severities = uniqueStrings(req.Severities)
+	for _, s := range req.Severities {
+		if !validSeverities[s] {
+			return "severities must be among: critical, high, medium, low, info"
+		}
+	}
+	return ""
+}
+
+// getWebhook loads a webhook by ID, without its secret
+func (a *App) getWebhook(id string) (*Webhook, error) {
+	return scanWebhook(a.DB.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+}
+
+// webhookColumns lists the columns read by scanWebhook, in order
+const webhookColumns = `id, owner_address, url, events, severities, active, created_at, updated_at`
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	var wh Webhook
+	var eventsJSON, severitiesJSON string
+	err := row.Scan(&wh.ID, &wh.OwnerAddress, &wh.URL, &eventsJSON, &severitiesJSON, &wh.Active, &wh.CreatedAt, &wh.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(eventsJSON), &wh.Events); err != nil {
+		wh.Events = []string{}
+	}
+	if err := json.Unmarshal([]byte(severitiesJSON), &wh.Severities); err != nil || wh.Severities == nil {
+		wh.Severities = []string{}
+	}
+	return &wh, nil
+}
+
+// deliveryColumns lists the columns read by scanDelivery, in order
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, response_code, response_status,
+	error, created_at, delivered_at`
+
+func scanDelivery(row rowScanner) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	var payload string
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var responseCode sql.NullInt64
+	var responseStatus, errMsg sql.NullString
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &nextAttemptAt, &responseCode,
+		&responseStatus, &errMsg, &d.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if responseCode.Valid {
+		code := int(responseCode.Int64)
+		d.ResponseCode = &code
+	}
+	d.ResponseStatus = responseStatus.String
+	d.Error = errMsg.String
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
@@ -0,0 +1,306 @@
+package attest
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// easABIJSON is the part of the EAS contract interface used to attest and look up report hashes
+const easABIJSON = `[
+{"type":"function","name":"attest","stateMutability":"payable","inputs":[{"name":"request","type":"tuple","components":[
+  {"name":"schema","type":"bytes32"},
+  {"name":"data","type":"tuple","components":[
+    {"name":"recipient","type":"address"},{"name":"expirationTime","type":"uint64"},{"name":"revocable","type":"bool"},
+    {"name":"refUID","type":"bytes32"},{"name":"data","type":"bytes"},{"name":"value","type":"uint256"}]}]}],
+ "outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"getAttestation","stateMutability":"view","inputs":[{"name":"uid","type":"bytes32"}],
+ "outputs":[{"name":"","type":"tuple","components":[
+  {"name":"uid","type":"bytes32"},{"name":"schema","type":"bytes32"},{"name":"time","type":"uint64"},
+  {"name":"expirationTime","type":"uint64"},{"name":"revocationTime","type":"uint64"},{"name":"refUID","type":"bytes32"},
+  {"name":"recipient","type":"address"},{"name":"attester","type":"address"},{"name":"revocable","type":"bool"},
+  {"name":"data","type":"bytes"}]}]},
+{"type":"event","name":"Attested","inputs":[
+  {"name":"recipient","type":"address","indexed":true},{"name":"attester","type":"address","indexed":true},
+  {"name":"uid","type":"bytes32","indexed":false},{"name":"schemaUID","type":"bytes32","indexed":true}]}
+]`
+
+// reportSchema is the EAS schema report hashes are attested under
+const reportSchema = "bytes32 reportHash"
+
+// receiptPollInterval is how often Attest checks whether its transaction was mined
+const receiptPollInterval = time.Second
+
+// EAS attests report hashes through an EAS-compatible contract. The schema must
+// encode a single bytes32 field holding the report's SHA-256 hash.
+type EAS struct {
+	client   *ethclient.Client
+	contract common.Address
+	schema   common.Hash
+	signer   *Signer
+	abi      abi.ABI
+}
+
+// Attestation is what the contract holds for an attestation
+type Attestation struct {
+	UID            [32]byte
+	Schema         [32]byte
+	Time           uint64
+	ExpirationTime uint64
+	RevocationTime uint64
+	RefUID         [32]byte
+	Recipient      common.Address
+	Attester       common.Address
+	Revocable      bool
+	Data           []byte
+}
+
+// Check is the outcome of verifying an attestation against a report hash
+type Check struct {
+	Valid    bool   `json:"valid"`
+	Attester string `json:"attester,omitempty"`
+	Time     uint64 `json:"time,omitempty"`
+	Revoked  bool   `json:"revoked"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type attestationRequest struct {
+	Schema [32]byte
+	Data   attestationRequestData
+}
+
+type attestationRequestData struct {
+	Recipient      common.Address
+	ExpirationTime uint64
+	Revocable      bool
+	RefUID         [32]byte
+	Data           []byte
+	Value          *big.Int
+}
+
+// NewEAS returns an attester for the contract at contract, sending transactions from signer's address
+func NewEAS(client *ethclient.Client, contract, schema string, signer *Signer) (*EAS, error) {
+	if !common.IsHexAddress(contract) {
+		return nil, errors.New("bad EAS contract address")
+	}
+	s := common.FromHex(schema)
+	if len(s) != 32 {
+		return nil, errors.New("EAS schema UID must be 32 bytes")
+	}
+	parsed, err := abi.JSON(strings.NewReader(easABIJSON))
+	if err != nil {
+		return nil, err
+	}
+	return &EAS{
+		client:   client,
+		contract: common.HexToAddress(contract),
+		schema:   common.BytesToHash(s),
+		signer:   signer,
+		abi:      parsed,
+	}, nil
+}
+
+// Contract returns the address of the EAS contract
+func (e *EAS) Contract() common.Address {
+	return e.contract
+}
+
+// Schema returns the UID of the schema report hashes are attested under
+func (e *EAS) Schema() common.Hash {
+	return e.schema
+}
+
+// ChainID returns the chain the EAS contract lives on
+func (e *EAS) ChainID(ctx context.Context) (int64, error) {
+	id, err := e.client.ChainID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return id.Int64(), nil
+}
+
+// Attest records hash on chain and waits for the transaction to be mined.
+// It returns the attestation UID and the transaction hash.
+func (e *EAS) Attest(ctx context.Context, hash [32]byte) (uid, txHash common.Hash, err error) {
+	data, err := hashArguments().Pack(hash)
+	if err != nil {
+		return uid, txHash, err
+	}
+	input, err := e.abi.Pack("attest", attestationRequest{
+		Schema: e.schema,
+		Data:   attestationRequestData{Revocable: true, Data: data, Value: new(big.Int)},
+	})
+	if err != nil {
+		return uid, txHash, err
+	}
+
+	receipt, err := e.transact(ctx, e.contract, input)
+	if receipt != nil {
+		txHash = receipt.TxHash
+	}
+	if err != nil {
+		return uid, txHash, err
+	}
+
+	event := e.abi.Events["Attested"]
+	for _, l := range receipt.Logs {
+		if l.Address != e.contract || len(l.Topics) == 0 || l.Topics[0] != event.ID {
+			continue
+		}
+		out, err := event.Inputs.NonIndexed().Unpack(l.Data)
+		if err != nil || len(out) != 1 {
+			continue
+		}
+		if b, ok := out[0].([32]byte); ok {
+			return common.Hash(b), txHash, nil
+		}
+	}
+	return uid, txHash, errors.New("attestation transaction has no Attested event")
+}
+
+// Verify checks that uid attests hash under the configured schema, was made by attester and isn't revoked or expired
+func (e *EAS) Verify(ctx context.Context, uid common.Hash, hash [32]byte, attester common.Address) (*Check, error) {
+	input, err := e.abi.Pack("getAttestation", uid)
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.client.CallContract(ctx, ethereum.CallMsg{To: &e.contract, Data: input}, nil)
+	if err != nil {
+		return nil, err
+	}
+	out, err := e.abi.Unpack("getAttestation", res)
+	if err != nil || len(out) != 1 {
+		return nil, errors.New("bad getAttestation response")
+	}
+	att := *abi.ConvertType(out[0], new(Attestation)).(*Attestation)
+
+	check := &Check{Attester: att.Attester.Hex(), Time: att.Time, Revoked: att.RevocationTime != 0}
+	switch {
+	case common.Hash(att.UID) != uid:
+		check.Reason = "attestation not found"
+	case common.Hash(att.Schema) != e.schema:
+		check.Reason = "attestation uses a different schema"
+	case att.Attester != attester:
+		check.Reason = "attestation was not made by the report signer"
+	case check.Revoked:
+		check.Reason = "attestation was revoked"
+	case att.ExpirationTime != 0 && att.ExpirationTime < uint64(time.Now().Unix()):
+		check.Reason = "attestation expired"
+	default:
+		vals, err := hashArguments().Unpack(att.Data)
+		if err != nil || len(vals) != 1 || vals[0].([32]byte) != hash {
+			check.Reason = "attestation is for a different report hash"
+		} else {
+			check.Valid = true
+		}
+	}
+	return check, nil
+}
+
+// transact sends a transaction calling to with input from the signer's address and waits for it to be
+// mined. A receipt is returned with the error when the transaction was mined but reverted.
+func (e *EAS) transact(ctx context.Context, to common.Address, input []byte) (*types.Receipt, error) {
+	from := e.signer.Address()
+	chainID, err := e.client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := e.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	gas, err := e.client.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &to, Data: input})
+	if err != nil {
+		return nil, err
+	}
+	gas += gas / 5 // headroom over the estimate
+	head, err := e.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data types.TxData
+	if head.BaseFee == nil {
+		// Chains without EIP-1559 have no base fee and only take legacy transactions
+		price, err := e.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data = &types.LegacyTx{Nonce: nonce, GasPrice: price, Gas: gas, To: &to, Data: input}
+	} else {
+		tip, err := e.client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data = &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: tip,
+			GasFeeCap: new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))),
+			Gas:       gas,
+			To:        &to,
+			Data:      input,
+		}
+	}
+
+	tx, err := types.SignTx(types.NewTx(data), types.LatestSignerForChainID(chainID), e.signer.key)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.client.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	receipt, err := e.waitMined(ctx, tx.Hash())
+	if err != nil {
+		return &types.Receipt{TxHash: tx.Hash()}, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return receipt, errors.New("transaction reverted")
+	}
+	return receipt, nil
+}
+
+func (e *EAS) waitMined(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	t := time.NewTicker(receiptPollInterval)
+	defer t.Stop()
+	for {
+		receipt, err := e.client.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// hashArguments is the attestation data layout: abi.encode(bytes32 reportHash)
+func hashArguments() abi.Arguments {
+	t, _ := abi.NewType("bytes32", "", nil)
+	return abi.Arguments{{Name: "reportHash", Type: t}}
+}
+
+// schemaUID returns the UID EAS assigns to the report hash schema registered with resolver and revocable,
+// i.e. keccak256(abi.encodePacked("bytes32 reportHash", resolver, revocable))
+func schemaUID(resolver common.Address, revocable bool) common.Hash {
+	r := byte(0)
+	if revocable {
+		r = 1
+	}
+	return crypto.Keccak256Hash([]byte(reportSchema), resolver.Bytes(), []byte{r})
+}
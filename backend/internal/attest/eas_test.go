@@ -0,0 +1,283 @@
+package attest
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// anvilKey is the private key of anvil's first dev account
+const anvilKey = "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+// schemaRegistryABIJSON is the part of the EAS SchemaRegistry interface used to register the report schema
+const schemaRegistryABIJSON = `[
+{"type":"function","name":"register","stateMutability":"nonpayable","inputs":[
+  {"name":"schema","type":"string"},{"name":"resolver","type":"address"},{"name":"revocable","type":"bool"}],
+ "outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"getSchema","stateMutability":"view","inputs":[{"name":"uid","type":"bytes32"}],
+ "outputs":[{"name":"","type":"tuple","components":[
+  {"name":"uid","type":"bytes32"},{"name":"resolver","type":"address"},{"name":"revocable","type":"bool"},
+  {"name":"schema","type":"string"}]}]}
+]`
+
+// schemaRecord is what the SchemaRegistry holds for a schema
+type schemaRecord struct {
+	UID       [32]byte
+	Resolver  common.Address
+	Revocable bool
+	Schema    string
+}
+
+// TestAttestLegacyChain attests on a node whose blocks have no base fee, which only takes legacy transactions
+func TestAttestLegacyChain(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000ea")
+	schema := schemaUID(common.Address{}, true)
+	wantUID := common.HexToHash("0x1234")
+	gasPrice := big.NewInt(1_000_000_000)
+
+	parsed, err := abi.JSON(strings.NewReader(easABIJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent *types.Transaction
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+			return
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "eth_chainId":
+			result = "0x7a69"
+		case "eth_getTransactionCount":
+			result = "0x0"
+		case "eth_estimateGas":
+			result = "0x186a0"
+		case "eth_gasPrice":
+			result = hexutil.EncodeBig(gasPrice)
+		case "eth_getBlockByNumber":
+			result = map[string]interface{}{
+				"parentHash":       common.Hash{},
+				"sha3Uncles":       types.EmptyUncleHash,
+				"miner":            common.Address{},
+				"stateRoot":        common.Hash{},
+				"transactionsRoot": types.EmptyTxsHash,
+				"receiptsRoot":     types.EmptyReceiptsHash,
+				"logsBloom":        types.Bloom{},
+				"difficulty":       "0x1",
+				"number":           "0x1",
+				"gasLimit":         "0x1c9c380",
+				"gasUsed":          "0x0",
+				"timestamp":        "0x1",
+				"extraData":        "0x",
+				"mixHash":          common.Hash{},
+				"nonce":            "0x0000000000000000",
+			}
+		case "eth_sendRawTransaction":
+			var raw hexutil.Bytes
+			if err := json.Unmarshal(req.Params[0], &raw); err != nil {
+				t.Errorf("bad raw transaction: %v", err)
+				return
+			}
+			sent = new(types.Transaction)
+			if err := sent.UnmarshalBinary(raw); err != nil {
+				t.Errorf("bad raw transaction: %v", err)
+				return
+			}
+			result = sent.Hash()
+		case "eth_getTransactionReceipt":
+			event := parsed.Events["Attested"]
+			result = map[string]interface{}{
+				"transactionHash":   sent.Hash(),
+				"cumulativeGasUsed": "0x186a0",
+				"gasUsed":           "0x186a0",
+				"logsBloom":         types.Bloom{},
+				"status":            "0x1",
+				"logs": []map[string]interface{}{{
+					"address":         contract,
+					"topics":          []common.Hash{event.ID, {}, {}, schema},
+					"data":            hexutil.Bytes(wantUID.Bytes()),
+					"transactionHash": sent.Hash(),
+				}},
+			}
+		default:
+			writeRPC(w, req.ID, nil, "method not supported: "+req.Method)
+			return
+		}
+		writeRPC(w, req.ID, result, "")
+	}))
+	defer srv.Close()
+
+	client, err := ethclient.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewSigner(anvilKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eas, err := NewEAS(client, contract.Hex(), schema.Hex(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, txHash, err := eas.Attest(context.Background(), testHash("report"))
+	if err != nil {
+		t.Fatalf("Attest: %v", err)
+	}
+	if uid != wantUID {
+		t.Errorf("uid = %s, want %s", uid, wantUID)
+	}
+	if txHash != sent.Hash() {
+		t.Errorf("tx hash = %s, want %s", txHash, sent.Hash())
+	}
+	if sent.Type() != types.LegacyTxType {
+		t.Errorf("tx type = %d, want legacy", sent.Type())
+	}
+	if sent.GasPrice().Cmp(gasPrice) != 0 {
+		t.Errorf("gas price = %s, want %s", sent.GasPrice(), gasPrice)
+	}
+	if sent.ChainId().Int64() != 31337 {
+		t.Errorf("chain ID = %s, want 31337", sent.ChainId())
+	}
+}
+
+// TestAttestOnAnvil attests and verifies a report hash on a local chain with EAS deployed, as QUICKSTART.md
+// describes. It runs when EAS_TEST_RPC_URL, EAS_TEST_ADDRESS and EAS_TEST_REGISTRY are set, e.g.
+//
+//	EAS_TEST_RPC_URL=http://127.0.0.1:8545 EAS_TEST_ADDRESS=<EAS> EAS_TEST_REGISTRY=<SchemaRegistry> \
+//		go test ./internal/attest -run Anvil
+//
+// Transactions are sent from anvil's first account unless EAS_TEST_KEY is set.
+func TestAttestOnAnvil(t *testing.T) {
+	rpcURL, contract, registry := os.Getenv("EAS_TEST_RPC_URL"), os.Getenv("EAS_TEST_ADDRESS"), os.Getenv("EAS_TEST_REGISTRY")
+	if rpcURL == "" || contract == "" || registry == "" {
+		t.Skip("EAS_TEST_RPC_URL, EAS_TEST_ADDRESS and EAS_TEST_REGISTRY are not set")
+	}
+	key := os.Getenv("EAS_TEST_KEY")
+	if key == "" {
+		key = anvilKey
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewSigner(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := schemaUID(common.Address{}, true)
+	eas, err := NewEAS(client, contract, schema.Hex(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerReportSchema(ctx, t, eas, common.HexToAddress(registry))
+
+	hash := testHash("report " + time.Now().String())
+	uid, _, err := eas.Attest(ctx, hash)
+	if err != nil {
+		t.Fatalf("Attest: %v", err)
+	}
+
+	check, err := eas.Verify(ctx, uid, hash, signer.Address())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !check.Valid {
+		t.Errorf("attestation is not valid: %s", check.Reason)
+	}
+
+	check, err = eas.Verify(ctx, uid, testHash("another report"), signer.Address())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if check.Valid {
+		t.Error("attestation is valid for a different report hash")
+	}
+}
+
+// registerReportSchema registers the report hash schema with no resolver, unless it already is,
+// and checks that the registry assigned it the UID schemaUID computes
+func registerReportSchema(ctx context.Context, t *testing.T, eas *EAS, registry common.Address) {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(schemaRegistryABIJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registered := func() bool {
+		input, err := parsed.Pack("getSchema", eas.Schema())
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := eas.client.CallContract(ctx, ethereum.CallMsg{To: &registry, Data: input}, nil)
+		if err != nil {
+			t.Fatalf("getSchema: %v", err)
+		}
+		out, err := parsed.Unpack("getSchema", res)
+		if err != nil || len(out) != 1 {
+			t.Fatalf("bad getSchema response: %v", err)
+		}
+		record := *abi.ConvertType(out[0], new(schemaRecord)).(*schemaRecord)
+		return common.Hash(record.UID) == eas.Schema() && record.Schema == reportSchema
+	}
+	if registered() {
+		return
+	}
+
+	input, err := parsed.Pack("register", reportSchema, common.Address{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eas.transact(ctx, registry, input); err != nil {
+		t.Fatalf("register schema: %v", err)
+	}
+	if !registered() {
+		t.Fatal("registered schema doesn't have the UID schemaUID computes")
+	}
+}
+
+// writeRPC writes a JSON-RPC response with result, or an error when errMsg is set
+func writeRPC(w http.ResponseWriter, id json.RawMessage, result interface{}, errMsg string) {
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if errMsg != "" {
+		resp["error"] = map[string]interface{}{"code": -32601, "message": errMsg}
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// testHash returns a stand-in for a report's hash
+func testHash(s string) [32]byte {
+	var h [32]byte
+	copy(h[:], crypto.Keccak256([]byte(s)))
+	return h
+}
@@ -0,0 +1,61 @@
+// Package attest signs report hashes with a server-held secp256k1 key and
+// optionally attests them through an EAS-compatible contract.
+package attest
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"watson/internal/auth"
+)
+
+// Signer signs report hashes as EIP-191 personal messages, so any wallet tooling can verify them
+type Signer struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewSigner loads a hex encoded secp256k1 private key
+func NewSigner(hexKey string) (*Signer, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+// Address returns the address reports are signed by
+func (s *Signer) Address() common.Address {
+	return s.address
+}
+
+// Sign signs hash, returning a 65-byte signature with v = 27 or 28
+func (s *Signer) Sign(hash []byte) ([]byte, error) {
+	sig, err := crypto.Sign(auth.EIP191Hash(string(hash)), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// Recover returns the address that produced sig over hash with Sign
+func Recover(hash, sig []byte) (common.Address, error) {
+	if len(sig) != 65 {
+		return common.Address{}, errors.New("signature must be 65 bytes")
+	}
+	s := make([]byte, 65)
+	copy(s, sig)
+	if s[64] >= 27 {
+		s[64] -= 27
+	}
+	pub, err := crypto.SigToPub(auth.EIP191Hash(string(hash)), s)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
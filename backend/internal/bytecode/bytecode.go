@@ -0,0 +1,83 @@
+// Package bytecode reads deployed EVM bytecode: it checks that it is what a source snapshot compiles to,
+// and decodes what it reveals on its own, its compiler metadata and the functions it dispatches to.
+// Runtime bytecode is compared without the parts that legitimately differ between the two:
+// the metadata hash solc appends, immutables filled in at deployment and linked library addresses.
+package bytecode
+
+import "bytes"
+
+// Outcomes of comparing deployed bytecode with compiled bytecode
+const (
+	StatusMatch    = "match"    // identical apart from immutables and library addresses
+	StatusPartial  = "partial"  // identical once the metadata hash is ignored as well
+	StatusMismatch = "mismatch" // the executable code differs
+)
+
+// statusRank orders outcomes from worst to best
+var statusRank = map[string]int{StatusMismatch: 0, StatusPartial: 1, StatusMatch: 2}
+
+// Range is a byte range of runtime bytecode
+type Range struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// MetadataLength returns the length of the CBOR metadata solc appends to runtime bytecode,
+// including the 2-byte big-endian length that ends it, or 0 when code has none
+func MetadataLength(code []byte) int {
+	if len(code) < 2 {
+		return 0
+	}
+	n := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	if n == 0 || n+2 > len(code) {
+		return 0
+	}
+	// The metadata is a CBOR map of a few entries (ipfs or bzzr, solc, experimental)
+	if h := code[len(code)-2-n]; h < 0xa1 || h > 0xa7 {
+		return 0
+	}
+	return n + 2
+}
+
+// StripMetadata returns code without its trailing CBOR metadata
+func StripMetadata(code []byte) []byte {
+	return code[:len(code)-MetadataLength(code)]
+}
+
+// Compare compares deployed runtime bytecode with compiled runtime bytecode. Ranges of
+// compiled in masks, such as immutables and library placeholders, are ignored.
+func Compare(deployed, compiled []byte, masks []Range) string {
+	d, c := mask(deployed, masks), mask(compiled, masks)
+	if bytes.Equal(d, c) {
+		return StatusMatch
+	}
+	// The metadata hash changes with comments, file paths and compiler build
+	if len(deployed) > 0 && bytes.Equal(StripMetadata(d), StripMetadata(c)) {
+		return StatusPartial
+	}
+	return StatusMismatch
+}
+
+// Worst returns the worse of two outcomes
+func Worst(a, b string) string {
+	if statusRank[b] < statusRank[a] {
+		return b
+	}
+	return a
+}
+
+// Better reports whether outcome a is better than b
+func Better(a, b string) bool {
+	return statusRank[a] > statusRank[b]
+}
+
+// mask returns a copy of code with the ranges zeroed
+func mask(code []byte, ranges []Range) []byte {
+	out := append([]byte(nil), code...)
+	for _, r := range ranges {
+		for i := r.Start; i < r.Start+r.Length && i < len(out); i++ {
+			out[i] = 0
+		}
+	}
+	return out
+}
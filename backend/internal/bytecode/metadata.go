@@ -0,0 +1,164 @@
+package bytecode
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrNoMetadata is returned for code without a CBOR metadata trailer, e.g. hand-written or stripped code
+var ErrNoMetadata = errors.New("bytecode has no metadata")
+
+// Metadata is what the CBOR trailer of solc (and vyper 0.3) runtime bytecode records
+type Metadata struct {
+	Compiler     string `json:"compiler,omitempty"` // e.g. 0.8.19, or vyper:0.3.10
+	IPFS         string `json:"ipfs,omitempty"`     // CIDv0 of the metadata JSON naming the sources and settings
+	Swarm        string `json:"swarm,omitempty"`    // bzzr0/bzzr1 hash of the metadata JSON
+	Experimental bool   `json:"experimental,omitempty"`
+}
+
+// DecodeMetadata decodes the CBOR metadata trailer of runtime bytecode
+func DecodeMetadata(code []byte) (*Metadata, error) {
+	n := MetadataLength(code)
+	if n == 0 {
+		return nil, ErrNoMetadata
+	}
+	d := &cborDecoder{b: code[len(code)-n : len(code)-2]}
+
+	entries := int(d.next() - 0xa0)
+	m := &Metadata{}
+	for i := 0; i < entries && d.err == nil; i++ {
+		key, _ := d.value().(string)
+		switch v := d.value().(type) {
+		case []byte:
+			switch {
+			case key == "ipfs":
+				m.IPFS = base58(v)
+			case strings.HasPrefix(key, "bzzr"):
+				m.Swarm = hex.EncodeToString(v)
+			case key == "solc" && len(v) == 3:
+				m.Compiler = fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+			}
+		case string: // pre-release solc builds record their full version
+			if key == "solc" {
+				m.Compiler = v
+			}
+		case []uint64:
+			if key == "vyper" && len(v) == 3 {
+				m.Compiler = fmt.Sprintf("vyper:%d.%d.%d", v[0], v[1], v[2])
+			}
+		case bool:
+			if key == "experimental" {
+				m.Experimental = v
+			}
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("bytecode: metadata: %w", d.err)
+	}
+	return m, nil
+}
+
+// cborDecoder reads the subset of CBOR metadata uses: short strings, small unsigned integers,
+// arrays of them and booleans
+type cborDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *cborDecoder) next() byte {
+	if len(d.b) == 0 {
+		d.fail()
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *cborDecoder) fail() {
+	if d.err == nil {
+		d.err = errors.New("truncated or unsupported CBOR")
+	}
+}
+
+// length reads the argument of a head byte
+func (d *cborDecoder) length(head byte) int {
+	switch info := head & 0x1f; {
+	case info < 24:
+		return int(info)
+	case info == 24:
+		return int(d.next())
+	case info == 25:
+		return int(d.next())<<8 | int(d.next())
+	}
+	d.fail()
+	return 0
+}
+
+// bytes reads n bytes
+func (d *cborDecoder) bytes(n int) []byte {
+	if n > len(d.b) {
+		d.fail()
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+// value reads a data item
+func (d *cborDecoder) value() interface{} {
+	head := d.next()
+	switch head >> 5 {
+	case 0:
+		return uint64(d.length(head))
+	case 2:
+		return d.bytes(d.length(head))
+	case 3:
+		return string(d.bytes(d.length(head)))
+	case 4:
+		items := make([]uint64, d.length(head))
+		for i := range items {
+			if v, ok := d.value().(uint64); ok {
+				items[i] = v
+			}
+		}
+		return items
+	case 7:
+		switch head {
+		case 0xf4:
+			return false
+		case 0xf5:
+			return true
+		}
+	}
+	d.fail()
+	return nil
+}
+
+// base58Alphabet is the Bitcoin alphabet IPFS CIDv0 use
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58 encodes b, as IPFS multihashes are written
+func base58(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	base, mod := big.NewInt(58), new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
@@ -0,0 +1,127 @@
+package bytecode
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Pieces of runtime bytecode the tests are assembled from
+const (
+	testCode = "6080604052348015600e575f80fd5b50" // a contract's code, without metadata
+	testHash = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+)
+
+// code decodes the concatenation of hex strings
+func code(t *testing.T, parts ...string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    []string
+		want    Metadata
+		wantErr error
+	}{
+		{
+			name: "solc 0.8 with ipfs",
+			// {"ipfs": h'1220…', "solc": h'000813'}
+			code: []string{testCode, "a2", "6469706673", "5822", "1220", testHash, "64736f6c63", "43000813", "0033"},
+			want: Metadata{Compiler: "0.8.19", IPFS: "QmNLfbof5rLekrACjeuLk9JmGZD2HDBHCU4z16iYKmx5SE"},
+		},
+		{
+			name: "solc 0.5 with bzzr1",
+			code: []string{testCode, "a2", "65627a7a7231", "5820", testHash, "64736f6c63", "43000511", "0032"},
+			want: Metadata{Compiler: "0.5.17", Swarm: testHash},
+		},
+		{
+			name: "solc 0.4 with bzzr0 only",
+			code: []string{testCode, "a1", "65627a7a7230", "5820", testHash, "0029"},
+			want: Metadata{Swarm: testHash},
+		},
+		{
+			name: "experimental",
+			code: []string{testCode, "a3", "6469706673", "5822", "1220", testHash, "6c6578706572696d656e74616c", "f5", "64736f6c63", "43000606", "0041"},
+			want: Metadata{Compiler: "0.6.6", IPFS: "QmNLfbof5rLekrACjeuLk9JmGZD2HDBHCU4z16iYKmx5SE", Experimental: true},
+		},
+		{
+			name: "pre-release solc",
+			// {"solc": "0.8.20-nightly.2023.4.26"}
+			code: []string{testCode, "a1", "64736f6c63", "78", "18", hex.EncodeToString([]byte("0.8.20-nightly.2023.4.26")), "0020"},
+			want: Metadata{Compiler: "0.8.20-nightly.2023.4.26"},
+		},
+		{
+			name: "vyper 0.3",
+			// {"vyper": [0, 3, 10]}
+			code: []string{testCode, "a1", "657679706572", "83", "00", "03", "0a", "000b"},
+			want: Metadata{Compiler: "vyper:0.3.10"},
+		},
+		{
+			name:    "no metadata",
+			code:    []string{testCode},
+			wantErr: ErrNoMetadata,
+		},
+		{
+			name:    "length past the start of the code",
+			code:    []string{"a1", "0040"},
+			wantErr: ErrNoMetadata,
+		},
+		{
+			name:    "length not ending at a CBOR map",
+			code:    []string{testCode, "0004"},
+			wantErr: ErrNoMetadata,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeMetadata(code(t, tt.code...))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMetadataTruncated(t *testing.T) {
+	// {"ipfs": h'…'} announcing 34 bytes that aren't there
+	_, err := DecodeMetadata(code(t, testCode, "a1", "6469706673", "5822", "1220", "000a"))
+	if err == nil || errors.Is(err, ErrNoMetadata) {
+		t.Fatalf("err = %v, want a CBOR error", err)
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		code []string
+		want string
+	}{
+		{"with metadata", []string{testCode, "a1", "65627a7a7230", "5820", testHash, "0029"}, testCode},
+		{"without metadata", []string{testCode}, testCode},
+		{"too short", []string{"00"}, "00"},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(StripMetadata(code(t, tt.code...))); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
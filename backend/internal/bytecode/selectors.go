@@ -0,0 +1,91 @@
+package bytecode
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// EVM opcodes the dispatcher is read from
+const (
+	opEQ     = 0x14
+	opPUSH1  = 0x60
+	opPUSH4  = 0x63
+	opPUSH32 = 0x7f
+	opDUP1   = 0x80
+	opDUP16  = 0x8f
+)
+
+//go:embed signatures.tsv
+var signaturesTSV string
+
+// signatures maps 0x-prefixed selectors to the known signatures hashing to them
+var signatures = func() map[string][]string {
+	m := map[string][]string{}
+	for _, line := range strings.Split(signaturesTSV, "\n") {
+		sel, sig, ok := strings.Cut(line, "\t")
+		if ok && !strings.HasPrefix(line, "#") {
+			m["0x"+sel] = append(m["0x"+sel], strings.TrimSpace(sig))
+		}
+	}
+	return m
+}()
+
+// Function is a function selector the dispatcher of some bytecode matches
+type Function struct {
+	Selector   string   `json:"selector"`             // e.g. 0xa9059cbb
+	Signatures []string `json:"signatures,omitempty"` // known signatures with the selector, e.g. transfer(address,uint256)
+}
+
+// Inspection is what can be read from runtime bytecode without its source
+type Inspection struct {
+	Size      int        `json:"size"`
+	Metadata  *Metadata  `json:"metadata,omitempty"`
+	Functions []Function `json:"functions"`
+}
+
+// Inspect decodes the metadata of runtime bytecode and the functions its dispatcher exposes
+func Inspect(code []byte) *Inspection {
+	in := &Inspection{Size: len(code), Functions: []Function{}}
+	in.Metadata, _ = DecodeMetadata(code)
+	for _, sel := range Selectors(code) {
+		in.Functions = append(in.Functions, Function{Selector: sel, Signatures: Lookup(sel)})
+	}
+	return in
+}
+
+// Selectors returns the function selectors the dispatcher of runtime bytecode compares calldata with:
+// the PUSH4 values directly compared by EQ, possibly after a DUP. Other PUSH4s, such as error
+// selectors and masks, aren't included.
+func Selectors(code []byte) []string {
+	code = StripMetadata(code)
+	seen := map[string]bool{}
+	var selectors []string
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+		if op < opPUSH1 || op > opPUSH32 {
+			continue
+		}
+		end := pc + 1 + int(op-opPUSH1+1)
+		if op == opPUSH4 && end < len(code) {
+			next := code[end]
+			if next >= opDUP1 && next <= opDUP16 && end+1 < len(code) {
+				next = code[end+1]
+			}
+			if sel := "0x" + hex.EncodeToString(code[pc+1:end]); next == opEQ && !seen[sel] {
+				seen[sel] = true
+				selectors = append(selectors, sel)
+			}
+		}
+		// Skip the pushed bytes, which aren't opcodes
+		pc = end - 1
+	}
+	sort.Strings(selectors)
+	return selectors
+}
+
+// Lookup returns the signatures of the bundled database with the given 0x-prefixed selector
+func Lookup(selector string) []string {
+	return signatures[strings.ToLower(selector)]
+}
@@ -0,0 +1,117 @@
+package bytecode
+
+import (
+	"reflect"
+	"testing"
+)
+
+// dispatcher loads the calldata selector: PUSH1 0 CALLDATALOAD PUSH1 0xe0 SHR
+const dispatcher = "600035" + "60e01c"
+
+func TestSelectors(t *testing.T) {
+	tests := []struct {
+		name string
+		code []string
+		want []string
+	}{
+		{
+			name: "DUP1 PUSH4 EQ",
+			code: []string{dispatcher, "80", "63a9059cbb", "14", "610040", "57", "80", "6370a08231", "14", "610050", "57"},
+			want: []string{"0x70a08231", "0xa9059cbb"},
+		},
+		{
+			name: "PUSH4 DUP2 EQ",
+			code: []string{dispatcher, "63095ea7b3", "81", "14", "610040", "57"},
+			want: []string{"0x095ea7b3"},
+		},
+		{
+			name: "compared twice",
+			code: []string{dispatcher, "80", "63a9059cbb", "14", "610040", "57", "80", "63a9059cbb", "14", "610050", "57"},
+			want: []string{"0xa9059cbb"},
+		},
+		{
+			name: "masks and error selectors",
+			// PUSH4 0xffffffff AND, and an error selector stored with PUSH4 … PUSH1 0 MSTORE
+			code: []string{dispatcher, "63ffffffff", "16", "6308c379a0", "6000", "52"},
+			want: nil,
+		},
+		{
+			name: "PUSH4 EQ inside push data",
+			code: []string{"7f", "0000000000000000000000000000000000000000000000000000", "63deadbeef", "14", "50"},
+			want: nil,
+		},
+		{
+			name: "PUSH4 EQ inside metadata",
+			code: []string{dispatcher, "00", "a1", "65627a7a7230", "5820", "63deadbeef14", "0000000000000000000000000000000000000000000000000000", "0029"},
+			want: nil,
+		},
+		{
+			name: "PUSH4 at the end of the code",
+			code: []string{dispatcher, "63a9059cbb"},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Selectors(code(t, tt.code...)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"0xa9059cbb", []string{"transfer(address,uint256)"}},
+		{"0xA9059CBB", []string{"transfer(address,uint256)"}},
+		{"0x70a08231", []string{"balanceOf(address)"}},
+		{"0x00000000", nil},
+		{"a9059cbb", nil}, // selectors are 0x-prefixed
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := Lookup(tt.selector); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignaturesDatabase(t *testing.T) {
+	for sel, sigs := range signatures {
+		if len(sel) != 10 {
+			t.Errorf("selector %q isn't 4 bytes", sel)
+		}
+		for _, sig := range sigs {
+			if sig == "" {
+				t.Errorf("selector %s has an empty signature", sel)
+			}
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	in := Inspect(code(t, dispatcher, "80", "63a9059cbb", "14", "610040", "57", "80", "6312345678", "14", "610050", "57",
+		"a2", "6469706673", "5822", "1220", testHash, "64736f6c63", "43000813", "0033"))
+
+	if in.Size != 81 {
+		t.Errorf("size = %d, want 81", in.Size)
+	}
+	if in.Metadata == nil || in.Metadata.Compiler != "0.8.19" {
+		t.Errorf("metadata = %+v, want compiler 0.8.19", in.Metadata)
+	}
+	want := []Function{
+		{Selector: "0x12345678"},
+		{Selector: "0xa9059cbb", Signatures: []string{"transfer(address,uint256)"}},
+	}
+	if !reflect.DeepEqual(in.Functions, want) {
+		t.Errorf("functions = %+v, want %+v", in.Functions, want)
+	}
+
+	if in := Inspect(nil); in.Metadata != nil || len(in.Functions) != 0 || in.Functions == nil {
+		t.Errorf("Inspect(nil) = %+v, want no metadata and an empty function list", in)
+	}
+}
@@ -0,0 +1,202 @@
+package bytecode
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// solcVersionPattern finds the version in `solc --version` output, e.g. 0.8.19+commit.7dd6d404
+var solcVersionPattern = regexp.MustCompile(`\d+\.\d+\.\d+(\+commit\.[0-9a-f]+)?`)
+
+// Solc compiles Solidity through a local solc binary's standard JSON interface
+type Solc struct {
+	Path    string
+	Timeout time.Duration
+}
+
+// NewSolc returns a compiler running the solc binary at path
+func NewSolc(path string) *Solc {
+	return &Solc{Path: path, Timeout: 5 * time.Minute}
+}
+
+// Contract is the runtime bytecode solc produced for a contract
+type Contract struct {
+	File    string
+	Name    string
+	Runtime []byte
+	Masks   []Range // immutables and library placeholders, which are set at deployment
+}
+
+// compilerOutput is the part of a solc standard JSON output a comparison needs
+type compilerOutput struct {
+	Errors []struct {
+		Severity         string `json:"severity"`
+		FormattedMessage string `json:"formattedMessage"`
+		Message          string `json:"message"`
+	} `json:"errors"`
+	Contracts map[string]map[string]struct {
+		EVM struct {
+			DeployedBytecode struct {
+				Object              string                        `json:"object"`
+				ImmutableReferences map[string][]Range            `json:"immutableReferences"`
+				LinkReferences      map[string]map[string][]Range `json:"linkReferences"`
+			} `json:"deployedBytecode"`
+		} `json:"evm"`
+	} `json:"contracts"`
+}
+
+// Version returns the version of the solc binary, e.g. 0.8.19+commit.7dd6d404
+func (s *Solc) Version(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, s.Path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("solc: %w", err)
+	}
+	v := solcVersionPattern.FindString(string(out))
+	if v == "" {
+		return "", errors.New("solc: unrecognized --version output")
+	}
+	return v, nil
+}
+
+// Compile compiles Solidity sources, keyed by path, with solc standard JSON settings.
+// Remappings are added to the settings' own; the output selection is replaced by the runtime bytecode.
+// Compilation errors are returned as an error carrying solc's first message.
+func (s *Solc) Compile(ctx context.Context, sources map[string]string, settings json.RawMessage, remappings []string) ([]Contract, error) {
+	input, err := standardInput(sources, settings, remappings)
+	if err != nil {
+		return nil, err
+	}
+
+	// solc reads imports missing from the sources from disk, relative to its base path, and its errors quote the
+	// file read. It runs in an empty directory set as base path, so an import of ".env" or of a path outside it
+	// can't leak the server's files.
+	dir, err := os.MkdirTemp("", "solc-")
+	if err != nil {
+		return nil, fmt.Errorf("solc: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, s.Path, "--standard-json", "--base-path", dir)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("solc: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var out compilerOutput
+	if err := json.Unmarshal(stdout, &out); err != nil {
+		return nil, fmt.Errorf("solc: output: %w", err)
+	}
+	for _, e := range out.Errors {
+		if e.Severity == "error" {
+			msg := e.FormattedMessage
+			if msg == "" {
+				msg = e.Message
+			}
+			return nil, errors.New("solc: " + strings.TrimSpace(msg))
+		}
+	}
+
+	var contracts []Contract
+	for file, byName := range out.Contracts {
+		for name, c := range byName {
+			bc := c.EVM.DeployedBytecode
+			// Interfaces and abstract contracts have no code
+			if bc.Object == "" {
+				continue
+			}
+			obj := []byte(bc.Object)
+			contract := Contract{File: file, Name: name}
+			for _, refs := range bc.ImmutableReferences {
+				contract.Masks = append(contract.Masks, refs...)
+			}
+			// Unlinked libraries are __$<hash>$__ placeholders, which aren't hex
+			for _, libs := range bc.LinkReferences {
+				for _, refs := range libs {
+					for _, r := range refs {
+						if 2*(r.Start+r.Length) <= len(obj) {
+							copy(obj[2*r.Start:], bytes.Repeat([]byte("0"), 2*r.Length))
+						}
+						contract.Masks = append(contract.Masks, r)
+					}
+				}
+			}
+			contract.Runtime, err = hex.DecodeString(string(obj))
+			if err != nil {
+				return nil, fmt.Errorf("solc: %s:%s: bytecode: %w", file, name, err)
+			}
+			contracts = append(contracts, contract)
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		if contracts[i].File != contracts[j].File {
+			return contracts[i].File < contracts[j].File
+		}
+		return contracts[i].Name < contracts[j].Name
+	})
+	return contracts, nil
+}
+
+// standardInput builds a solc standard JSON input selecting only the runtime bytecode
+func standardInput(sources map[string]string, settings json.RawMessage, remappings []string) ([]byte, error) {
+	s := map[string]interface{}{}
+	if len(settings) > 0 {
+		if err := json.Unmarshal(settings, &s); err != nil {
+			return nil, fmt.Errorf("solc: settings: %w", err)
+		}
+	}
+	if len(remappings) > 0 {
+		var existing []string
+		if r, ok := s["remappings"].([]interface{}); ok {
+			for _, v := range r {
+				if str, ok := v.(string); ok {
+					existing = append(existing, str)
+				}
+			}
+		}
+		s["remappings"] = append(existing, remappings...)
+	}
+	s["outputSelection"] = map[string]interface{}{
+		"*": map[string]interface{}{
+			"*": []string{
+				"evm.deployedBytecode.object",
+				"evm.deployedBytecode.immutableReferences",
+				"evm.deployedBytecode.linkReferences",
+			},
+		},
+	}
+
+	in := map[string]interface{}{"language": "Solidity", "settings": s}
+	srcs := map[string]interface{}{}
+	for path, content := range sources {
+		srcs[path] = map[string]string{"content": content}
+	}
+	in["sources"] = srcs
+	return json.Marshal(in)
+}
+
+// ParseRemappings reads a remappings.txt, one prefix=target remapping per line
+func ParseRemappings(content string) []string {
+	var remappings []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") && strings.Contains(line, "=") {
+			remappings = append(remappings, line)
+		}
+	}
+	return remappings
+}
@@ -0,0 +1,149 @@
+package bytecode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the test binary as fakeSolc when it's started by a test as the solc binary
+func TestMain(m *testing.M) {
+	if os.Getenv("BYTECODE_FAKE_SOLC") == "1" {
+		fakeSolc()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeSolc answers a standard JSON input as solc does, for the parts Compile relies on: every contract gets the
+// code 0x6001, and imports missing from the sources are read from disk relative to the base path (the working
+// directory without one), refusing files outside it. Like solc's parser errors, the error for an imported file
+// quotes its first line.
+func fakeSolc() {
+	var in struct {
+		Sources map[string]struct {
+			Content string `json:"content"`
+		} `json:"sources"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&in); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	base, _ := os.Getwd()
+	for i, arg := range os.Args {
+		if arg == "--base-path" && i+1 < len(os.Args) {
+			base = os.Args[i+1]
+		}
+	}
+
+	type solcError struct {
+		Severity         string `json:"severity"`
+		FormattedMessage string `json:"formattedMessage"`
+	}
+	out := map[string]interface{}{}
+	var errs []solcError
+	contracts := map[string]map[string]interface{}{}
+	imports := regexp.MustCompile(`import "([^"]+)";`)
+	names := regexp.MustCompile(`contract (\w+)`)
+	for path, src := range in.Sources {
+		for _, m := range imports.FindAllStringSubmatch(src.Content, -1) {
+			if _, ok := in.Sources[m[1]]; ok {
+				continue
+			}
+			file := m[1]
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(base, file)
+			}
+			content, err := os.ReadFile(file)
+			if rel, relErr := filepath.Rel(base, file); relErr != nil || strings.HasPrefix(rel, "..") {
+				err = fmt.Errorf("file outside of allowed directories")
+			}
+			if err != nil {
+				errs = append(errs, solcError{"error", fmt.Sprintf("Error: Source %q not found: %v", m[1], err)})
+				continue
+			}
+			line, _, _ := strings.Cut(string(content), "\n")
+			errs = append(errs, solcError{"error", fmt.Sprintf("ParserError: Expected pragma, import directive or contract definition.\n --> %s:1:1:\n  |\n1 | %s", m[1], line)})
+		}
+		for _, m := range names.FindAllStringSubmatch(src.Content, -1) {
+			if contracts[path] == nil {
+				contracts[path] = map[string]interface{}{}
+			}
+			contracts[path][m[1]] = map[string]interface{}{
+				"evm": map[string]interface{}{"deployedBytecode": map[string]interface{}{"object": "6001"}},
+			}
+		}
+	}
+	out["errors"] = errs
+	if len(errs) == 0 {
+		out["contracts"] = contracts
+	}
+	json.NewEncoder(os.Stdout).Encode(out)
+}
+
+// testSolc returns a compiler running the solc binary at SOLC_TEST_PATH, or the test binary as fakeSolc
+func testSolc(t *testing.T) *Solc {
+	t.Helper()
+	if path := os.Getenv("SOLC_TEST_PATH"); path != "" {
+		return NewSolc(path)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BYTECODE_FAKE_SOLC", "1")
+	return NewSolc(exe)
+}
+
+func TestCompile(t *testing.T) {
+	sources := map[string]string{
+		"src/Vault.sol": "// SPDX-License-Identifier: MIT\npragma solidity >=0.4.0;\nimport \"src/Math.sol\";\ncontract Vault {}\n",
+		"src/Math.sol":  "// SPDX-License-Identifier: MIT\npragma solidity >=0.4.0;\ncontract Math {}\n",
+	}
+	contracts, err := testSolc(t).Compile(context.Background(), sources, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range contracts {
+		got = append(got, c.File+":"+c.Name)
+		if len(c.Runtime) == 0 {
+			t.Errorf("%s:%s has no runtime code", c.File, c.Name)
+		}
+	}
+	if want := []string{"src/Math.sol:Math", "src/Vault.sol:Vault"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("contracts = %q, want %q", got, want)
+	}
+}
+
+func TestCompileImportOutsideSources(t *testing.T) {
+	// A secret next to the server, which runs from its working directory
+	const secret = "API_KEY=hunter2"
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	if err := os.WriteFile(env, []byte(secret+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	solc := testSolc(t)
+	for _, path := range []string{".env", "./.env", env, "../" + filepath.Base(dir) + "/.env"} {
+		t.Run(path, func(t *testing.T) {
+			sources := map[string]string{"Contract.sol": "pragma solidity >=0.4.0;\nimport \"" + path + "\";\ncontract A {}\n"}
+			_, err := solc.Compile(context.Background(), sources, nil, nil)
+			if err == nil {
+				t.Fatal("want an error for the missing import")
+			}
+			if strings.Contains(err.Error(), "hunter2") {
+				t.Fatalf("error leaks the imported file: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+
+	"github.com/pressly/goose/v3"
+)
+
+// legacySourcePath is the file audits.source_code is moved to
+const legacySourcePath = "Contract.sol"
+
+// Moving audits.source_code into source files needs SHA-256, which SQLite doesn't have,
+// so this migration is written in Go. It runs between the SQL migrations by its version.
+func init() {
+	goose.AddNamedMigrationContext("0020_legacy_source_code.go", upLegacySourceCode, downLegacySourceCode)
+}
+
+// upLegacySourceCode moves each audit's single source_code text into a source file, then drops the column
+func upLegacySourceCode(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, source_code, created_at FROM audits
+		WHERE source_code IS NOT NULL AND source_code != ''
+		  AND NOT EXISTS (SELECT 1 FROM source_files WHERE source_files.audit_id = audits.id)
+	`)
+	if err != nil {
+		return err
+	}
+	type legacy struct {
+		id, code  string
+		createdAt interface{}
+	}
+	var audits []legacy
+	for rows.Next() {
+		var l legacy
+		if err := rows.Scan(&l.id, &l.code, &l.createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		audits = append(audits, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range audits {
+		sum := sha256.Sum256([]byte(l.code))
+		hash := hex.EncodeToString(sum[:])
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO source_blobs (sha256, content, size, created_at) VALUES (?, ?, ?, ?)
+		`, hash, []byte(l.code), len(l.code), l.createdAt)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO source_files (audit_id, path, sha256, size, created_at) VALUES (?, ?, ?, ?, ?)
+		`, l.id, legacySourcePath, hash, len(l.code), l.createdAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `ALTER TABLE audits DROP COLUMN source_code`)
+	return err
+}
+
+// downLegacySourceCode restores source_code from the files the up migration created
+func downLegacySourceCode(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE audits ADD COLUMN source_code TEXT`); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		UPDATE audits SET source_code = (
+			SELECT CAST(source_blobs.content AS TEXT)
+			FROM source_files JOIN source_blobs ON source_blobs.sha256 = source_files.sha256
+			WHERE source_files.audit_id = audits.id AND source_files.path = ?
+		)
+	`, legacySourcePath)
+	return err
+}
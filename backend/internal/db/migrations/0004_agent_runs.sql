@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS agent_runs (
+  id                TEXT PRIMARY KEY,     -- uuid
+  audit_id          TEXT NOT NULL,
+  agent_id          TEXT NOT NULL,
+  status            TEXT NOT NULL DEFAULT 'queued', -- queued, running, completed, failed
+  prompt_tokens     INTEGER NOT NULL DEFAULT 0,
+  completion_tokens INTEGER NOT NULL DEFAULT 0,
+  created_at        DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  started_at        DATETIME,
+  finished_at       DATETIME,
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE CASCADE,
+  FOREIGN KEY (agent_id) REFERENCES agents(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_runs_audit ON agent_runs(audit_id);
+CREATE INDEX IF NOT EXISTS idx_agent_runs_agent ON agent_runs(agent_id);
+CREATE INDEX IF NOT EXISTS idx_findings_agent ON findings(agent_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_findings_agent;
+DROP TABLE IF EXISTS agent_runs;
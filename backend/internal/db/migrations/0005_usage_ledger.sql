@@ -0,0 +1,29 @@
+-- +goose Up
+-- Ledger rows outlive the audit and agent they were recorded for so that spend history stays intact
+CREATE TABLE IF NOT EXISTS usage_ledger (
+  id                TEXT PRIMARY KEY,     -- uuid
+  owner_address     TEXT NOT NULL,        -- lower-case ethereum address billed for the usage
+  audit_id          TEXT,
+  run_id            TEXT,
+  agent_id          TEXT NOT NULL,
+  model             TEXT NOT NULL,
+  prompt_tokens     INTEGER NOT NULL DEFAULT 0,
+  completion_tokens INTEGER NOT NULL DEFAULT 0,
+  tool_calls        INTEGER NOT NULL DEFAULT 0,
+  cost_usd          REAL NOT NULL DEFAULT 0,
+  priced            INTEGER NOT NULL DEFAULT 1, -- 0 = model pricing was unavailable, cost_usd is 0
+  created_at        DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE SET NULL,
+  FOREIGN KEY (run_id) REFERENCES agent_runs(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_owner_created ON usage_ledger(owner_address, created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_audit ON usage_ledger(audit_id);
+
+ALTER TABLE agent_runs ADD COLUMN tool_calls INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE agent_runs ADD COLUMN cost_usd REAL NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE agent_runs DROP COLUMN cost_usd;
+ALTER TABLE agent_runs DROP COLUMN tool_calls;
+DROP TABLE IF EXISTS usage_ledger;
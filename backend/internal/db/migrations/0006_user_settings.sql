@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_settings (
+  address           TEXT PRIMARY KEY,     -- lower-case ethereum address
+  cost_ceiling_usd  REAL,                 -- starting an audit estimated above this requires confirmation
+  updated_at        DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_settings;
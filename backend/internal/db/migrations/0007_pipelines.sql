@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS pipelines (
+  id              TEXT PRIMARY KEY,     -- uuid
+  owner_address   TEXT NOT NULL,        -- lower-case ethereum address
+  name            TEXT NOT NULL,
+  description     TEXT,
+  stages          TEXT NOT NULL DEFAULT '[]', -- JSON array of stages (name, role, agents, depends_on)
+  created_at      DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  updated_at      DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pipelines_owner ON pipelines(owner_address);
+
+-- Audits keep a copy of the stages they were created with so later pipeline edits don't affect them
+ALTER TABLE audits ADD COLUMN pipeline_id TEXT;
+ALTER TABLE audits ADD COLUMN pipeline_stages TEXT;
+
+-- Index of the pipeline stage a run or finding belongs to (0 for audits without a pipeline)
+ALTER TABLE agent_runs ADD COLUMN stage INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE findings ADD COLUMN stage INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE findings DROP COLUMN stage;
+ALTER TABLE agent_runs DROP COLUMN stage;
+ALTER TABLE audits DROP COLUMN pipeline_stages;
+ALTER TABLE audits DROP COLUMN pipeline_id;
+DROP TABLE IF EXISTS pipelines;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Archived agents are hidden from listings and can't be used for new audits,
+-- but stay resolvable for the findings and runs that reference them
+ALTER TABLE agents ADD COLUMN archived_at DATETIME;
+
+-- +goose Down
+ALTER TABLE agents DROP COLUMN archived_at;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE findings ADD COLUMN status TEXT NOT NULL DEFAULT 'open';
+
+CREATE INDEX IF NOT EXISTS idx_findings_audit_status ON findings(audit_id, status);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_findings_audit_status;
+ALTER TABLE findings DROP COLUMN status;
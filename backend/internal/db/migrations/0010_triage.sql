@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE findings ADD COLUMN assignee TEXT; -- lower-case ethereum address
+
+CREATE TABLE IF NOT EXISTS finding_status_history (
+  id            TEXT PRIMARY KEY,     -- uuid
+  finding_id    TEXT NOT NULL,
+  from_status   TEXT NOT NULL,
+  to_status     TEXT NOT NULL,        -- open, confirmed, false_positive, wont_fix, fixed
+  justification TEXT,
+  actor         TEXT NOT NULL,        -- lower-case ethereum address
+  created_at    DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_finding_status_history_finding ON finding_status_history(finding_id);
+
+CREATE TABLE IF NOT EXISTS finding_comments (
+  id          TEXT PRIMARY KEY,     -- uuid
+  finding_id  TEXT NOT NULL,
+  author      TEXT NOT NULL,        -- lower-case ethereum address
+  body        TEXT NOT NULL,
+  created_at  DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  FOREIGN KEY (finding_id) REFERENCES findings(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_finding_comments_finding ON finding_comments(finding_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS finding_comments;
+DROP TABLE IF EXISTS finding_status_history;
+ALTER TABLE findings DROP COLUMN assignee;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS finding_clusters (
+  id            TEXT PRIMARY KEY,     -- uuid
+  audit_id      TEXT NOT NULL,
+  canonical_id  TEXT NOT NULL,        -- finding representing the cluster
+  created_at    DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_finding_clusters_audit ON finding_clusters(audit_id);
+
+-- Findings that duplicate others share a cluster; unique findings have no cluster
+ALTER TABLE findings ADD COLUMN cluster_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_findings_cluster ON findings(cluster_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_findings_cluster;
+ALTER TABLE findings DROP COLUMN cluster_id;
+DROP TABLE IF EXISTS finding_clusters;
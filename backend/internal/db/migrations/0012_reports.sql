@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS report_templates (
+  id             TEXT PRIMARY KEY,     -- uuid
+  owner_address  TEXT NOT NULL,
+  name           TEXT NOT NULL,
+  format         TEXT NOT NULL,        -- markdown (also used for pdf) or html
+  body           TEXT NOT NULL,
+  created_at     DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  updated_at     DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_templates_owner ON report_templates(owner_address);
+
+CREATE TABLE IF NOT EXISTS reports (
+  id             TEXT PRIMARY KEY,     -- uuid
+  audit_id       TEXT NOT NULL,
+  owner_address  TEXT NOT NULL,
+  format         TEXT NOT NULL,        -- markdown, html, pdf
+  template_id    TEXT,                 -- NULL for the built-in template
+  finding_ids    TEXT NOT NULL,        -- JSON array of the findings included
+  content        BLOB NOT NULL,
+  sha256         TEXT NOT NULL,        -- hex digest of content
+  size           INTEGER NOT NULL,
+  created_at     DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_audit ON reports(audit_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS report_templates;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- ai agents run prompts against a model; tool agents attribute findings imported from static analyzers
+ALTER TABLE agents ADD COLUMN kind TEXT NOT NULL DEFAULT 'ai';
+
+-- Insert synthetic tool agents (owned by no user)
+INSERT INTO agents (id, owner_address, name, description, model, system_prompt, kind) VALUES
+  ('fd0da4d9-a532-4d10-aa74-a191189e950c', '', 'Slither', 'Findings imported from Slither', 'slither', '', 'tool'),
+  ('e95a17b0-3605-4c65-b897-b5b1fb913a90', '', 'Aderyn', 'Findings imported from Aderyn', 'aderyn', '', 'tool'),
+  ('473f2362-8d46-464d-9418-8ac4455f60d6', '', 'Mythril', 'Findings imported from Mythril', 'mythril', '', 'tool'),
+  ('c634b5c6-973f-403f-ab99-5bad39cb3dd7', '', 'SARIF import', 'Findings imported from SARIF logs', 'sarif', '', 'tool');
+
+-- +goose Down
+DELETE FROM findings WHERE agent_id IN (SELECT id FROM agents WHERE kind = 'tool');
+DELETE FROM agents WHERE kind = 'tool';
+ALTER TABLE agents DROP COLUMN kind;
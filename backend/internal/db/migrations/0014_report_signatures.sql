@@ -0,0 +1,21 @@
+-- +goose Up
+-- A finalized report's SHA-256 digest is signed with the server's secp256k1 key
+ALTER TABLE reports ADD COLUMN signature TEXT;              -- hex, 65 bytes, EIP-191 over the raw digest
+ALTER TABLE reports ADD COLUMN signer TEXT;                 -- address that produced signature
+ALTER TABLE reports ADD COLUMN finalized_at DATETIME;
+
+-- and optionally attested through an EAS-compatible contract
+ALTER TABLE reports ADD COLUMN attestation_uid TEXT;
+ALTER TABLE reports ADD COLUMN attestation_tx TEXT;
+ALTER TABLE reports ADD COLUMN attestation_chain_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_reports_sha256 ON reports(sha256);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_reports_sha256;
+ALTER TABLE reports DROP COLUMN attestation_chain_id;
+ALTER TABLE reports DROP COLUMN attestation_tx;
+ALTER TABLE reports DROP COLUMN attestation_uid;
+ALTER TABLE reports DROP COLUMN finalized_at;
+ALTER TABLE reports DROP COLUMN signer;
+ALTER TABLE reports DROP COLUMN signature;
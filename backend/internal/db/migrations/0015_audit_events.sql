@@ -0,0 +1,15 @@
+-- +goose Up
+-- Persisted progress events of an audit, streamed over SSE. id is the SSE event ID clients resume from.
+CREATE TABLE IF NOT EXISTS audit_events (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  audit_id    TEXT NOT NULL,
+  type        TEXT NOT NULL,        -- agent.started, agent.finished, tool.call, finding.created, status.changed, cost.updated
+  data        TEXT NOT NULL,        -- JSON payload
+  created_at  DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_audit ON audit_events(audit_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_events;
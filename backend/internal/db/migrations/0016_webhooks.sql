@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS webhooks (
+  id             TEXT PRIMARY KEY,     -- uuid
+  owner_address  TEXT NOT NULL,
+  url            TEXT NOT NULL,
+  secret         TEXT NOT NULL,        -- HMAC-SHA256 key for the X-Watson-Signature header
+  events         TEXT NOT NULL,        -- JSON array of subscribed events
+  severities     TEXT NOT NULL DEFAULT '[]', -- JSON array filtering finding.created; empty = all
+  active         INTEGER NOT NULL DEFAULT 1,
+  created_at     DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  updated_at     DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_owner ON webhooks(owner_address);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id               TEXT PRIMARY KEY,     -- uuid, sent as X-Watson-Delivery
+  webhook_id       TEXT NOT NULL,
+  event            TEXT NOT NULL,
+  payload          TEXT NOT NULL,        -- JSON body
+  status           TEXT NOT NULL DEFAULT 'pending', -- pending, succeeded, failed
+  attempts         INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at  DATETIME,             -- NULL once succeeded or failed
+  response_code    INTEGER,              -- of the last attempt
+  response_body    TEXT,                 -- of the last attempt, truncated
+  error            TEXT,                 -- of the last attempt when no response was received
+  created_at       DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  delivered_at     DATETIME,
+  FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS projects (
+  id              TEXT PRIMARY KEY,     -- uuid
+  owner_address   TEXT NOT NULL,        -- lower-case ethereum address
+  name            TEXT NOT NULL,
+  description     TEXT,
+  github_url      TEXT,
+  deployments     TEXT NOT NULL DEFAULT '[]', -- JSON array of deployed contracts (blockchain, address, label)
+  default_agents  TEXT NOT NULL DEFAULT '[]', -- JSON array of agent IDs used by audits created without agents
+  created_at      DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  updated_at      DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP)
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_owner ON projects(owner_address);
+
+ALTER TABLE audits ADD COLUMN project_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_audits_project ON audits(project_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_audits_project;
+ALTER TABLE audits DROP COLUMN project_id;
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Snapshot of the files an audit analyzes, taken when the audit is created
+CREATE TABLE IF NOT EXISTS source_files (
+  audit_id    TEXT NOT NULL,
+  path        TEXT NOT NULL,        -- relative to the repository root
+  content     BLOB NOT NULL,
+  size        INTEGER NOT NULL,
+  sha256      TEXT NOT NULL,        -- hex SHA-256 of content
+  created_at  DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  PRIMARY KEY (audit_id, path),
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE CASCADE
+);
+
+-- The ref asked for and the commit it resolved to when the snapshot was taken
+ALTER TABLE audits ADD COLUMN github_ref TEXT;
+ALTER TABLE audits ADD COLUMN commit_sha TEXT;
+
+-- +goose Down
+ALTER TABLE audits DROP COLUMN commit_sha;
+ALTER TABLE audits DROP COLUMN github_ref;
+DROP TABLE IF EXISTS source_files;
@@ -0,0 +1,50 @@
+-- +goose Up
+-- File contents are stored once per distinct content and shared by every audit that has them
+CREATE TABLE IF NOT EXISTS source_blobs (
+  sha256      TEXT PRIMARY KEY,     -- hex SHA-256 of content
+  content     BLOB NOT NULL,
+  size        INTEGER NOT NULL,
+  created_at  DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP)
+);
+
+INSERT OR IGNORE INTO source_blobs (sha256, content, size, created_at)
+SELECT sha256, content, size, created_at FROM source_files;
+
+CREATE TABLE source_files_new (
+  audit_id    TEXT NOT NULL,
+  path        TEXT NOT NULL,        -- relative to the project root
+  sha256      TEXT NOT NULL,
+  size        INTEGER NOT NULL,
+  created_at  DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  PRIMARY KEY (audit_id, path),
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE CASCADE,
+  FOREIGN KEY (sha256) REFERENCES source_blobs(sha256)
+);
+
+INSERT INTO source_files_new (audit_id, path, sha256, size, created_at)
+SELECT audit_id, path, sha256, size, created_at FROM source_files;
+
+DROP TABLE source_files;
+ALTER TABLE source_files_new RENAME TO source_files;
+
+CREATE INDEX IF NOT EXISTS idx_source_files_sha256 ON source_files(sha256);
+
+-- +goose Down
+CREATE TABLE source_files_old (
+  audit_id    TEXT NOT NULL,
+  path        TEXT NOT NULL,
+  content     BLOB NOT NULL,
+  size        INTEGER NOT NULL,
+  sha256      TEXT NOT NULL,
+  created_at  DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  PRIMARY KEY (audit_id, path),
+  FOREIGN KEY (audit_id) REFERENCES audits(id) ON DELETE CASCADE
+);
+
+INSERT INTO source_files_old (audit_id, path, content, size, sha256, created_at)
+SELECT source_files.audit_id, source_files.path, source_blobs.content, source_files.size, source_files.sha256, source_files.created_at
+FROM source_files JOIN source_blobs ON source_blobs.sha256 = source_files.sha256;
+
+DROP TABLE source_files;
+ALTER TABLE source_files_old RENAME TO source_files;
+DROP TABLE IF EXISTS source_blobs;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Compiler of a contract_address audit whose source was fetched from a block explorer
+ALTER TABLE audits ADD COLUMN contract_name TEXT;
+ALTER TABLE audits ADD COLUMN compiler_version TEXT;
+ALTER TABLE audits ADD COLUMN compiler_settings TEXT; -- JSON, solc standard JSON settings
+
+-- +goose Down
+ALTER TABLE audits DROP COLUMN compiler_settings;
+ALTER TABLE audits DROP COLUMN compiler_version;
+ALTER TABLE audits DROP COLUMN contract_name;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Proxies in front of the audited contract_address and the implementations they run
+ALTER TABLE audits ADD COLUMN proxy_topology TEXT; -- JSON
+ALTER TABLE audits ADD COLUMN proxy_admin TEXT;    -- who can upgrade the audited proxy
+
+-- +goose Down
+ALTER TABLE audits DROP COLUMN proxy_admin;
+ALTER TABLE audits DROP COLUMN proxy_topology;
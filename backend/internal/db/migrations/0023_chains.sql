@@ -0,0 +1,25 @@
+-- +goose Up
+-- Chains audited contracts can be deployed on; audits and project deployments refer to them by id
+CREATE TABLE IF NOT EXISTS chains (
+  id                TEXT PRIMARY KEY,     -- slug, e.g. ethereum
+  chain_id          INTEGER NOT NULL UNIQUE,
+  name              TEXT NOT NULL,
+  rpc_urls          TEXT NOT NULL DEFAULT '[]', -- JSON array; the first is used
+  explorer_api_url  TEXT,                 -- Etherscan-compatible API
+  currency_name     TEXT NOT NULL,
+  currency_symbol   TEXT NOT NULL,
+  currency_decimals INTEGER NOT NULL DEFAULT 18,
+  testnet           INTEGER NOT NULL DEFAULT 0,
+  created_at        DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP),
+  updated_at        DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP)
+);
+
+INSERT OR IGNORE INTO chains (id, chain_id, name, explorer_api_url, currency_name, currency_symbol) VALUES
+  ('ethereum', 1, 'Ethereum', 'https://api.etherscan.io/api', 'Ether', 'ETH'),
+  ('polygon', 137, 'Polygon', 'https://api.polygonscan.com/api', 'POL', 'POL'),
+  ('arbitrum', 42161, 'Arbitrum One', 'https://api.arbiscan.io/api', 'Ether', 'ETH'),
+  ('optimism', 10, 'OP Mainnet', 'https://api-optimistic.etherscan.io/api', 'Ether', 'ETH'),
+  ('base', 8453, 'Base', 'https://api.basescan.org/api', 'Ether', 'ETH');
+
+-- +goose Down
+DROP TABLE IF EXISTS chains;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- The audited contract_address as deployed when the audit was created
+ALTER TABLE audits ADD COLUMN code_hash TEXT;           -- keccak256 of the runtime bytecode
+ALTER TABLE audits ADD COLUMN deployment_block INTEGER; -- first block with the code, when the RPC serves history
+CREATE INDEX IF NOT EXISTS idx_audits_contract ON audits(owner_address, blockchain, contract_address);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_audits_contract;
+ALTER TABLE audits DROP COLUMN deployment_block;
+ALTER TABLE audits DROP COLUMN code_hash;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Whether contract_address runs the code the source snapshot compiles to
+ALTER TABLE audits ADD COLUMN verification_status TEXT; -- match, partial, mismatch or failed; NULL until verified
+ALTER TABLE audits ADD COLUMN verification TEXT;        -- JSON: per-address results, compiler and error
+
+-- +goose Down
+ALTER TABLE audits DROP COLUMN verification;
+ALTER TABLE audits DROP COLUMN verification_status;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- What the audited code's bytecode reveals without its source: compiler metadata and dispatched functions
+ALTER TABLE audits ADD COLUMN bytecode TEXT; -- JSON array, one entry per inspected address
+
+-- +goose Down
+ALTER TABLE audits DROP COLUMN bytecode;
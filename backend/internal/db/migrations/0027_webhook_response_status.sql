@@ -0,0 +1,6 @@
+-- +goose Up
+-- Deliveries keep only the status line of responses; bodies stored before could echo internal services
+UPDATE webhook_deliveries SET response_body = NULL;
+
+-- +goose Down
+-- Dropped response bodies can't be restored
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Etherscan retired its per-chain V1 APIs; the V2 API serves every chain, selected by its chainid
+UPDATE chains SET explorer_api_url = 'https://api.etherscan.io/v2/api', updated_at = CURRENT_TIMESTAMP
+WHERE (id, explorer_api_url) IN (VALUES
+  ('ethereum', 'https://api.etherscan.io/api'),
+  ('polygon', 'https://api.polygonscan.com/api'),
+  ('arbitrum', 'https://api.arbiscan.io/api'),
+  ('optimism', 'https://api-optimistic.etherscan.io/api'),
+  ('base', 'https://api.basescan.org/api'));
+
+-- +goose Down
+UPDATE chains SET explorer_api_url = CASE id
+  WHEN 'ethereum' THEN 'https://api.etherscan.io/api'
+  WHEN 'polygon' THEN 'https://api.polygonscan.com/api'
+  WHEN 'arbitrum' THEN 'https://api.arbiscan.io/api'
+  WHEN 'optimism' THEN 'https://api-optimistic.etherscan.io/api'
+  WHEN 'base' THEN 'https://api.basescan.org/api'
+END
+WHERE id IN ('ethereum', 'polygon', 'arbitrum', 'optimism', 'base') AND explorer_api_url = 'https://api.etherscan.io/v2/api';
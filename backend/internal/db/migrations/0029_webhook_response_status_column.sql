@@ -0,0 +1,6 @@
+-- +goose Up
+-- The column has only held the status line of responses since 0027
+ALTER TABLE webhook_deliveries RENAME COLUMN response_body TO response_status;
+
+-- +goose Down
+ALTER TABLE webhook_deliveries RENAME COLUMN response_status TO response_body;
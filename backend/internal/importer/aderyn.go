@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// aderynSections maps the issue sections of Aderyn reports to severities
+var aderynSections = map[string]string{
+	"critical": "critical",
+	"high":     "high",
+	"medium":   "medium",
+	"low":      "low",
+	"nc":       "info",
+}
+
+type aderynIssue struct {
+	Title        string           `json:"title"`
+	Description  string           `json:"description"`
+	DetectorName string           `json:"detector_name"`
+	Instances    []aderynInstance `json:"instances"`
+}
+
+type aderynInstance struct {
+	ContractPath string `json:"contract_path"`
+	LineNo       int    `json:"line_no"`
+	Src          string `json:"src"`
+	Hint         string `json:"hint"`
+}
+
+// parseAderyn parses the JSON report written by `aderyn -o report.json`.
+// Issues are grouped in <severity>_issues sections.
+func parseAderyn(data []byte) ([]Finding, error) {
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, section := range []string{"critical", "high", "medium", "low", "nc"} {
+		raw, ok := out[section+"_issues"]
+		if !ok {
+			continue
+		}
+		var issues struct {
+			Issues []aderynIssue `json:"issues"`
+		}
+		if err := json.Unmarshal(raw, &issues); err != nil {
+			return nil, err
+		}
+		for _, issue := range issues.Issues {
+			findings = append(findings, aderynFinding(issue, aderynSections[section]))
+		}
+	}
+	return findings, nil
+}
+
+// aderynFinding turns an issue into one finding located at its first instance; the other instances are listed in the description
+func aderynFinding(issue aderynIssue, severity string) Finding {
+	f := Finding{
+		Title:       issue.Title,
+		Description: strings.TrimSpace(issue.Description),
+		Severity:    severity,
+	}
+	if f.Title == "" {
+		f.Title = issue.DetectorName
+	}
+	if len(issue.Instances) > 0 {
+		f.File = issue.Instances[0].ContractPath
+		f.Line = issue.Instances[0].LineNo
+	}
+	if len(issue.Instances) > 1 {
+		var b strings.Builder
+		b.WriteString("\n\nInstances:")
+		for _, in := range issue.Instances {
+			b.WriteString("\n- " + in.ContractPath)
+			if in.LineNo > 0 {
+				b.WriteString(":" + strconv.Itoa(in.LineNo))
+			}
+		}
+		f.Description += b.String()
+	}
+	return f
+}
+
+var (
+	// Section headings: "# High Issues"
+	aderynSectionRe = regexp.MustCompile(`^#\s+(Critical|High|Medium|Low|NC)\s+Issues`)
+	// Issue headings: "## H-1: Title"
+	aderynIssueRe = regexp.MustCompile(`^##\s+[A-Z]+-\d+:\s*(.+)$`)
+	// Instances: "- Found in src/Vault.sol [Line: 12](...)"
+	aderynInstanceRe = regexp.MustCompile(`^\s*-\s+Found in\s+(\S+)\s+\[Line:\s*(\d+)\]`)
+)
+
+// parseAderynMarkdown parses the Markdown report Aderyn writes by default
+func parseAderynMarkdown(data []byte) ([]Finding, error) {
+	findings := []Finding{}
+	var issue *aderynIssue
+	severity := ""
+	inDetails := false
+
+	flush := func() {
+		if issue != nil {
+			findings = append(findings, aderynFinding(*issue, severity))
+			issue = nil
+		}
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+
+		if m := aderynSectionRe.FindStringSubmatch(line); m != nil {
+			flush()
+			severity = aderynSections[strings.ToLower(m[1])]
+			continue
+		}
+		if strings.HasPrefix(line, "# ") {
+			// Any other top-level section ends the issues
+			flush()
+			severity = ""
+			continue
+		}
+		if severity == "" {
+			continue
+		}
+		if m := aderynIssueRe.FindStringSubmatch(line); m != nil {
+			flush()
+			issue = &aderynIssue{Title: strings.TrimSpace(m[1])}
+			inDetails = false
+			continue
+		}
+		if issue == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), "<details>"):
+			inDetails = true
+		case strings.HasPrefix(strings.TrimSpace(line), "</details>"):
+			inDetails = false
+		case inDetails:
+			if m := aderynInstanceRe.FindStringSubmatch(line); m != nil {
+				n, _ := strconv.Atoi(m[2])
+				issue.Instances = append(issue.Instances, aderynInstance{ContractPath: m[1], LineNo: n})
+			}
+		default:
+			issue.Description += line + "\n"
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return findings, nil
+}
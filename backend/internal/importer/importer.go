@@ -0,0 +1,149 @@
+// Package importer parses the reports of static analyzers into findings with
+// severities normalized to the critical/high/medium/low/info scale.
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Formats of analyzer output
+const (
+	Slither    = "slither"
+	Aderyn     = "aderyn"
+	AderynMD   = "aderyn-md"
+	Mythril    = "mythril"
+	SARIF      = "sarif"
+	autoDetect = ""
+)
+
+// ErrUnknownFormat is returned when the format is neither known nor detectable
+var ErrUnknownFormat = errors.New("unrecognized analyzer output")
+
+// Finding is a finding parsed from analyzer output
+type Finding struct {
+	Title          string
+	Description    string
+	Severity       string
+	File           string
+	Line           int
+	Function       string
+	Recommendation string
+	CodeSnippet    string
+}
+
+// Tool returns the analyzer that produces a format, which is what imported findings are attributed to
+func Tool(format string) string {
+	if format == AderynMD {
+		return Aderyn
+	}
+	return format
+}
+
+// Parse parses analyzer output. An empty format is detected from the data.
+// It returns the format that was used.
+func Parse(format string, data []byte) ([]Finding, string, error) {
+	if format == autoDetect {
+		format = Detect(data)
+	}
+
+	var findings []Finding
+	var err error
+	switch format {
+	case Slither:
+		findings, err = parseSlither(data)
+	case Aderyn:
+		findings, err = parseAderyn(data)
+	case AderynMD:
+		findings, err = parseAderynMarkdown(data)
+	case Mythril:
+		findings, err = parseMythril(data)
+	case SARIF:
+		findings, err = parseSARIF(data)
+	default:
+		return nil, "", ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, format, fmt.Errorf("%s: %w", format, err)
+	}
+	return findings, format, nil
+}
+
+// Detect guesses the format of analyzer output from its structure; it returns "" when unsure
+func Detect(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return ""
+	}
+
+	// Mythril's jsonv2 output is the only array-shaped report
+	if trimmed[0] == '[' {
+		return Mythril
+	}
+	if trimmed[0] != '{' {
+		if bytes.Contains(trimmed, []byte("Aderyn")) || bytes.Contains(trimmed, []byte("Found Instances")) {
+			return AderynMD
+		}
+		return ""
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &probe); err != nil {
+		return ""
+	}
+	if _, ok := probe["runs"]; ok {
+		return SARIF
+	}
+	if res, ok := probe["results"]; ok && bytes.Contains(res, []byte(`"detectors"`)) {
+		return Slither
+	}
+	for k := range probe {
+		if strings.HasSuffix(k, "_issues") {
+			return Aderyn
+		}
+	}
+	if _, ok := probe["issues"]; ok {
+		return Mythril
+	}
+	return ""
+}
+
+// severityScale maps analyzer severity and impact labels to Watson severities
+var severityScale = map[string]string{
+	"critical":      "critical",
+	"high":          "high",
+	"medium":        "medium",
+	"low":           "low",
+	"informational": "info",
+	"info":          "info",
+	"optimization":  "info",
+	"nc":            "info", // non-critical
+	"note":          "low",
+	"warning":       "medium",
+	"error":         "high",
+	"none":          "info",
+}
+
+// normalizeSeverity maps a label to a Watson severity, falling back to info
+func normalizeSeverity(label string) string {
+	if s, ok := severityScale[strings.ToLower(strings.TrimSpace(label))]; ok {
+		return s
+	}
+	return "info"
+}
+
+// firstLine returns the first non-empty line of s, cut to max characters
+func firstLine(s string, max int) string {
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			if len(l) > max {
+				l = strings.TrimSpace(l[:max]) + "..."
+			}
+			return l
+		}
+	}
+	return ""
+}
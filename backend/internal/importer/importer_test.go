@@ -0,0 +1,203 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readFixture reads analyzer output from testdata
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file   string
+		format string
+		want   []Finding
+	}{
+		{
+			file:   "slither.json",
+			format: Slither,
+			want: []Finding{
+				{
+					Title:       "reentrancy-eth",
+					Description: "Reentrancy in Vault.withdraw() (contracts/Vault.sol#42-50):\n\tExternal calls:\n\t- msg.sender.call{value: amount}() (contracts/Vault.sol#44)\n\nConfidence: Medium",
+					Severity:    "high",
+					File:        "contracts/Vault.sol",
+					Line:        42,
+					Function:    "withdraw",
+				},
+				{
+					Title:       "immutable-states",
+					Description: "Vault.owner should be immutable\n\nConfidence: High",
+					Severity:    "info",
+					File:        "contracts/Vault.sol",
+					Line:        5,
+				},
+			},
+		},
+		{
+			file:   "aderyn.json",
+			format: Aderyn,
+			want: []Finding{
+				{
+					Title:       "Reentrancy in withdraw: state change after external call",
+					Description: "Changing state after an external call can lead to re-entrancy attacks.\n\nInstances:\n- contracts/Vault.sol:44\n- contracts/Vault.sol:60",
+					Severity:    "high",
+					File:        "contracts/Vault.sol",
+					Line:        44,
+				},
+				{
+					Title:       "Centralization Risk",
+					Description: "Contracts have owners with privileged rights.",
+					Severity:    "low",
+					File:        "contracts/Vault.sol",
+					Line:        10,
+				},
+			},
+		},
+		{
+			file:   "aderyn.md",
+			format: AderynMD,
+			want: []Finding{
+				{
+					Title:       "Unprotected initializer",
+					Description: "Consider protecting the initializer functions with modifiers.\n\nInstances:\n- src/Vault.sol:14\n- src/Vault.sol:30",
+					Severity:    "high",
+					File:        "src/Vault.sol",
+					Line:        14,
+				},
+				{
+					Title:       "Solidity pragma should be specific, not wide",
+					Description: "Consider using a specific version of Solidity in your contracts instead of a wide version.",
+					Severity:    "low",
+					File:        "src/Vault.sol",
+					Line:        2,
+				},
+			},
+		},
+		{
+			file:   "mythril.json",
+			format: Mythril,
+			want: []Finding{
+				{
+					Title:       "External Call To User-Supplied Address",
+					Description: "A call to a user-supplied address is executed.\nAn external message call to an address specified by the caller is executed.\n\nSWC-107",
+					Severity:    "low",
+					File:        "contracts/Vault.sol",
+					Line:        44,
+					Function:    "withdraw",
+					CodeSnippet: `msg.sender.call.value(amount)("")`,
+				},
+			},
+		},
+		{
+			// Mythril's jsonv2 output has no line numbers, only source maps
+			file:   "mythrilv2.json",
+			format: Mythril,
+			want: []Finding{
+				{
+					Title:       "Unprotected SELFDESTRUCT Instruction",
+					Description: "The contract can be killed by anyone.\nAnyone can kill this contract.\n\nSWC-106",
+					Severity:    "high",
+					File:        "contracts/Kill.sol",
+					Function:    "kill",
+				},
+			},
+		},
+		{
+			file:   "other.sarif",
+			format: SARIF,
+			want: []Finding{
+				{
+					Title:          "tx.origin used for auth",
+					Description:    "tx.origin is used for authorization\n\nReported by Semgrep (sol.tx-origin)",
+					Severity:       "high",
+					File:           "contracts/My Vault.sol",
+					Line:           12,
+					Function:       "onlyOwner",
+					Recommendation: "Use msg.sender",
+				},
+				{
+					Title:       "StyleRule",
+					Description: "style issue\n\nReported by Semgrep (sol.style)",
+					Severity:    "low",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := readFixture(t, tt.file)
+			if got := Detect(data); got != tt.format {
+				t.Errorf("Detect = %q, want %q", got, tt.format)
+			}
+			// Detected and given formats parse the same
+			for _, format := range []string{autoDetect, tt.format} {
+				got, used, err := Parse(format, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if used != tt.format {
+					t.Errorf("Parse(%q) used %q, want %q", format, used, tt.format)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Parse(%q) =\n%#v\nwant\n%#v", format, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", "  \n"},
+		{"other JSON", `{"findings": []}`},
+		{"malformed JSON", `{"runs": `},
+		{"other markdown", "# Audit report\n\nNo issues."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect([]byte(tt.data)); got != "" {
+				t.Errorf("Detect = %q, want none", got)
+			}
+			if _, _, err := Parse(autoDetect, []byte(tt.data)); !errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("err = %v, want %v", err, ErrUnknownFormat)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, format := range []string{Slither, Aderyn, Mythril, SARIF} {
+		t.Run(format, func(t *testing.T) {
+			_, used, err := Parse(format, []byte(`{"results": `))
+			if err == nil {
+				t.Fatal("want an error")
+			}
+			if used != format {
+				t.Errorf("format = %q, want %q", used, format)
+			}
+		})
+	}
+}
+
+func TestTool(t *testing.T) {
+	for format, want := range map[string]string{Slither: "slither", Aderyn: "aderyn", AderynMD: "aderyn", Mythril: "mythril", SARIF: "sarif"} {
+		if got := Tool(format); got != want {
+			t.Errorf("Tool(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// mythrilOutput is the report written by `myth analyze -o json`
+type mythrilOutput struct {
+	Success bool           `json:"success"`
+	Error   *string        `json:"error"`
+	Issues  []mythrilIssue `json:"issues"`
+}
+
+type mythrilIssue struct {
+	Title       string `json:"title"`
+	SWCID       string `json:"swc-id"`
+	Severity    string `json:"severity"`
+	Contract    string `json:"contract"`
+	Function    string `json:"function"`
+	Description string `json:"description"`
+	Filename    string `json:"filename"`
+	LineNo      int    `json:"lineno"`
+	Code        string `json:"code"`
+}
+
+// mythrilV2Report is one element of the report written by `myth analyze -o jsonv2`
+type mythrilV2Report struct {
+	Issues []struct {
+		SWCID       string `json:"swcID"`
+		SWCTitle    string `json:"swcTitle"`
+		Severity    string `json:"severity"`
+		Description struct {
+			Head string `json:"head"`
+			Tail string `json:"tail"`
+		} `json:"description"`
+		Locations []struct {
+			SourceMap string `json:"sourceMap"`
+		} `json:"locations"`
+		Extra struct {
+			Function string `json:"function"`
+		} `json:"extra"`
+	} `json:"issues"`
+	SourceList []string `json:"sourceList"`
+}
+
+func parseMythril(data []byte) ([]Finding, error) {
+	if t := bytes.TrimSpace(data); len(t) > 0 && t[0] == '[' {
+		return parseMythrilV2(t)
+	}
+
+	var out mythrilOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if out.Error != nil && *out.Error != "" {
+		return nil, errors.New("report contains an error: " + *out.Error)
+	}
+
+	findings := []Finding{}
+	for _, issue := range out.Issues {
+		f := Finding{
+			Title:       issue.Title,
+			Description: strings.TrimSpace(issue.Description),
+			Severity:    normalizeSeverity(issue.Severity),
+			File:        issue.Filename,
+			Line:        issue.LineNo,
+			Function:    mythrilFunction(issue.Function),
+			CodeSnippet: issue.Code,
+		}
+		if issue.SWCID != "" {
+			f.Description += "\n\nSWC-" + issue.SWCID
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// parseMythrilV2 parses jsonv2 reports. Locations are byte offsets into the sources,
+// so only the file is known.
+func parseMythrilV2(data []byte) ([]Finding, error) {
+	var reports []mythrilV2Report
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, rep := range reports {
+		for _, issue := range rep.Issues {
+			f := Finding{
+				Title:       issue.SWCTitle,
+				Description: strings.TrimSpace(issue.Description.Head + "\n" + issue.Description.Tail),
+				Severity:    normalizeSeverity(issue.Severity),
+				Function:    mythrilFunction(issue.Extra.Function),
+			}
+			if f.Title == "" {
+				f.Title = firstLine(issue.Description.Head, 120)
+			}
+			if issue.SWCID != "" {
+				f.Description += "\n\n" + issue.SWCID
+			}
+			// sourceMap is "offset:length:file index"
+			if len(issue.Locations) > 0 {
+				parts := strings.Split(issue.Locations[0].SourceMap, ":")
+				if len(parts) >= 3 {
+					if i, err := strconv.Atoi(parts[2]); err == nil && i >= 0 && i < len(rep.SourceList) {
+						f.File = rep.SourceList[i]
+					}
+				}
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// mythrilFunction strips the parameter list from a signature such as "withdraw(uint256)"
+func mythrilFunction(sig string) string {
+	if i := strings.IndexByte(sig, '('); i >= 0 {
+		sig = sig[:i]
+	}
+	return strings.TrimSpace(sig)
+}
@@ -0,0 +1,191 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// sarifLog holds the parts of a SARIF 2.1.0 log findings are read from
+type sarifLog struct {
+	Version string `json:"version"`
+	Runs    []struct {
+		Tool struct {
+			Driver struct {
+				Name  string      `json:"name"`
+				Rules []sarifRule `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []sarifResult `json:"results"`
+	} `json:"runs"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	FullDescription  sarifMessage `json:"fullDescription"`
+	Help             sarifMessage `json:"help"`
+	Default          struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	Properties struct {
+		SecuritySeverity json.RawMessage `json:"security-severity"`
+	} `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown"`
+}
+
+func (m sarifMessage) String() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	return m.Markdown
+}
+
+type sarifResult struct {
+	RuleID    string       `json:"ruleId"`
+	RuleIndex *int         `json:"ruleIndex"`
+	Level     string       `json:"level"`
+	Message   sarifMessage `json:"message"`
+	Locations []struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+				Snippet   struct {
+					Text string `json:"text"`
+				} `json:"snippet"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+		LogicalLocations []struct {
+			Name               string `json:"name"`
+			FullyQualifiedName string `json:"fullyQualifiedName"`
+			Kind               string `json:"kind"`
+		} `json:"logicalLocations"`
+	} `json:"locations"`
+}
+
+// parseSARIF parses SARIF 2.1.0 logs from any tool. Severity comes from the rule's
+// security-severity score when present, otherwise from the result or rule level.
+func parseSARIF(data []byte) ([]Finding, error) {
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, err
+	}
+	if log.Version != "" && log.Version != "2.1.0" {
+		return nil, errors.New("unsupported SARIF version " + log.Version)
+	}
+
+	findings := []Finding{}
+	for _, run := range log.Runs {
+		rules := run.Tool.Driver.Rules
+		byID := map[string]*sarifRule{}
+		for i := range rules {
+			byID[rules[i].ID] = &rules[i]
+		}
+
+		for _, res := range run.Results {
+			rule := byID[res.RuleID]
+			if res.RuleIndex != nil && *res.RuleIndex >= 0 && *res.RuleIndex < len(rules) {
+				rule = &rules[*res.RuleIndex]
+			}
+
+			f := Finding{Description: strings.TrimSpace(res.Message.String())}
+			level := res.Level
+			if rule != nil {
+				f.Title = rule.ShortDescription.String()
+				if f.Title == "" {
+					f.Title = rule.Name
+				}
+				f.Recommendation = rule.Help.String()
+				if level == "" {
+					level = rule.Default.Level
+				}
+			}
+			if f.Title == "" {
+				f.Title = res.RuleID
+			}
+			if f.Title == "" {
+				f.Title = firstLine(f.Description, 120)
+			}
+			if run.Tool.Driver.Name != "" {
+				f.Description += "\n\nReported by " + run.Tool.Driver.Name
+				if res.RuleID != "" {
+					f.Description += " (" + res.RuleID + ")"
+				}
+			}
+
+			// SARIF's default level is warning
+			if level == "" {
+				level = "warning"
+			}
+			f.Severity = normalizeSeverity(level)
+			if rule != nil {
+				if s, ok := securitySeverity(rule.Properties.SecuritySeverity); ok {
+					f.Severity = s
+				}
+			}
+
+			if len(res.Locations) > 0 {
+				loc := res.Locations[0]
+				f.File = sarifPath(loc.PhysicalLocation.ArtifactLocation.URI)
+				f.Line = loc.PhysicalLocation.Region.StartLine
+				f.CodeSnippet = loc.PhysicalLocation.Region.Snippet.Text
+				for _, l := range loc.LogicalLocations {
+					if l.Kind == "" || l.Kind == "function" || l.Kind == "member" {
+						f.Function = l.Name
+						if f.Function == "" {
+							f.Function = l.FullyQualifiedName
+						}
+						break
+					}
+				}
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// securitySeverity maps a CVSS-like security-severity score, a string by convention, to a severity
+func securitySeverity(raw json.RawMessage) (string, bool) {
+	if len(raw) == 0 {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		s = string(raw)
+	}
+	score, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case score >= 9:
+		return "critical", true
+	case score >= 7:
+		return "high", true
+	case score >= 4:
+		return "medium", true
+	case score > 0:
+		return "low", true
+	}
+	return "info", true
+}
+
+// sarifPath turns an artifact URI into a file path
+func sarifPath(uri string) string {
+	uri = strings.TrimPrefix(uri, "file://")
+	if p, err := url.PathUnescape(uri); err == nil {
+		return p
+	}
+	return uri
+}
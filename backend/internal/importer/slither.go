@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// slitherOutput is the report written by `slither --json`
+type slitherOutput struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+	Results struct {
+		Detectors []slitherDetector `json:"detectors"`
+	} `json:"results"`
+}
+
+type slitherDetector struct {
+	Check       string           `json:"check"`
+	Impact      string           `json:"impact"`
+	Confidence  string           `json:"confidence"`
+	Description string           `json:"description"`
+	Elements    []slitherElement `json:"elements"`
+}
+
+type slitherElement struct {
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	SourceMapping struct {
+		FilenameRelative string `json:"filename_relative"`
+		FilenameShort    string `json:"filename_short"`
+		Lines            []int  `json:"lines"`
+	} `json:"source_mapping"`
+	TypeSpecificFields struct {
+		Parent *slitherElement `json:"parent"`
+	} `json:"type_specific_fields"`
+}
+
+func parseSlither(data []byte) ([]Finding, error) {
+	var out slitherOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if !out.Success && out.Error != "" {
+		return nil, errors.New("report contains an error: " + out.Error)
+	}
+
+	findings := []Finding{}
+	for _, d := range out.Results.Detectors {
+		f := Finding{
+			Title:       d.Check,
+			Description: strings.TrimSpace(d.Description),
+			Severity:    normalizeSeverity(d.Impact),
+		}
+		if f.Title == "" {
+			f.Title = firstLine(d.Description, 120)
+		}
+		if d.Confidence != "" {
+			f.Description += "\n\nConfidence: " + d.Confidence
+		}
+
+		// The first element is the primary location of the result
+		if len(d.Elements) > 0 {
+			e := d.Elements[0]
+			f.File = e.SourceMapping.FilenameRelative
+			if f.File == "" {
+				f.File = e.SourceMapping.FilenameShort
+			}
+			if len(e.SourceMapping.Lines) > 0 {
+				f.Line = e.SourceMapping.Lines[0]
+			}
+			f.Function = slitherFunction(&e)
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// slitherFunction returns the name of the function an element is, or is nested in
+func slitherFunction(e *slitherElement) string {
+	for ; e != nil; e = e.TypeSpecificFields.Parent {
+		if e.Type == "function" {
+			return e.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,257 @@
+// Package match scores how likely two findings describe the same issue, groups
+// findings into clusters of duplicates and pairs up the findings of two audits.
+// Everything is computed locally from the finding's location and text.
+package match
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold is the similarity above which two findings are treated as duplicates
+const DefaultThreshold = 0.6
+
+// minTextSimilarity is the text similarity two findings need before their location counts. Different issues are
+// often reported in the same function, so a shared location alone must not make findings duplicates.
+const minTextSimilarity = 0.5
+
+// Item is the part of a finding used for matching
+type Item struct {
+	Agent       string // who reported the finding; Cluster never groups two items of one agent
+	File        string
+	Line        int
+	Function    string
+	Title       string
+	Description string
+}
+
+// stopwords are common words that carry no meaning for matching
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"can": true, "could": true, "for": true, "from": true, "has": true, "in": true, "is": true, "it": true,
+	"its": true, "may": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true,
+	"to": true, "which": true, "with": true,
+}
+
+// Similarity returns a score in [0, 1] of how likely a and b describe the same issue.
+// When both findings have a location and their texts are similar enough, location and
+// text weigh equally; findings in different files never score above 0.5. Otherwise only
+// the text is compared.
+func Similarity(a, b Item) float64 {
+	text := textSimilarity(a, b)
+	loc, known := locationSimilarity(a, b)
+	if !known || text < minTextSimilarity {
+		return text
+	}
+	return (loc + text) / 2
+}
+
+// Cluster groups items whose similarity reaches threshold, transitively, the most similar
+// pairs first. Items of the same agent are never grouped, as an agent reporting two
+// findings means two issues. Groups are returned as sorted indexes into items, ordered by
+// their first index; items that match nothing are returned as single-item groups.
+func Cluster(items []Item, threshold float64) [][]int {
+	parent := make([]int, len(items))
+	agents := make([]map[string]bool, len(items))
+	for i := range parent {
+		parent[i] = i
+		agents[i] = map[string]bool{}
+		if items[i].Agent != "" {
+			agents[i][items[i].Agent] = true
+		}
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	var candidates []Pair
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			if items[i].Agent != "" && items[i].Agent == items[j].Agent {
+				continue
+			}
+			if s := Similarity(items[i], items[j]); s >= threshold {
+				candidates = append(candidates, Pair{Left: i, Right: j, Score: s})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	for _, p := range candidates {
+		ri, rj := find(p.Left), find(p.Right)
+		if ri == rj || shareAgent(agents[ri], agents[rj]) {
+			continue
+		}
+		parent[rj] = ri
+		for agent := range agents[rj] {
+			agents[ri][agent] = true
+		}
+	}
+
+	groups := map[int][]int{}
+	var roots []int
+	for i := range items {
+		r := find(i)
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], i)
+	}
+
+	out := make([][]int, 0, len(roots))
+	for _, r := range roots {
+		g := groups[r]
+		sort.Ints(g)
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out
+}
+
+// Pair is a match between an item of one list and an item of another
+type Pair struct {
+	Left, Right int
+	Score       float64
+}
+
+// Match pairs items of left with items of right describing the same issue, each item
+// at most once. The most similar items are paired first; pairs scoring below threshold
+// are dropped. Pairs are returned ordered by their left index.
+func Match(left, right []Item, threshold float64) []Pair {
+	var candidates []Pair
+	for i := range left {
+		for j := range right {
+			if s := Similarity(left[i], right[j]); s >= threshold {
+				candidates = append(candidates, Pair{Left: i, Right: j, Score: s})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	usedLeft, usedRight := map[int]bool{}, map[int]bool{}
+	var out []Pair
+	for _, p := range candidates {
+		if usedLeft[p.Left] || usedRight[p.Right] {
+			continue
+		}
+		usedLeft[p.Left], usedRight[p.Right] = true, true
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Left < out[j].Left })
+	return out
+}
+
+// shareAgent reports whether two groups have an agent in common
+func shareAgent(a, b map[string]bool) bool {
+	for agent := range a {
+		if b[agent] {
+			return true
+		}
+	}
+	return false
+}
+
+// locationSimilarity compares where two findings were reported.
+// known is false when there is not enough location information to compare.
+func locationSimilarity(a, b Item) (score float64, known bool) {
+	fa, fb := normalizeFile(a.File), normalizeFile(b.File)
+	fnA, fnB := strings.ToLower(strings.TrimSpace(a.Function)), strings.ToLower(strings.TrimSpace(b.Function))
+	sameFunction := fnA != "" && fnA == fnB
+	differentFunction := fnA != "" && fnB != "" && fnA != fnB
+
+	if fa == "" || fb == "" {
+		if sameFunction {
+			return 1, true
+		}
+		return 0, false
+	}
+	if fa != fb {
+		return 0, true
+	}
+
+	score = 0.5
+	if a.Line > 0 && b.Line > 0 {
+		switch d := abs(a.Line - b.Line); {
+		case d <= 3:
+			score = 1
+		case d <= 15:
+			score = 0.7
+		default:
+			score = 0.2
+		}
+	}
+	if sameFunction {
+		score = math.Max(score, 0.9)
+	}
+	if differentFunction {
+		score = math.Min(score, 0.3)
+	}
+	return score, true
+}
+
+// textSimilarity is the cosine similarity of the findings' term frequencies.
+// Title terms count twice as they are the most specific part of a finding.
+func textSimilarity(a, b Item) float64 {
+	va, vb := terms(a), terms(b)
+	if len(va) == 0 || len(vb) == 0 {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for t, x := range va {
+		na += x * x
+		dot += x * vb[t]
+	}
+	for _, y := range vb {
+		nb += y * y
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func terms(it Item) map[string]float64 {
+	v := map[string]float64{}
+	for _, t := range tokenize(it.Title) {
+		v[t] += 2
+	}
+	for _, t := range tokenize(it.Description) {
+		v[t]++
+	}
+	return v
+}
+
+// tokenize splits text into lower-case words, dropping stopwords and single characters
+func tokenize(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if len(f) > 1 && !stopwords[f] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// normalizeFile reduces a path to its base name, as agents report paths relative to different roots
+func normalizeFile(f string) string {
+	f = strings.TrimSpace(strings.ReplaceAll(f, "\\", "/"))
+	if f == "" {
+		return ""
+	}
+	return strings.ToLower(path.Base(f))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+package match
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Findings on a vault's withdraw function, as different agents word them
+var (
+	reentrancy = Item{
+		Agent: "a", File: "src/Vault.sol", Line: 42, Function: "withdraw",
+		Title:       "Reentrancy in withdraw",
+		Description: "withdraw sends ETH to the caller before updating its balance, so the caller can reenter withdraw and drain the vault.",
+	}
+	reentrancyReworded = Item{
+		Agent: "b", File: "contracts/Vault.sol", Line: 43, Function: "withdraw",
+		Title:       "Reentrancy vulnerability in withdraw",
+		Description: "The balance is updated after the external call, allowing reentrancy to drain the vault.",
+	}
+	uncheckedCall = Item{
+		Agent: "b", File: "src/Vault.sol", Line: 44, Function: "withdraw",
+		Title:       "Unchecked return value of low-level call in withdraw",
+		Description: "withdraw ignores the success flag returned by the low-level call that sends ETH to the caller.",
+	}
+	missingEvent = Item{
+		Agent: "c", File: "src/Token.sol", Line: 10, Function: "setOwner",
+		Title:       "Missing event for owner change",
+		Description: "setOwner changes the owner without emitting an event.",
+	}
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     Item
+		min, max float64
+	}{
+		{name: "identical", a: reentrancy, b: reentrancy, min: 1, max: 1},
+		{name: "same issue reworded", a: reentrancy, b: reentrancyReworded, min: DefaultThreshold, max: 1},
+		{name: "different issues in the same function", a: reentrancy, b: uncheckedCall, min: 0, max: DefaultThreshold - 0.01},
+		{name: "unrelated", a: reentrancy, b: missingEvent, min: 0, max: 0.1},
+		{
+			name: "same text in different files",
+			a:    reentrancy,
+			b:    Item{File: "src/Pool.sol", Line: 42, Function: "withdraw", Title: reentrancy.Title, Description: reentrancy.Description},
+			min:  0.5, max: 0.5,
+		},
+		{
+			name: "same location without text",
+			a:    Item{File: "Vault.sol", Line: 42, Function: "withdraw"},
+			b:    Item{File: "Vault.sol", Line: 42, Function: "withdraw"},
+			min:  0, max: 0,
+		},
+		{
+			name: "same text without location",
+			a:    Item{Title: "Reentrancy in withdraw"},
+			b:    Item{Title: "Reentrancy in withdraw"},
+			min:  1, max: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Similarity(tt.a, tt.b)
+			if got < tt.min-1e-9 || got > tt.max+1e-9 {
+				t.Errorf("Similarity = %.3f, want between %.2f and %.2f", got, tt.min, tt.max)
+			}
+			if rev := Similarity(tt.b, tt.a); math.Abs(rev-got) > 1e-9 {
+				t.Errorf("Similarity isn't symmetric: %.3f and %.3f", got, rev)
+			}
+		})
+	}
+}
+
+func TestCluster(t *testing.T) {
+	sameAgentTwin := reentrancyReworded
+	sameAgentTwin.Agent = "a"
+
+	tests := []struct {
+		name  string
+		items []Item
+		want  [][]int
+	}{
+		{
+			name:  "duplicates across agents",
+			items: []Item{reentrancy, missingEvent, reentrancyReworded},
+			want:  [][]int{{0, 2}, {1}},
+		},
+		{
+			name:  "different issues in the same function",
+			items: []Item{reentrancy, uncheckedCall},
+			want:  [][]int{{0}, {1}},
+		},
+		{
+			name:  "same agent",
+			items: []Item{reentrancy, sameAgentTwin},
+			want:  [][]int{{0}, {1}},
+		},
+		{
+			// b's finding matches both of a's; a's findings aren't merged through it
+			name:  "same agent through another agent",
+			items: []Item{reentrancy, sameAgentTwin, {Agent: "b", Title: reentrancy.Title, Description: reentrancy.Description}},
+			want:  [][]int{{0, 2}, {1}},
+		},
+		{
+			name:  "no agents",
+			items: []Item{{Title: "Reentrancy in withdraw"}, {Title: "Reentrancy in withdraw"}},
+			want:  [][]int{{0, 1}},
+		},
+		{
+			name:  "empty",
+			items: nil,
+			want:  [][]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Cluster(tt.items, DefaultThreshold); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cluster = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right []Item
+		want        [][2]int
+	}{
+		{
+			name:  "same issues in another round",
+			left:  []Item{missingEvent, reentrancyReworded},
+			right: []Item{reentrancy, missingEvent},
+			want:  [][2]int{{0, 1}, {1, 0}},
+		},
+		{
+			// The same agent reports the issue again in the next audit
+			name:  "same agent",
+			left:  []Item{reentrancy},
+			right: []Item{reentrancy},
+			want:  [][2]int{{0, 0}},
+		},
+		{
+			name:  "each item paired once, the most similar first",
+			left:  []Item{reentrancyReworded, reentrancy},
+			right: []Item{reentrancy},
+			want:  [][2]int{{1, 0}},
+		},
+		{
+			name:  "different issues in the same function",
+			left:  []Item{uncheckedCall},
+			right: []Item{reentrancy},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][2]int
+			for _, p := range Match(tt.left, tt.right, DefaultThreshold) {
+				got = append(got, [2]int{p.Left, p.Right})
+				if p.Score < DefaultThreshold {
+					t.Errorf("pair %d-%d scores %.3f, below the threshold", p.Left, p.Right, p.Score)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+// Package proxy follows upgradeable proxies to the code they run: EIP-1967 transparent,
+// UUPS and beacon proxies, EIP-2535 diamonds and EIP-1167 minimal clones.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Proxy kinds
+const (
+	KindTransparent = "transparent" // EIP-1967 implementation upgraded through the admin slot
+	KindUUPS        = "uups"        // EIP-1967 implementation upgraded through the implementation (ERC-1822)
+	KindEIP1967     = "eip1967"     // EIP-1967 implementation with neither an admin nor proxiableUUID
+	KindBeacon      = "beacon"      // EIP-1967 beacon, whose implementation() is delegated to
+	KindDiamond     = "diamond"     // EIP-2535, delegating each selector to a facet
+	KindMinimal     = "minimal"     // EIP-1167 clone, with its implementation in the bytecode
+)
+
+// maxDepth bounds how many proxies are followed, e.g. a beacon proxy behind a transparent proxy
+const maxDepth = 4
+
+// ErrTooDeep is returned when proxies point at each other, or more than maxDepth are chained
+var ErrTooDeep = errors.New("proxy chain is too long or cyclic")
+
+// EIP-1967 storage slots: keccak256("eip1967.proxy.<name>") - 1
+var (
+	implementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	adminSlot          = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+	beaconSlot         = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+)
+
+// EIP-1167 minimal proxy bytecode around the implementation address
+var (
+	minimalPrefix = common.FromHex("0x363d3d373d3d3d363d73")
+	minimalSuffix = common.FromHex("0x5af43d82803e903d91602b57fd5bf3")
+)
+
+// proxyABIJSON holds the views proxies and their implementations are probed with
+const proxyABIJSON = `[
+{"type":"function","name":"implementation","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+{"type":"function","name":"proxiableUUID","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"owner","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+{"type":"function","name":"facets","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"tuple[]","components":[
+  {"name":"facetAddress","type":"address"},{"name":"functionSelectors","type":"bytes4[]"}]}]}
+]`
+
+var proxyABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(proxyABIJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Facet is a diamond facet and the function selectors routed to it
+type Facet struct {
+	Address   string   `json:"address"`
+	Selectors []string `json:"selectors"`
+}
+
+// Proxy is a proxy found on the way from an audited address to the code it runs
+type Proxy struct {
+	Address        string  `json:"address"`
+	Kind           string  `json:"kind"`
+	Admin          string  `json:"admin,omitempty"` // who can upgrade it: the admin slot, or owner() of the upgrade authority
+	Beacon         string  `json:"beacon,omitempty"`
+	Implementation string  `json:"implementation,omitempty"`
+	Facets         []Facet `json:"facets,omitempty"`
+}
+
+// Topology is the chain of proxies in front of an address and the implementations they end at
+type Topology struct {
+	Proxies         []Proxy  `json:"proxies"`
+	Implementations []string `json:"implementations"` // the final implementation, or a diamond's facets
+}
+
+// Resolve follows the proxies at address to their implementations.
+// It returns nil when address isn't a proxy.
+func Resolve(ctx context.Context, client *ethclient.Client, address common.Address) (*Topology, error) {
+	t := &Topology{}
+	seen := map[common.Address]bool{}
+	for addr := address; ; {
+		if seen[addr] || len(t.Proxies) == maxDepth {
+			return nil, ErrTooDeep
+		}
+		seen[addr] = true
+
+		p, err := detect(ctx, client, addr)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			if len(t.Proxies) == 0 {
+				return nil, nil
+			}
+			t.Implementations = []string{addr.Hex()}
+			return t, nil
+		}
+		t.Proxies = append(t.Proxies, *p)
+
+		if p.Kind == KindDiamond {
+			for _, f := range p.Facets {
+				t.Implementations = append(t.Implementations, f.Address)
+			}
+			return t, nil
+		}
+		addr = common.HexToAddress(p.Implementation)
+	}
+}
+
+// detect returns the proxy at addr, or nil when its code isn't one of the known proxy patterns
+func detect(ctx context.Context, client *ethclient.Client, addr common.Address) (*Proxy, error) {
+	code, err := client.CodeAt(ctx, addr, nil)
+	if err != nil || len(code) == 0 {
+		return nil, err
+	}
+	p := &Proxy{Address: addr.Hex()}
+
+	if len(code) == len(minimalPrefix)+common.AddressLength+len(minimalSuffix) &&
+		bytes.HasPrefix(code, minimalPrefix) && bytes.HasSuffix(code, minimalSuffix) {
+		p.Kind = KindMinimal
+		p.Implementation = common.BytesToAddress(code[len(minimalPrefix) : len(minimalPrefix)+common.AddressLength]).Hex()
+		return p, nil
+	}
+
+	impl, err := slotAddress(ctx, client, addr, implementationSlot)
+	if err != nil {
+		return nil, err
+	}
+	if impl != (common.Address{}) {
+		p.Implementation = impl.Hex()
+		admin, err := slotAddress(ctx, client, addr, adminSlot)
+		if err != nil {
+			return nil, err
+		}
+		if admin != (common.Address{}) {
+			p.Kind = KindTransparent
+			p.Admin = admin.Hex()
+			return p, nil
+		}
+		uups, err := isUUPS(ctx, client, impl)
+		if err != nil {
+			return nil, err
+		}
+		p.Kind = KindEIP1967
+		if uups {
+			p.Kind = KindUUPS
+			if p.Admin, err = owner(ctx, client, addr); err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	}
+
+	beacon, err := slotAddress(ctx, client, addr, beaconSlot)
+	if err != nil {
+		return nil, err
+	}
+	if beacon != (common.Address{}) {
+		var impl common.Address
+		ok, err := callView(ctx, client, beacon, "implementation", &impl)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || impl == (common.Address{}) {
+			return nil, errors.New("proxy: beacon " + beacon.Hex() + " has no implementation")
+		}
+		p.Kind = KindBeacon
+		p.Beacon = beacon.Hex()
+		p.Implementation = impl.Hex()
+		if p.Admin, err = owner(ctx, client, beacon); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	var facets []struct {
+		FacetAddress      common.Address
+		FunctionSelectors [][4]byte
+	}
+	ok, err := callView(ctx, client, addr, "facets", &facets)
+	if err != nil {
+		return nil, err
+	}
+	if ok && len(facets) > 0 {
+		p.Kind = KindDiamond
+		if p.Admin, err = owner(ctx, client, addr); err != nil {
+			return nil, err
+		}
+		for _, f := range facets {
+			facet := Facet{Address: f.FacetAddress.Hex(), Selectors: make([]string, len(f.FunctionSelectors))}
+			for i, s := range f.FunctionSelectors {
+				facet.Selectors[i] = "0x" + common.Bytes2Hex(s[:])
+			}
+			p.Facets = append(p.Facets, facet)
+		}
+		return p, nil
+	}
+	return nil, nil
+}
+
+// slotAddress reads an address stored in a storage slot
+func slotAddress(ctx context.Context, client *ethclient.Client, addr common.Address, slot common.Hash) (common.Address, error) {
+	v, err := client.StorageAt(ctx, addr, slot, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(v), nil
+}
+
+// isUUPS reports whether an implementation is ERC-1822 proxiable through the EIP-1967 slot
+func isUUPS(ctx context.Context, client *ethclient.Client, impl common.Address) (bool, error) {
+	var uuid [32]byte
+	ok, err := callView(ctx, client, impl, "proxiableUUID", &uuid)
+	return ok && common.Hash(uuid) == implementationSlot, err
+}
+
+// owner returns the EIP-173 owner of a contract, or "" when it has none
+func owner(ctx context.Context, client *ethclient.Client, addr common.Address) (string, error) {
+	var o common.Address
+	ok, err := callView(ctx, client, addr, "owner", &o)
+	if !ok || o == (common.Address{}) {
+		return "", err
+	}
+	return o.Hex(), nil
+}
+
+// callView calls a view of proxyABI, reporting whether the contract has it and out was decoded.
+// Reverts and undecodable results mean the contract doesn't implement the view; other
+// failures, such as an unreachable or rate-limited RPC, are returned.
+func callView(ctx context.Context, client *ethclient.Client, addr common.Address, method string, out interface{}) (bool, error) {
+	input, err := proxyABI.Pack(method)
+	if err != nil {
+		return false, err
+	}
+	res, err := client.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: input}, nil)
+	if err != nil {
+		if executionFailed(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if len(res) == 0 {
+		return false, nil
+	}
+	return proxyABI.UnpackIntoInterface(out, method, res) == nil, nil
+}
+
+// executionErrors are the messages nodes answer eth_call with when the call itself fails, e.g. on a selector
+// the contract has no function for; geth also answers reverts with code 3
+var executionErrors = []string{"revert", "invalid opcode", "invalid jump", "out of gas", "stack underflow"}
+
+// executionFailed reports whether err is the node's answer that a call failed in the EVM, rather than a
+// failure to get an answer
+func executionFailed(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	if rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	msg := strings.ToLower(rpcErr.Error())
+	for _, e := range executionErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
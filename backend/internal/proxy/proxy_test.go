@@ -0,0 +1,339 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	proxyAddr  = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	implAddr   = common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	implAddr2  = common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	beaconAddr = common.HexToAddress("0x00000000000000000000000000000000000000be")
+	adminAddr  = common.HexToAddress("0x00000000000000000000000000000000000000ad")
+	ownerAddr  = common.HexToAddress("0x00000000000000000000000000000000000000ee")
+)
+
+// rpcReply is a fake node's answer to a call: an ABI-encoded result, a JSON-RPC error, or an HTTP status
+type rpcReply struct {
+	result  []interface{}
+	code    int
+	message string
+	status  int
+}
+
+var (
+	reverted    = rpcReply{code: -32000, message: "execution reverted"}
+	rateLimited = rpcReply{code: -32005, message: "limit exceeded"}
+	unavailable = rpcReply{status: http.StatusBadGateway}
+)
+
+// fakeNode answers eth_getCode, eth_getStorageAt and eth_call like a node would for the contracts it holds.
+// Calls to views of proxyABI a contract has no reply for revert.
+type fakeNode struct {
+	code    map[common.Address][]byte
+	storage map[common.Address]map[common.Hash]common.Address
+	calls   map[common.Address]map[string]rpcReply
+}
+
+// contract is code that isn't a minimal proxy
+var contract = []byte{0x60, 0x00}
+
+// dial serves the node over HTTP and returns a client for it
+func (n fakeNode) dial(t *testing.T) *ethclient.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+			return
+		}
+
+		var addr common.Address
+		switch req.Method {
+		case "eth_getCode":
+			json.Unmarshal(req.Params[0], &addr)
+			writeRPC(w, req.ID, hexutil.Bytes(n.code[addr]), 0, "")
+		case "eth_getStorageAt":
+			var slot common.Hash
+			json.Unmarshal(req.Params[0], &addr)
+			json.Unmarshal(req.Params[1], &slot)
+			writeRPC(w, req.ID, common.BytesToHash(n.storage[addr][slot].Bytes()), 0, "")
+		case "eth_call":
+			var call struct {
+				To    common.Address `json:"to"`
+				Input hexutil.Bytes  `json:"input"`
+			}
+			json.Unmarshal(req.Params[0], &call)
+			var method string
+			for name, m := range proxyABI.Methods {
+				if len(call.Input) >= 4 && string(call.Input[:4]) == string(m.ID) {
+					method = name
+				}
+			}
+			reply, ok := n.calls[call.To][method]
+			if !ok {
+				reply = reverted
+			}
+			if reply.status != 0 {
+				w.WriteHeader(reply.status)
+				return
+			}
+			if reply.code != 0 {
+				writeRPC(w, req.ID, nil, reply.code, reply.message)
+				return
+			}
+			out, err := proxyABI.Methods[method].Outputs.Pack(reply.result...)
+			if err != nil {
+				t.Errorf("%s: %v", method, err)
+			}
+			writeRPC(w, req.ID, hexutil.Bytes(out), 0, "")
+		default:
+			writeRPC(w, req.ID, nil, -32601, "method not supported: "+req.Method)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := ethclient.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// writeRPC writes a JSON-RPC response with result, or with an error when code isn't 0
+func writeRPC(w http.ResponseWriter, id json.RawMessage, result interface{}, code int, message string) {
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if code != 0 {
+		resp["error"] = map[string]interface{}{"code": code, "message": message}
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// minimalClone is the EIP-1167 bytecode of a clone of impl
+func minimalClone(impl common.Address) []byte {
+	return append(append(append([]byte{}, minimalPrefix...), impl.Bytes()...), minimalSuffix...)
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name string
+		node fakeNode
+		want *Topology
+	}{
+		{
+			name: "not a proxy",
+			node: fakeNode{code: map[common.Address][]byte{proxyAddr: contract}},
+			want: nil,
+		},
+		{
+			name: "no code",
+			node: fakeNode{},
+			want: nil,
+		},
+		{
+			name: "transparent",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract, implAddr: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {implementationSlot: implAddr, adminSlot: adminAddr}},
+			},
+			want: &Topology{
+				Proxies:         []Proxy{{Address: proxyAddr.Hex(), Kind: KindTransparent, Admin: adminAddr.Hex(), Implementation: implAddr.Hex()}},
+				Implementations: []string{implAddr.Hex()},
+			},
+		},
+		{
+			name: "UUPS",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract, implAddr: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {implementationSlot: implAddr}},
+				calls: map[common.Address]map[string]rpcReply{
+					implAddr:  {"proxiableUUID": {result: []interface{}{[32]byte(implementationSlot)}}},
+					proxyAddr: {"owner": {result: []interface{}{ownerAddr}}},
+				},
+			},
+			want: &Topology{
+				Proxies:         []Proxy{{Address: proxyAddr.Hex(), Kind: KindUUPS, Admin: ownerAddr.Hex(), Implementation: implAddr.Hex()}},
+				Implementations: []string{implAddr.Hex()},
+			},
+		},
+		{
+			// The implementation reverts on proxiableUUID, as it has no such function
+			name: "EIP-1967",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract, implAddr: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {implementationSlot: implAddr}},
+			},
+			want: &Topology{
+				Proxies:         []Proxy{{Address: proxyAddr.Hex(), Kind: KindEIP1967, Implementation: implAddr.Hex()}},
+				Implementations: []string{implAddr.Hex()},
+			},
+		},
+		{
+			// The beacon has no owner(), and reverts with geth's revert code
+			name: "beacon",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract, implAddr: contract, beaconAddr: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {beaconSlot: beaconAddr}},
+				calls: map[common.Address]map[string]rpcReply{
+					beaconAddr: {
+						"implementation": {result: []interface{}{implAddr}},
+						"owner":          {code: 3, message: "execution reverted"},
+					},
+				},
+			},
+			want: &Topology{
+				Proxies:         []Proxy{{Address: proxyAddr.Hex(), Kind: KindBeacon, Beacon: beaconAddr.Hex(), Implementation: implAddr.Hex()}},
+				Implementations: []string{implAddr.Hex()},
+			},
+		},
+		{
+			name: "diamond",
+			node: fakeNode{
+				code: map[common.Address][]byte{proxyAddr: contract},
+				calls: map[common.Address]map[string]rpcReply{
+					proxyAddr: {
+						"facets": {result: []interface{}{[]struct {
+							FacetAddress      common.Address
+							FunctionSelectors [][4]byte
+						}{
+							{implAddr, [][4]byte{{0x12, 0x34, 0x56, 0x78}}},
+							{implAddr2, [][4]byte{{0xaa, 0xbb, 0xcc, 0xdd}, {0x11, 0x22, 0x33, 0x44}}},
+						}}},
+						"owner": {result: []interface{}{ownerAddr}},
+					},
+				},
+			},
+			want: &Topology{
+				Proxies: []Proxy{{Address: proxyAddr.Hex(), Kind: KindDiamond, Admin: ownerAddr.Hex(), Facets: []Facet{
+					{Address: implAddr.Hex(), Selectors: []string{"0x12345678"}},
+					{Address: implAddr2.Hex(), Selectors: []string{"0xaabbccdd", "0x11223344"}},
+				}}},
+				Implementations: []string{implAddr.Hex(), implAddr2.Hex()},
+			},
+		},
+		{
+			name: "minimal clone behind a transparent proxy",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract, implAddr: minimalClone(implAddr2), implAddr2: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {implementationSlot: implAddr, adminSlot: adminAddr}},
+			},
+			want: &Topology{
+				Proxies: []Proxy{
+					{Address: proxyAddr.Hex(), Kind: KindTransparent, Admin: adminAddr.Hex(), Implementation: implAddr.Hex()},
+					{Address: implAddr.Hex(), Kind: KindMinimal, Implementation: implAddr2.Hex()},
+				},
+				Implementations: []string{implAddr2.Hex()},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(context.Background(), tt.node.dial(t), proxyAddr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	uups := func(owner rpcReply) fakeNode {
+		return fakeNode{
+			code:    map[common.Address][]byte{proxyAddr: contract, implAddr: contract},
+			storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {implementationSlot: implAddr}},
+			calls: map[common.Address]map[string]rpcReply{
+				implAddr:  {"proxiableUUID": {result: []interface{}{[32]byte(implementationSlot)}}},
+				proxyAddr: {"owner": owner},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		node    fakeNode
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name: "cycle",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {implementationSlot: proxyAddr, adminSlot: adminAddr}},
+			},
+			wantErr: ErrTooDeep,
+		},
+		{
+			name: "beacon without an implementation",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract, beaconAddr: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {beaconSlot: beaconAddr}},
+			},
+			wantMsg: "has no implementation",
+		},
+		{
+			// A UUPS proxy's owner isn't dropped because the RPC failed to answer
+			name:    "owner rate-limited",
+			node:    uups(rateLimited),
+			wantMsg: "limit exceeded",
+		},
+		{
+			name:    "owner unavailable",
+			node:    uups(unavailable),
+			wantMsg: "502",
+		},
+		{
+			// Nor is a UUPS proxy taken for a plain EIP-1967 one
+			name: "proxiableUUID unavailable",
+			node: fakeNode{
+				code:    map[common.Address][]byte{proxyAddr: contract, implAddr: contract},
+				storage: map[common.Address]map[common.Hash]common.Address{proxyAddr: {implementationSlot: implAddr}},
+				calls:   map[common.Address]map[string]rpcReply{implAddr: {"proxiableUUID": unavailable}},
+			},
+			wantMsg: "502",
+		},
+		{
+			// Nor is a diamond taken for a contract that isn't a proxy
+			name: "facets rate-limited",
+			node: fakeNode{
+				code:  map[common.Address][]byte{proxyAddr: contract},
+				calls: map[common.Address]map[string]rpcReply{proxyAddr: {"facets": rateLimited}},
+			},
+			wantMsg: "limit exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(context.Background(), tt.node.dial(t), proxyAddr)
+			if err == nil {
+				t.Fatalf("got %+v, want an error", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("err = %v, want %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
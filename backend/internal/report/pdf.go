@@ -0,0 +1,251 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page layout in points
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 50.0
+	lineSpacing  = 1.35
+	bulletIndent = 12.0
+	codeIndent   = 10.0
+)
+
+// Fonts are the PDF standard fonts, so nothing has to be embedded
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	fontMono    = "F3"
+)
+
+// charWidth approximates the average glyph width of each font as a fraction of the font size
+var charWidth = map[string]float64{
+	fontRegular: 0.52,
+	fontBold:    0.56,
+	fontMono:    0.6,
+}
+
+// pdfLine is one laid out line of text
+type pdfLine struct {
+	font   string
+	size   float64
+	indent float64
+	before float64 // extra space above the line
+	text   string
+}
+
+// renderPDF lays out a Markdown report as a paginated PDF document
+func renderPDF(markdown, title string) []byte {
+	return writePDF(paginate(layout(markdown)), title)
+}
+
+// layout turns the Markdown produced by report templates into wrapped lines.
+// Only the constructs used by report templates are recognized: headings, tables,
+// bullet lists, fenced code blocks and paragraphs.
+func layout(markdown string) []pdfLine {
+	var lines []pdfLine
+	width := pageWidth - 2*pageMargin
+	inCode := false
+	gap := 0.0
+
+	add := func(font string, size, indent float64, text string) {
+		for i, l := range wrap(text, font, size, width-indent) {
+			before := 0.0
+			if i == 0 {
+				before = gap
+			}
+			lines = append(lines, pdfLine{font: font, size: size, indent: indent, before: before, text: l})
+		}
+		gap = 0
+	}
+
+	for _, raw := range strings.Split(markdown, "\n") {
+		line := strings.TrimRight(raw, " \t\r")
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			gap = 4
+			continue
+		}
+		if inCode {
+			add(fontMono, 8.5, codeIndent, strings.ReplaceAll(line, "\t", "    "))
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			gap = 6
+		case strings.HasPrefix(line, "# "):
+			add(fontBold, 18, 0, inline(line[2:]))
+			gap = 8
+		case strings.HasPrefix(line, "## "):
+			gap = 14
+			add(fontBold, 14, 0, inline(line[3:]))
+			gap = 4
+		case strings.HasPrefix(line, "### "):
+			gap = 10
+			add(fontBold, 12, 0, inline(line[4:]))
+			gap = 2
+		case strings.HasPrefix(line, "|"):
+			if row := tableRow(line); row != "" {
+				add(fontRegular, 10, 0, row)
+			}
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "):
+			add(fontRegular, 10, bulletIndent, "• "+inline(line[2:]))
+		default:
+			add(fontRegular, 10, 0, inline(line))
+		}
+	}
+	return lines
+}
+
+// inline strips Markdown emphasis and code markers
+func inline(s string) string {
+	return strings.NewReplacer("**", "", "`", "").Replace(strings.TrimSpace(s))
+}
+
+// tableRow renders a Markdown table row as text; separator and empty rows render as ""
+func tableRow(line string) string {
+	var cells []string
+	for _, c := range strings.Split(strings.Trim(line, "|"), "|") {
+		c = inline(c)
+		if strings.Trim(c, "-: ") == "" {
+			continue
+		}
+		cells = append(cells, c)
+	}
+	if len(cells) == 2 {
+		return cells[0] + ": " + cells[1]
+	}
+	return strings.Join(cells, "   ")
+}
+
+// wrap breaks text into lines that fit width, splitting words longer than a line
+func wrap(text, font string, size, width float64) []string {
+	max := int(width / (charWidth[font] * size))
+	if max < 1 {
+		max = 1
+	}
+	if len([]rune(text)) <= max {
+		return []string{text}
+	}
+
+	var out []string
+	var cur []rune
+	for _, word := range strings.Split(text, " ") {
+		w := []rune(word)
+		if len(cur) > 0 && len(cur)+1+len(w) > max {
+			out = append(out, string(cur))
+			cur = nil
+		}
+		for len(w) > max {
+			if len(cur) > 0 {
+				out = append(out, string(cur))
+				cur = nil
+			}
+			out = append(out, string(w[:max]))
+			w = w[max:]
+		}
+		if len(cur) > 0 {
+			cur = append(cur, ' ')
+		}
+		cur = append(cur, w...)
+	}
+	return append(out, string(cur))
+}
+
+// paginate splits lines into pages of content stream operators
+func paginate(lines []pdfLine) []string {
+	var pages []string
+	var page strings.Builder
+	y := pageHeight - pageMargin
+
+	for _, l := range lines {
+		step := l.before + l.size*lineSpacing
+		if y-step < pageMargin && page.Len() > 0 {
+			pages = append(pages, page.String())
+			page.Reset()
+			y = pageHeight - pageMargin
+			step = l.size * lineSpacing
+		}
+		y -= step
+		fmt.Fprintf(&page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", l.font, l.size, pageMargin+l.indent, y, pdfString(l.text))
+	}
+	if page.Len() > 0 || len(pages) == 0 {
+		pages = append(pages, page.String())
+	}
+	return pages
+}
+
+// pdfString encodes text as a WinAnsi PDF string literal body
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '•':
+			b.WriteString(`\225`)
+		case r == '‘' || r == '’':
+			b.WriteByte('\'')
+		case r == '“' || r == '”':
+			b.WriteByte('"')
+		case r == '–' || r == '—':
+			b.WriteByte('-')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// writePDF assembles the document: catalog, page tree, fonts, then a page and content stream per page
+func writePDF(pages []string, title string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-5 are fixed; pages start at object 6, each followed by its content stream
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 7+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (Watson) >>", pdfString(title)))
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, len(offsets), xref)
+	return buf.Bytes()
+}
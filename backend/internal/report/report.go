@@ -0,0 +1,266 @@
+// Package report renders audit reports as Markdown, self-contained HTML and PDF.
+// Markdown and HTML are produced by templates; PDF is laid out from the Markdown rendering.
+package report
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Formats a report can be rendered to
+const (
+	Markdown = "markdown"
+	HTML     = "html"
+	PDF      = "pdf"
+)
+
+// ErrUnknownFormat is returned for formats other than Markdown, HTML and PDF
+var ErrUnknownFormat = errors.New("unknown report format")
+
+// Data is what report templates are executed with
+type Data struct {
+	Title       string
+	Audit       Audit
+	Summary     Summary
+	Findings    []Finding
+	Diff        *Diff // nil unless the report compares the audit with an earlier one
+	GeneratedAt time.Time
+}
+
+// Audit is the audit metadata shown in a report
+type Audit struct {
+	ID              string
+	Name            string
+	Description     string
+	Owner           string
+	Status          string
+	Blockchain      string
+	ContractAddress string
+	GitHubURL       string
+	Agents          []string
+	CreatedAt       time.Time
+	CompletedAt     *time.Time
+	Verification    *Verification // nil unless the deployed bytecode was compared with the source
+}
+
+// Verification is the outcome of comparing the audited contract's deployed bytecode with the compiled source
+type Verification struct {
+	Status          string // match, partial, mismatch or failed
+	Targets         []VerificationTarget
+	CompilerVersion string
+	Warnings        []string // e.g. the local compiler differs from the verified one
+	Error           string
+	VerifiedAt      time.Time
+}
+
+// VerificationTarget is the outcome for one deployed address, e.g. each implementation behind a proxy
+type VerificationTarget struct {
+	Address  string
+	Status   string
+	Contract string
+}
+
+// Summary counts the reported findings by severity
+type Summary struct {
+	Critical int
+	High     int
+	Medium   int
+	Low      int
+	Info     int
+	Total    int
+}
+
+// Finding is a finding included in a report
+type Finding struct {
+	ID             string
+	Title          string
+	Description    string
+	Severity       string
+	Status         string
+	Location       string
+	Recommendation string
+	CodeSnippet    string
+	Agents         []string
+}
+
+// Diff compares the reported audit's findings with those of an earlier audit
+type Diff struct {
+	Base    Audit
+	Summary DiffSummary
+	Changes []Change
+}
+
+// DiffSummary counts the changes of a diff by kind
+type DiffSummary struct {
+	New             int
+	Resolved        int
+	Persisting      int
+	SeverityChanged int
+}
+
+// Change is a finding of either audit and how it changed. Resolved findings are
+// described as they were in the earlier audit.
+type Change struct {
+	Change           string // new, resolved, persisting or severity_changed
+	Title            string
+	Severity         string
+	PreviousSeverity string // set when the severity changed
+	Location         string
+}
+
+// changeLabels are the headings of the kinds of change
+var changeLabels = map[string]string{
+	"new":              "New",
+	"resolved":         "Resolved",
+	"persisting":       "Persisting",
+	"severity_changed": "Severity changed",
+}
+
+// verificationLabels describe the outcomes of a bytecode verification
+var verificationLabels = map[string]string{
+	"match":    "Match",
+	"partial":  "Partial match (metadata hash differs)",
+	"mismatch": "Mismatch",
+	"failed":   "Not verified",
+}
+
+// Summarize counts findings by severity
+func Summarize(findings []Finding) Summary {
+	var s Summary
+	for _, f := range findings {
+		switch f.Severity {
+		case "critical":
+			s.Critical++
+		case "high":
+			s.High++
+		case "medium":
+			s.Medium++
+		case "low":
+			s.Low++
+		case "info":
+			s.Info++
+		}
+		s.Total++
+	}
+	return s
+}
+
+// TemplateFormat returns the template format used to render a report format: PDF is laid out from Markdown
+func TemplateFormat(format string) (string, error) {
+	switch format {
+	case Markdown, PDF:
+		return Markdown, nil
+	case HTML:
+		return HTML, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType returns the MIME type of a rendered report
+func ContentType(format string) string {
+	switch format {
+	case Markdown:
+		return "text/markdown; charset=utf-8"
+	case HTML:
+		return "text/html; charset=utf-8"
+	case PDF:
+		return "application/pdf"
+	}
+	return "application/octet-stream"
+}
+
+// Extension returns the file extension of a rendered report
+func Extension(format string) string {
+	switch format {
+	case Markdown:
+		return "md"
+	case HTML:
+		return "html"
+	}
+	return format
+}
+
+// Validate checks that a custom template of the given template format parses
+func Validate(templateFormat, body string) error {
+	_, err := parse(templateFormat, body)
+	return err
+}
+
+// Render renders data to format. An empty body uses the built-in template; otherwise body
+// is a custom template of the format's template format (see TemplateFormat).
+func Render(format, body string, data Data) ([]byte, error) {
+	tf, err := TemplateFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	if body == "" {
+		b, err := templateFS.ReadFile("templates/report." + Extension(tf) + ".tmpl")
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+	}
+
+	t, err := parse(tf, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	if format == PDF {
+		return renderPDF(buf.String(), data.Title), nil
+	}
+	return buf.Bytes(), nil
+}
+
+// executor is implemented by text/template and html/template templates
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+func parse(templateFormat, body string) (executor, error) {
+	switch templateFormat {
+	case Markdown:
+		return texttemplate.New("report").Funcs(funcs).Parse(body)
+	case HTML:
+		return htmltemplate.New("report").Funcs(funcs).Parse(body)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// funcs are available to all report templates
+var funcs = map[string]interface{}{
+	"upper": strings.ToUpper,
+	"join":  strings.Join,
+	"inc":   func(i int) int { return i + 1 },
+	"date":  func(t time.Time) string { return t.UTC().Format("2006-01-02") },
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 UTC")
+	},
+	"change": func(kind string) string {
+		if l, ok := changeLabels[kind]; ok {
+			return l
+		}
+		return kind
+	},
+	"verification": func(status string) string {
+		if l, ok := verificationLabels[status]; ok {
+			return l
+		}
+		return status
+	},
+}
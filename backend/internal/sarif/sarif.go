@@ -0,0 +1,175 @@
+// Package sarif implements the subset of the SARIF 2.1.0 log format used to export findings
+// to code scanning tools. Field names and required properties follow the OASIS schema:
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+package sarif
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Version and Schema identify the SARIF format of a Log
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Log is the top-level SARIF document
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run holds the results produced by one tool
+type Run struct {
+	Tool              Tool               `json:"tool"`
+	AutomationDetails *AutomationDetails `json:"automationDetails,omitempty"`
+	Results           []Result           `json:"results"`
+}
+
+// AutomationDetails identifies a run among the runs of the same analysis
+type AutomationDetails struct {
+	ID string `json:"id"`
+}
+
+// Tool describes the analysis tool of a run
+type Tool struct {
+	Driver ToolComponent `json:"driver"`
+}
+
+// ToolComponent is the tool that produced the results and defines their rules
+type ToolComponent struct {
+	Name           string                 `json:"name"`
+	FullName       string                 `json:"fullName,omitempty"`
+	InformationURI string                 `json:"informationUri,omitempty"`
+	Rules          []ReportingDescriptor  `json:"rules"`
+	Properties     map[string]interface{} `json:"properties,omitempty"`
+}
+
+// ReportingDescriptor describes a rule results refer to
+type ReportingDescriptor struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name,omitempty"`
+	ShortDescription     *Message               `json:"shortDescription,omitempty"`
+	FullDescription      *Message               `json:"fullDescription,omitempty"`
+	Help                 *Message               `json:"help,omitempty"`
+	DefaultConfiguration *Configuration         `json:"defaultConfiguration,omitempty"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Configuration is the default configuration of a rule
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+// Message is a plain text message
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is one finding
+type Result struct {
+	RuleID       string                 `json:"ruleId"`
+	RuleIndex    int                    `json:"ruleIndex"`
+	Level        string                 `json:"level"`
+	Message      Message                `json:"message"`
+	Locations    []Location             `json:"locations,omitempty"`
+	Fingerprints map[string]string      `json:"fingerprints,omitempty"`
+	Suppressions []Suppression          `json:"suppressions,omitempty"`
+	Properties   map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Location is where a result was found
+type Location struct {
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []LogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+// PhysicalLocation is a file and optionally a region of it
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is the URI of a file, relative to the analyzed sources
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is a range of lines; lines start at 1
+type Region struct {
+	StartLine int `json:"startLine"`
+}
+
+// LogicalLocation is a named construct such as a function
+type LogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+}
+
+// Suppression marks a result as reviewed and dismissed
+type Suppression struct {
+	Kind          string `json:"kind"`
+	Status        string `json:"status,omitempty"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// Level maps a finding severity to a SARIF result level
+func Level(severity string) string {
+	switch severity {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	}
+	return "note"
+}
+
+// SecuritySeverity maps a finding severity to the CVSS-like score code scanning tools sort security results by
+func SecuritySeverity(severity string) string {
+	switch severity {
+	case "critical":
+		return "9.5"
+	case "high":
+		return "8.0"
+	case "medium":
+		return "5.5"
+	case "low":
+		return "2.0"
+	}
+	return "0.0"
+}
+
+// ArtifactURI turns a reported file path into a relative URI reference
+func ArtifactURI(file string) string {
+	file = strings.TrimPrefix(strings.ReplaceAll(strings.TrimSpace(file), "\\", "/"), "./")
+	parts := strings.Split(strings.TrimLeft(file, "/"), "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// RuleID derives a stable rule identifier from a finding title
+func RuleID(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	id := strings.TrimSuffix(b.String(), "-")
+	if len(id) > 64 {
+		id = strings.TrimSuffix(id[:64], "-")
+	}
+	if id == "" {
+		return "finding"
+	}
+	return id
+}
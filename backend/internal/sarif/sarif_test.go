@@ -0,0 +1,78 @@
+package sarif
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleID(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Reentrancy in withdraw", "reentrancy-in-withdraw"},
+		{"  Unchecked return value (call)  ", "unchecked-return-value-call"},
+		{"tx.origin used for auth!", "tx-origin-used-for-auth"},
+		{"ERC-20 approve race", "erc-20-approve-race"},
+		{"Dépôt non vérifié", "d-p-t-non-v-rifi"},
+		{"!!!", "finding"},
+		{"", "finding"},
+		// Cut at 64 characters
+		{strings.Repeat("overflow ", 10), "overflow-overflow-overflow-overflow-overflow-overflow-overflow-o"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got := RuleID(tt.title)
+			if got != tt.want {
+				t.Errorf("RuleID(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+			if len(got) > 64 {
+				t.Errorf("RuleID(%q) is %d characters long", tt.title, len(got))
+			}
+		})
+	}
+}
+
+func TestArtifactURI(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"src/Vault.sol", "src/Vault.sol"},
+		{"./src/Vault.sol", "src/Vault.sol"},
+		{"/src/Vault.sol", "src/Vault.sol"},
+		{" src\\lib\\Math.sol ", "src/lib/Math.sol"},
+		{"src/My Vault.sol", "src/My%20Vault.sol"},
+		{"src/#1/Vault.sol", "src/%231/Vault.sol"},
+		{"node_modules/@oz/token/ERC20.sol", "node_modules/@oz/token/ERC20.sol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := ArtifactURI(tt.file); got != tt.want {
+				t.Errorf("ArtifactURI(%q) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		severity string
+		level    string
+		score    string
+	}{
+		{"critical", "error", "9.5"},
+		{"high", "error", "8.0"},
+		{"medium", "warning", "5.5"},
+		{"low", "note", "2.0"},
+		{"info", "note", "0.0"},
+	}
+	for _, tt := range tests {
+		if got := Level(tt.severity); got != tt.level {
+			t.Errorf("Level(%q) = %q, want %q", tt.severity, got, tt.level)
+		}
+		if got := SecuritySeverity(tt.severity); got != tt.score {
+			t.Errorf("SecuritySeverity(%q) = %q, want %q", tt.severity, got, tt.score)
+		}
+	}
+}
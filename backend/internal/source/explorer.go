@@ -0,0 +1,190 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotVerified is returned when an explorer has no verified source for an address
+var ErrNotVerified = errors.New("contract source is not verified")
+
+// Verified is the verified source of a deployed contract
+type Verified struct {
+	ContractName    string
+	CompilerVersion string          // e.g. v0.8.19+commit.7dd6d404, or vyper:0.3.10
+	Settings        json.RawMessage // solc standard JSON settings
+	Files           []File
+	Proxy           bool     // the explorer flags the contract as a proxy
+	Implementation  string   // the proxy's implementation, when the explorer knows it
+	Skipped         []string // source paths that couldn't be kept, as they escape the root or repeat another's
+}
+
+// Explorer fetches verified sources through the getsourcecode action of
+// Etherscan-compatible block explorer APIs, such as one per chain
+type Explorer struct {
+	APIKey string
+	Client *http.Client
+}
+
+// NewExplorer returns an explorer client authenticating with apiKey, if set
+func NewExplorer(apiKey string) *Explorer {
+	return &Explorer{
+		APIKey: apiKey,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// sourceCodeResult is an entry of a getsourcecode response
+type sourceCodeResult struct {
+	SourceCode       string `json:"SourceCode"`
+	ContractName     string `json:"ContractName"`
+	CompilerVersion  string `json:"CompilerVersion"`
+	OptimizationUsed string `json:"OptimizationUsed"`
+	Runs             string `json:"Runs"`
+	EVMVersion       string `json:"EVMVersion"`
+	Library          string `json:"Library"`
+	Proxy            string `json:"Proxy"`
+	Implementation   string `json:"Implementation"`
+}
+
+// standardInput is the part of a solc standard JSON input a snapshot keeps
+type standardInput struct {
+	Sources map[string]struct {
+		Content string `json:"content"`
+	} `json:"sources"`
+	Settings json.RawMessage `json:"settings"`
+}
+
+// SourceCode returns the verified source of the contract at address, from the explorer API at apiURL.
+// chainID selects the chain on multichain APIs such as Etherscan V2; single-chain explorers ignore it.
+func (e *Explorer) SourceCode(ctx context.Context, apiURL string, chainID int64, address string) (*Verified, error) {
+	q := url.Values{"module": {"contract"}, "action": {"getsourcecode"}, "address": {address}}
+	if chainID != 0 {
+		q.Set("chainid", strconv.FormatInt(chainID, 10))
+	}
+	if e.APIKey != "" {
+		q.Set("apikey", e.APIKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("explorer: %s", resp.Status)
+	}
+
+	// Errors such as rate limiting or a bad key come back as status "0" with a message as the result
+	var body struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("explorer: %w", err)
+	}
+	var results []sourceCodeResult
+	if body.Status != "1" || json.Unmarshal(body.Result, &results) != nil {
+		var msg string
+		json.Unmarshal(body.Result, &msg)
+		return nil, fmt.Errorf("explorer: %s %s", body.Message, msg)
+	}
+	if len(results) == 0 || results[0].SourceCode == "" {
+		return nil, ErrNotVerified
+	}
+	return results[0].verified()
+}
+
+// verified reads the three shapes SourceCode comes in: a single file, a JSON object
+// of files, or a solc standard JSON input wrapped in an extra pair of braces
+func (r sourceCodeResult) verified() (*Verified, error) {
+	v := &Verified{
+		ContractName:    r.ContractName,
+		CompilerVersion: r.CompilerVersion,
+		Proxy:           r.Proxy == "1",
+		Implementation:  r.Implementation,
+	}
+
+	code := strings.TrimSpace(r.SourceCode)
+	var input standardInput
+	switch {
+	case strings.HasPrefix(code, "{{") && strings.HasSuffix(code, "}}"):
+		if err := json.Unmarshal([]byte(code[1:len(code)-1]), &input); err != nil {
+			return nil, fmt.Errorf("explorer: standard JSON source: %w", err)
+		}
+		v.Settings = input.Settings
+	case strings.HasPrefix(code, "{"):
+		if err := json.Unmarshal([]byte(code), &input.Sources); err != nil {
+			return nil, fmt.Errorf("explorer: multi-file source: %w", err)
+		}
+	default:
+		ext := ".sol"
+		if strings.HasPrefix(strings.ToLower(r.CompilerVersion), "vyper") {
+			ext = ".vy"
+		}
+		v.Files = []File{{Path: r.ContractName + ext, Content: []byte(r.SourceCode)}}
+	}
+
+	paths := make([]string, 0, len(input.Sources))
+	for p := range input.Sources {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var total int
+	seen := map[string]bool{}
+	for _, p := range paths {
+		// Truffle verifications key sources by absolute path, e.g. /Users/x/contracts/A.sol
+		clean := CleanPath(strings.TrimLeft(strings.ReplaceAll(p, "\\", "/"), "/"), 0)
+		if clean == "" || seen[clean] {
+			v.Skipped = append(v.Skipped, p)
+			continue
+		}
+		seen[clean] = true
+		content := input.Sources[p].Content
+		total += len(content)
+		v.Files = append(v.Files, File{Path: clean, Content: []byte(content)})
+	}
+	if len(v.Files) > MaxFiles || total > MaxTotalSize {
+		return nil, ErrTooLarge
+	}
+	if v.Settings == nil {
+		v.Settings = r.settings()
+	}
+	return v, nil
+}
+
+// settings rebuilds solc settings from the compiler options of a non-standard-JSON verification
+func (r sourceCodeResult) settings() json.RawMessage {
+	runs, _ := strconv.Atoi(r.Runs)
+	settings := map[string]interface{}{
+		"optimizer": map[string]interface{}{"enabled": r.OptimizationUsed == "1", "runs": runs},
+	}
+	if r.EVMVersion != "" && !strings.EqualFold(r.EVMVersion, "default") {
+		settings["evmVersion"] = strings.ToLower(r.EVMVersion)
+	}
+	// Libraries are listed as Name:0xaddress pairs separated by semicolons
+	if r.Library != "" {
+		libs := map[string]string{}
+		for _, l := range strings.Split(r.Library, ";") {
+			if name, addr, ok := strings.Cut(l, ":"); ok {
+				libs[strings.TrimSpace(name)] = strings.TrimSpace(addr)
+			}
+		}
+		settings["libraries"] = map[string]interface{}{"": libs}
+	}
+	b, _ := json.Marshal(settings)
+	return b
+}
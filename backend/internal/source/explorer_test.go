@@ -0,0 +1,233 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// explorerServer serves results as the getsourcecode result of every request, recording their queries
+func explorerServer(t *testing.T, results ...map[string]string) (*httptest.Server, *[]url.Values) {
+	t.Helper()
+	var queries []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "1", "message": "OK", "result": results})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &queries
+}
+
+// sourceJSON encodes sources keyed by path as the sources of a multi-file verification
+func sourceJSON(t *testing.T, sources map[string]string) string {
+	t.Helper()
+	m := map[string]map[string]string{}
+	for p, content := range sources {
+		m[p] = map[string]string{"content": content}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSourceCodeQuery(t *testing.T) {
+	srv, queries := explorerServer(t, map[string]string{"SourceCode": "contract A {}", "ContractName": "A"})
+
+	e := NewExplorer("key")
+	if _, err := e.SourceCode(context.Background(), srv.URL+"/v2/api", 8453, "0xabc"); err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"module":  {"contract"},
+		"action":  {"getsourcecode"},
+		"address": {"0xabc"},
+		"chainid": {"8453"},
+		"apikey":  {"key"},
+	}
+	if got := (*queries)[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("query = %v, want %v", got, want)
+	}
+
+	// Single-chain explorers are queried without chainid, and without a key when none is set
+	if _, err := NewExplorer("").SourceCode(context.Background(), srv.URL, 0, "0xabc"); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*queries)[1]; got.Has("chainid") || got.Has("apikey") {
+		t.Errorf("query = %v, want no chainid or apikey", got)
+	}
+}
+
+func TestSourceCodeSingleFile(t *testing.T) {
+	srv, _ := explorerServer(t, map[string]string{
+		"SourceCode":       "contract Token {}",
+		"ContractName":     "Token",
+		"CompilerVersion":  "v0.8.19+commit.7dd6d404",
+		"OptimizationUsed": "1",
+		"Runs":             "200",
+		"EVMVersion":       "Paris",
+		"Library":          "Math:0x1111111111111111111111111111111111111111",
+		"Proxy":            "1",
+		"Implementation":   "0x2222222222222222222222222222222222222222",
+	})
+
+	v, err := NewExplorer("").SourceCode(context.Background(), srv.URL, 1, "0xabc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(v.Files); !reflect.DeepEqual(got, []string{"Token.sol: contract Token {}"}) {
+		t.Errorf("files = %q", got)
+	}
+	if v.ContractName != "Token" || v.CompilerVersion != "v0.8.19+commit.7dd6d404" {
+		t.Errorf("contract = %s, compiler = %s", v.ContractName, v.CompilerVersion)
+	}
+	if !v.Proxy || v.Implementation != "0x2222222222222222222222222222222222222222" {
+		t.Errorf("proxy = %v, implementation = %s", v.Proxy, v.Implementation)
+	}
+
+	var settings map[string]interface{}
+	if err := json.Unmarshal(v.Settings, &settings); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"optimizer":  map[string]interface{}{"enabled": true, "runs": float64(200)},
+		"evmVersion": "paris",
+		"libraries":  map[string]interface{}{"": map[string]interface{}{"Math": "0x1111111111111111111111111111111111111111"}},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("settings = %v, want %v", settings, want)
+	}
+}
+
+func TestSourceCodeVyper(t *testing.T) {
+	srv, _ := explorerServer(t, map[string]string{"SourceCode": "# @version 0.3.10", "ContractName": "Pool", "CompilerVersion": "vyper:0.3.10"})
+
+	v, err := NewExplorer("").SourceCode(context.Background(), srv.URL, 1, "0xabc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(v.Files); !reflect.DeepEqual(got, []string{"Pool.vy: # @version 0.3.10"}) {
+		t.Errorf("files = %q", got)
+	}
+}
+
+func TestSourceCodeMultiFile(t *testing.T) {
+	srv, _ := explorerServer(t, map[string]string{
+		"SourceCode": sourceJSON(t, map[string]string{
+			"contracts/Vault.sol": "contract Vault {}",
+			"contracts\\Math.sol": "library Math {}",
+		}),
+		"ContractName": "Vault",
+	})
+
+	v, err := NewExplorer("").SourceCode(context.Background(), srv.URL, 1, "0xabc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"contracts/Math.sol: library Math {}", "contracts/Vault.sol: contract Vault {}"}
+	if got := paths(v.Files); !reflect.DeepEqual(got, want) {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+	if len(v.Skipped) != 0 {
+		t.Errorf("skipped = %q, want none", v.Skipped)
+	}
+}
+
+func TestSourceCodeStandardJSON(t *testing.T) {
+	input, err := json.Marshal(map[string]interface{}{
+		"language": "Solidity",
+		"sources": map[string]map[string]string{
+			// Truffle verifications key sources by absolute path
+			"/Users/dev/project/contracts/Vault.sol":              {"content": "contract Vault {}"},
+			"/Users/dev/project/node_modules/@oz/token/ERC20.sol": {"content": "contract ERC20 {}"},
+			"C:\\project\\contracts\\Math.sol":                    {"content": "library Math {}"},
+			"../outside/Evil.sol":                                 {"content": "contract Evil {}"},
+			"Users/dev/project/contracts/Vault.sol":               {"content": "contract Copy {}"},
+		},
+		"settings": map[string]interface{}{"optimizer": map[string]interface{}{"enabled": true, "runs": 1000}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, _ := explorerServer(t, map[string]string{"SourceCode": "{" + string(input) + "}", "ContractName": "Vault"})
+
+	v, err := NewExplorer("").SourceCode(context.Background(), srv.URL, 1, "0xabc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"C:/project/contracts/Math.sol: library Math {}",
+		"Users/dev/project/contracts/Vault.sol: contract Vault {}",
+		"Users/dev/project/node_modules/@oz/token/ERC20.sol: contract ERC20 {}",
+	}
+	if got := paths(v.Files); !reflect.DeepEqual(got, want) {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+	// Paths are taken in sorted order, so the absolute path is kept and its relative twin skipped
+	wantSkipped := []string{"../outside/Evil.sol", "Users/dev/project/contracts/Vault.sol"}
+	if !reflect.DeepEqual(v.Skipped, wantSkipped) {
+		t.Errorf("skipped = %q, want %q", v.Skipped, wantSkipped)
+	}
+	if got := string(v.Settings); got != `{"optimizer":{"enabled":true,"runs":1000}}` {
+		t.Errorf("settings = %s", got)
+	}
+}
+
+func TestSourceCodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name: "not verified",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"status":"1","message":"OK","result":[{"SourceCode":"","ABI":"Contract source code not verified"}]}`))
+			},
+			wantErr: ErrNotVerified,
+		},
+		{
+			name: "API error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"status":"0","message":"NOTOK","result":"Invalid API Key"}`))
+			},
+			wantMsg: "NOTOK Invalid API Key",
+		},
+		{
+			name: "HTTP error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(502)
+			},
+			wantMsg: "502",
+		},
+		{
+			name: "bad standard JSON",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"status":"1","message":"OK","result":[{"SourceCode":"{{\"sources\": [}}"}]}`))
+			},
+			wantMsg: "standard JSON source",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			_, err := NewExplorer("").SourceCode(context.Background(), srv.URL, 1, "0xabc")
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.wantMsg)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a repository or ref doesn't exist, or isn't visible with the configured token
+var ErrNotFound = errors.New("repository or ref not found")
+
+// Repo identifies a GitHub repository and, optionally, the branch, tag or commit taken from its URL
+type Repo struct {
+	Owner string
+	Name  string
+	Ref   string
+}
+
+// ParseGitHubURL reads a repository URL on webHost (github.com, or the host of a GitHub Enterprise server) such as
+// https://github.com/owner/repo(.git), https://github.com/owner/repo/tree/<ref> or https://github.com/owner/repo/commit/<sha>
+func ParseGitHubURL(raw, webHost string) (Repo, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return Repo{}, errors.New("not a repository URL")
+	}
+	if host := strings.TrimPrefix(strings.ToLower(u.Host), "www."); host != strings.ToLower(webHost) {
+		return Repo{}, errors.New("repository URL must be on " + webHost)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return Repo{}, errors.New("repository URL must name an owner and a repository")
+	}
+	repo := Repo{Owner: parts[0], Name: strings.TrimSuffix(parts[1], ".git")}
+	if len(parts) > 3 && (parts[2] == "tree" || parts[2] == "commit") {
+		repo.Ref = strings.Join(parts[3:], "/")
+	}
+	return repo, nil
+}
+
+// GitHub fetches repositories through the GitHub REST API, or any server implementing
+// the same repos endpoints
+type GitHub struct {
+	BaseURL string // e.g. https://api.github.com
+	WebHost string // host of the repository URLs the API serves, e.g. github.com
+	Token   string // optional; needed for private repositories and higher rate limits
+	Client  *http.Client
+}
+
+// NewGitHub returns a client for the API at baseURL. Repository URLs are expected on the API's host without
+// its api. prefix: github.com for api.github.com, or the host of a GitHub Enterprise server's /api/v3.
+func NewGitHub(baseURL, token string) *GitHub {
+	baseURL = strings.TrimRight(baseURL, "/")
+	webHost := "github.com"
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		webHost = strings.TrimPrefix(strings.ToLower(u.Host), "api.")
+	}
+	return &GitHub{
+		BaseURL: baseURL,
+		WebHost: webHost,
+		Token:   token,
+		Client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// ResolveCommit returns the SHA of the commit a branch, tag or commit ref points to.
+// An empty ref resolves the repository's default branch.
+func (g *GitHub) ResolveCommit(ctx context.Context, repo Repo, ref string) (string, error) {
+	if ref == "" {
+		var info struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := g.getJSON(ctx, repoPath(repo), &info); err != nil {
+			return "", err
+		}
+		ref = info.DefaultBranch
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := g.getJSON(ctx, repoPath(repo)+"/commits/"+escapeRef(ref), &commit); err != nil {
+		return "", err
+	}
+	if len(commit.SHA) != 40 {
+		return "", errors.New("github: commit has no SHA")
+	}
+	return commit.SHA, nil
+}
+
+// Snapshot downloads the repository at a commit and returns its Solidity and Vyper files
+func (g *GitHub) Snapshot(ctx context.Context, repo Repo, sha string) ([]File, error) {
+	resp, err := g.get(ctx, repoPath(repo)+"/tarball/"+sha)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	files, err := ExtractTarGz(resp.Body, 1)
+	if err != nil && !errors.Is(err, ErrTooLarge) {
+		return nil, fmt.Errorf("github: tarball: %w", err)
+	}
+	return files, err
+}
+
+func (g *GitHub) getJSON(ctx context.Context, p string, v interface{}) error {
+	resp, err := g.get(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("github: %s: %w", p, err)
+	}
+	return nil
+}
+
+// get requests a path of the API; responses other than 200 are returned as errors
+func (g *GitHub) get(ctx context.Context, p string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", g.BaseURL+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 200 {
+		return resp, nil
+	}
+	resp.Body.Close()
+	// Unknown refs are 422; private repositories without access are 404
+	if resp.StatusCode == 404 || resp.StatusCode == 422 {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("github: %s: %s", p, resp.Status)
+}
+
+func repoPath(repo Repo) string {
+	return "/repos/" + url.PathEscape(repo.Owner) + "/" + url.PathEscape(repo.Name)
+}
+
+// escapeRef escapes each segment of a ref, keeping the slashes of branch names like feature/x
+func escapeRef(ref string) string {
+	parts := strings.Split(ref, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,172 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitHubURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		webHost string
+		want    Repo
+		wantErr string
+	}{
+		{raw: "https://github.com/owner/repo", webHost: "github.com", want: Repo{Owner: "owner", Name: "repo"}},
+		{raw: "https://github.com/owner/repo.git", webHost: "github.com", want: Repo{Owner: "owner", Name: "repo"}},
+		{raw: " https://www.github.com/owner/repo/ ", webHost: "github.com", want: Repo{Owner: "owner", Name: "repo"}},
+		{raw: "http://GitHub.com/owner/repo", webHost: "github.com", want: Repo{Owner: "owner", Name: "repo"}},
+		{raw: "https://github.com/owner/repo/tree/main", webHost: "github.com", want: Repo{Owner: "owner", Name: "repo", Ref: "main"}},
+		{raw: "https://github.com/owner/repo/tree/feature/x", webHost: "github.com", want: Repo{Owner: "owner", Name: "repo", Ref: "feature/x"}},
+		{
+			raw:     "https://github.com/owner/repo/commit/0123456789abcdef0123456789abcdef01234567",
+			webHost: "github.com",
+			want:    Repo{Owner: "owner", Name: "repo", Ref: "0123456789abcdef0123456789abcdef01234567"},
+		},
+		{raw: "https://github.com/owner/repo/blob/main/src/A.sol", webHost: "github.com", want: Repo{Owner: "owner", Name: "repo"}},
+		{raw: "https://ghe.example.com/team/vault", webHost: "ghe.example.com", want: Repo{Owner: "team", Name: "vault"}},
+		{raw: "https://gitlab.com/owner/repo", webHost: "github.com", wantErr: "must be on github.com"},
+		{raw: "https://github.com.evil.com/owner/repo", webHost: "github.com", wantErr: "must be on github.com"},
+		{raw: "https://github.com/owner/repo", webHost: "ghe.example.com", wantErr: "must be on ghe.example.com"},
+		{raw: "https://github.com/owner", webHost: "github.com", wantErr: "must name an owner and a repository"},
+		{raw: "ssh://git@github.com/owner/repo", webHost: "github.com", wantErr: "not a repository URL"},
+		{raw: "github.com/owner/repo", webHost: "github.com", wantErr: "not a repository URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseGitHubURL(tt.raw, tt.webHost)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGitHubWebHost(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.github.com", "github.com"},
+		{"https://api.github.com/", "github.com"},
+		{"https://ghe.example.com/api/v3", "ghe.example.com"},
+		{"https://API.GHE.example.com/api/v3", "ghe.example.com"},
+	}
+	for _, tt := range tests {
+		if got := NewGitHub(tt.baseURL, "").WebHost; got != tt.want {
+			t.Errorf("NewGitHub(%q).WebHost = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.EscapedPath())
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("Authorization = %q", got)
+		}
+		switch r.URL.EscapedPath() {
+		case "/repos/owner/repo":
+			w.Write([]byte(`{"default_branch": "develop"}`))
+		case "/repos/owner/repo/commits/develop", "/repos/owner/repo/commits/feature/a%20b":
+			w.Write([]byte(`{"sha": "` + sha + `"}`))
+		case "/repos/owner/repo/commits/bad":
+			w.Write([]byte(`{"sha": "short"}`))
+		case "/repos/owner/repo/commits/unknown":
+			w.WriteHeader(422)
+		case "/repos/owner/repo/commits/broken":
+			w.WriteHeader(500)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGitHub(srv.URL, "tok")
+	repo := Repo{Owner: "owner", Name: "repo"}
+	ctx := context.Background()
+
+	t.Run("default branch", func(t *testing.T) {
+		requests = nil
+		got, err := g.ResolveCommit(ctx, repo, "")
+		if err != nil || got != sha {
+			t.Fatalf("got %q, %v; want %q", got, err, sha)
+		}
+		if want := []string{"/repos/owner/repo", "/repos/owner/repo/commits/develop"}; !reflect.DeepEqual(requests, want) {
+			t.Errorf("requests = %q, want %q", requests, want)
+		}
+	})
+	t.Run("branch with a slash", func(t *testing.T) {
+		if got, err := g.ResolveCommit(ctx, repo, "feature/a b"); err != nil || got != sha {
+			t.Fatalf("got %q, %v; want %q", got, err, sha)
+		}
+	})
+	t.Run("no SHA", func(t *testing.T) {
+		if _, err := g.ResolveCommit(ctx, repo, "bad"); err == nil {
+			t.Fatal("want an error")
+		}
+	})
+	t.Run("unknown ref", func(t *testing.T) {
+		if _, err := g.ResolveCommit(ctx, repo, "unknown"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("err = %v, want %v", err, ErrNotFound)
+		}
+	})
+	t.Run("unknown repository", func(t *testing.T) {
+		if _, err := g.ResolveCommit(ctx, Repo{Owner: "owner", Name: "private"}, ""); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("err = %v, want %v", err, ErrNotFound)
+		}
+	})
+	t.Run("server error", func(t *testing.T) {
+		_, err := g.ResolveCommit(ctx, repo, "broken")
+		if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "500") {
+			t.Fatalf("err = %v, want the 500 status", err)
+		}
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	tarball := gzipped(t, tarArchive(t,
+		entry{"owner-repo-0123456/src/Vault.sol", "contract Vault {}"},
+		entry{"owner-repo-0123456/remappings.txt", "@oz/=lib/oz/"},
+		entry{"owner-repo-0123456/README.md", "# readme"},
+	))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/tarball/"+sha {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(tarball)
+	}))
+	defer srv.Close()
+
+	g := NewGitHub(srv.URL, "")
+	files, err := g.Snapshot(context.Background(), Repo{Owner: "owner", Name: "repo"}, sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"remappings.txt: @oz/=lib/oz/", "src/Vault.sol: contract Vault {}"}
+	if got := paths(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := g.Snapshot(context.Background(), Repo{Owner: "owner", Name: "gone"}, sha); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want %v", err, ErrNotFound)
+	}
+}
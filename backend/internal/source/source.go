@@ -0,0 +1,219 @@
+// Package source ingests the contract sources an audit analyzes: it fetches
+// repositories from GitHub-compatible APIs and verified sources from block explorers,
+// and extracts the Solidity and Vyper files of an archive, with the config resolving
+// their imports, into a snapshot.
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Snapshot limits, so a huge repository can't exhaust memory or the database
+const (
+	MaxFiles     = 2000
+	MaxFileSize  = 1 << 20  // 1MB
+	MaxTotalSize = 20 << 20 // 20MB
+)
+
+// ErrTooLarge is returned when a snapshot exceeds MaxFiles or MaxTotalSize
+var ErrTooLarge = fmt.Errorf("source exceeds %d files or %dMB", MaxFiles, MaxTotalSize>>20)
+
+// ErrUnknownArchive is returned for archives that aren't zip, tar or gzipped tar
+var ErrUnknownArchive = errors.New("archive must be a zip, tar or tar.gz file")
+
+// ErrDuplicatePath is returned for archives with several entries at the same path once cleaned, e.g. a.sol and ./a.sol
+var ErrDuplicatePath = errors.New("duplicate path")
+
+// configFiles are kept alongside the sources as they tell how imports resolve
+var configFiles = map[string]bool{
+	"remappings.txt": true,
+	"foundry.toml":   true,
+}
+
+// File is a source file of a snapshot, at a slash-separated path relative to the repository root
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// IsSource reports whether a path names a Solidity or Vyper file
+func IsSource(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".sol", ".vy":
+		return true
+	}
+	return false
+}
+
+// Wanted reports whether a file belongs in a snapshot: a source file, or a config file resolving imports
+func Wanted(p string) bool {
+	return IsSource(p) || configFiles[path.Base(p)]
+}
+
+// CleanPath normalizes a path inside an archive, dropping its first strip components.
+// It returns "" for paths that are empty after stripping or escape the root.
+func CleanPath(p string, strip int) string {
+	p = strings.TrimPrefix(strings.ReplaceAll(p, "\\", "/"), "./")
+	parts := strings.Split(p, "/")
+	if len(parts) <= strip {
+		return ""
+	}
+	p = path.Clean(strings.Join(parts[strip:], "/"))
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") || strings.HasPrefix(p, "/") {
+		return ""
+	}
+	return p
+}
+
+// ExtractTarGz reads the wanted files of a gzipped tarball, dropping the first strip
+// components of each path (GitHub tarballs wrap the repository in one directory)
+func ExtractTarGz(r io.Reader, strip int) ([]File, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var c collector
+	if err := c.readTar(gz, strip); err != nil {
+		return nil, err
+	}
+	return c.files, nil
+}
+
+// ExtractArchive reads the wanted files of an uploaded zip, tar or gzipped tar archive.
+// When every entry sits in one top-level directory, as in a downloaded repository, it is stripped.
+func ExtractArchive(data []byte) ([]File, error) {
+	var c collector
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		err = c.readZip(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			err = c.readTar(gz, 0)
+			gz.Close()
+		}
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		err = c.readTar(bytes.NewReader(data), 0)
+	default:
+		return nil, ErrUnknownArchive
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.stripRoot(), nil
+}
+
+// collector accumulates the wanted files of an archive within the snapshot limits
+type collector struct {
+	files []File
+	total int64
+	seen  map[string]bool // cleaned path of every kept file
+	roots map[string]bool // first path component of every entry
+	flat  bool            // an entry sits at the top level
+}
+
+// add keeps a file if it's wanted, reading at most MaxFileSize of it; larger files are skipped.
+// Entries cleaning to the path of a kept file are rejected with ErrDuplicatePath.
+func (c *collector) add(name string, size int64, strip int, open func() (io.Reader, error)) error {
+	if c.roots == nil {
+		c.roots = map[string]bool{}
+		c.seen = map[string]bool{}
+	}
+	if full := CleanPath(name, 0); full != "" {
+		if i := strings.Index(full, "/"); i >= 0 {
+			c.roots[full[:i]] = true
+		} else {
+			c.flat = true
+		}
+	}
+
+	p := CleanPath(name, strip)
+	if p == "" || !Wanted(p) || size > MaxFileSize {
+		return nil
+	}
+	if c.seen[p] {
+		return fmt.Errorf("%w %s", ErrDuplicatePath, p)
+	}
+	c.seen[p] = true
+	c.total += size
+	if len(c.files) == MaxFiles || c.total > MaxTotalSize {
+		return ErrTooLarge
+	}
+
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(io.LimitReader(r, MaxFileSize))
+	if err != nil {
+		return err
+	}
+	c.files = append(c.files, File{Path: p, Content: content})
+	return nil
+}
+
+func (c *collector) readTar(r io.Reader, strip int) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := c.add(h.Name, h.Size, strip, func() (io.Reader, error) { return tr, nil }); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *collector) readZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !f.Mode().IsRegular() {
+			continue
+		}
+		var rc io.ReadCloser
+		err := c.add(f.Name, int64(f.UncompressedSize64), 0, func() (io.Reader, error) {
+			var err error
+			rc, err = f.Open()
+			return rc, err
+		})
+		if rc != nil {
+			rc.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripRoot drops the top-level directory shared by every entry of the archive, if there is one
+func (c *collector) stripRoot() []File {
+	if c.flat || len(c.roots) != 1 {
+		return c.files
+	}
+	for i := range c.files {
+		c.files[i].Path = CleanPath(c.files[i].Path, 1)
+	}
+	return c.files
+}
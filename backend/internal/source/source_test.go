@@ -0,0 +1,228 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// entry is a file of a test archive
+type entry struct {
+	name    string
+	content string
+}
+
+func zipArchive(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarArchive(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// paths returns the sorted path: content pairs of files
+func paths(files []File) []string {
+	var out []string
+	for _, f := range files {
+		out = append(out, f.Path+": "+string(f.Content))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		strip int
+		want  string
+	}{
+		{"contracts/A.sol", 0, "contracts/A.sol"},
+		{"./contracts/A.sol", 0, "contracts/A.sol"},
+		{"contracts\\lib\\B.sol", 0, "contracts/lib/B.sol"},
+		{"contracts/../A.sol", 0, "A.sol"},
+		{"repo-abc123/contracts/A.sol", 1, "contracts/A.sol"},
+		{"repo-abc123", 1, ""},
+		{"../A.sol", 0, ""},
+		{"contracts/../../A.sol", 0, ""},
+		{"/etc/passwd", 0, ""},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := CleanPath(tt.path, tt.strip); got != tt.want {
+			t.Errorf("CleanPath(%q, %d) = %q, want %q", tt.path, tt.strip, got, tt.want)
+		}
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	repo := []entry{
+		{"repo-main/src/Vault.sol", "contract Vault {}"},
+		{"repo-main/src/Pool.vy", "# pool"},
+		{"repo-main/remappings.txt", "@oz/=lib/oz/"},
+		{"repo-main/README.md", "# readme"},
+		{"repo-main/test/Vault.t.sol", "contract VaultTest {}"},
+	}
+	wantRepo := []string{
+		"remappings.txt: @oz/=lib/oz/",
+		"src/Pool.vy: # pool",
+		"src/Vault.sol: contract Vault {}",
+		"test/Vault.t.sol: contract VaultTest {}",
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"zip in a root directory", zipArchive(t, repo...), wantRepo},
+		{"tar in a root directory", tarArchive(t, repo...), wantRepo},
+		{"tar.gz in a root directory", gzipped(t, tarArchive(t, repo...)), wantRepo},
+		{
+			name: "flat",
+			data: zipArchive(t, entry{"Vault.sol", "contract Vault {}"}, entry{"lib/Math.sol", "library Math {}"}),
+			want: []string{"Vault.sol: contract Vault {}", "lib/Math.sol: library Math {}"},
+		},
+		{
+			name: "several top-level directories",
+			data: zipArchive(t, entry{"src/Vault.sol", "contract Vault {}"}, entry{"lib/Math.sol", "library Math {}"}),
+			want: []string{"lib/Math.sol: library Math {}", "src/Vault.sol: contract Vault {}"},
+		},
+		{
+			name: "escaping and oversized entries",
+			data: zipArchive(t,
+				entry{"src/Vault.sol", "contract Vault {}"},
+				entry{"lib/Math.sol", "library Math {}"},
+				entry{"../Evil.sol", "contract Evil {}"},
+				entry{"src/Huge.sol", strings.Repeat("x", MaxFileSize+1)},
+			),
+			want: []string{"lib/Math.sol: library Math {}", "src/Vault.sol: contract Vault {}"},
+		},
+		{
+			name: "nothing wanted",
+			data: zipArchive(t, entry{"README.md", "# readme"}),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ExtractArchive(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := paths(files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{
+			name: "duplicate path in a zip",
+			data: zipArchive(t, entry{"src/A.sol", "contract A {}"}, entry{"./src/A.sol", "contract B {}"}),
+			want: ErrDuplicatePath,
+		},
+		{
+			name: "duplicate path in a tar",
+			data: tarArchive(t, entry{"src/A.sol", "contract A {}"}, entry{"src/lib/../A.sol", "contract B {}"}),
+			want: ErrDuplicatePath,
+		},
+		{
+			name: "duplicate path with backslashes",
+			data: zipArchive(t, entry{"src/A.sol", "contract A {}"}, entry{"src\\A.sol", "contract B {}"}),
+			want: ErrDuplicatePath,
+		},
+		{
+			name: "not an archive",
+			data: []byte("pragma solidity ^0.8.0;"),
+			want: ErrUnknownArchive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExtractArchive(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveTooManyFiles(t *testing.T) {
+	entries := make([]entry, MaxFiles+1)
+	for i := range entries {
+		entries[i] = entry{name: fmt.Sprintf("src/C%d.sol", i)}
+	}
+	if _, err := ExtractArchive(zipArchive(t, entries...)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestExtractTarGzStrip(t *testing.T) {
+	data := gzipped(t, tarArchive(t,
+		entry{"owner-repo-abc123/src/Vault.sol", "contract Vault {}"},
+		entry{"owner-repo-abc123/foundry.toml", "[profile.default]"},
+		entry{"owner-repo-abc123/package.json", "{}"},
+	))
+	files, err := ExtractTarGz(bytes.NewReader(data), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"foundry.toml: [profile.default]", "src/Vault.sol: contract Vault {}"}
+	if got := paths(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}